@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE match_games (
+                             match_id TEXT NOT NULL,
+                             game_no INT NOT NULL,
+                             p1_id UUID REFERENCES users(id) ON DELETE SET NULL,
+                             p2_id UUID REFERENCES users(id) ON DELETE SET NULL,
+                             winner TEXT NOT NULL,
+                             rounds INT NOT NULL,
+                             finished_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                             PRIMARY KEY (match_id, game_no)
+);
+
+CREATE TABLE match_series (
+                              match_id TEXT PRIMARY KEY,
+                              best_of INT NOT NULL,
+                              p1_id UUID REFERENCES users(id) ON DELETE SET NULL,
+                              p2_id UUID REFERENCES users(id) ON DELETE SET NULL,
+                              p1_wins INT NOT NULL,
+                              p2_wins INT NOT NULL,
+                              draws INT NOT NULL,
+                              winner TEXT NOT NULL,
+                              finished_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE player_stats
+    ADD COLUMN series_wins INT NOT NULL DEFAULT 0,
+    ADD COLUMN series_losses INT NOT NULL DEFAULT 0,
+    ADD COLUMN series_draws INT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE player_stats
+    DROP COLUMN series_wins,
+    DROP COLUMN series_losses,
+    DROP COLUMN series_draws;
+
+DROP TABLE match_series;
+DROP TABLE match_games;
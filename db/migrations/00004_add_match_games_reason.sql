@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE match_games
+    ADD COLUMN reason TEXT NOT NULL DEFAULT 'solved';
+
+ALTER TABLE player_stats
+    ADD COLUMN tiebreak_wins INT NOT NULL DEFAULT 0,
+    ADD COLUMN tiebreak_losses INT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE player_stats
+    DROP COLUMN tiebreak_wins,
+    DROP COLUMN tiebreak_losses;
+
+ALTER TABLE match_games
+    DROP COLUMN reason;
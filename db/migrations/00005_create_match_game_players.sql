@@ -0,0 +1,17 @@
+-- +goose Up
+-- Места всех игроков партии (дуэль и free-for-all до 8 игроков).
+CREATE TABLE match_game_players (
+                                    match_id TEXT NOT NULL,
+                                    game_no INT NOT NULL,
+                                    slot TEXT NOT NULL,
+                                    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+                                    rank INT NOT NULL,
+                                    solved_round INT NOT NULL DEFAULT 0,
+                                    PRIMARY KEY (match_id, game_no, slot),
+                                    FOREIGN KEY (match_id, game_no) REFERENCES match_games(match_id, game_no) ON DELETE CASCADE
+);
+
+CREATE INDEX match_game_players_user_idx ON match_game_players (user_id);
+
+-- +goose Down
+DROP TABLE match_game_players;
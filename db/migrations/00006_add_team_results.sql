@@ -0,0 +1,18 @@
+-- +goose Up
+-- Командный режим 2v2: команда игрока в партии и командная статистика.
+ALTER TABLE match_game_players
+    ADD COLUMN team TEXT;
+
+ALTER TABLE player_stats
+    ADD COLUMN team_wins INT NOT NULL DEFAULT 0,
+    ADD COLUMN team_losses INT NOT NULL DEFAULT 0,
+    ADD COLUMN team_draws INT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE player_stats
+    DROP COLUMN team_wins,
+    DROP COLUMN team_losses,
+    DROP COLUMN team_draws;
+
+ALTER TABLE match_game_players
+    DROP COLUMN team;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Refresh-токены храним только хешем (sha256). family_id — цепочка ротаций одного логина:
+-- повторное использование уже обменянного токена отзывает всю цепочку.
+CREATE TABLE refresh_tokens (
+                                id UUID PRIMARY KEY,
+                                user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                family_id UUID NOT NULL,
+                                token_hash TEXT NOT NULL UNIQUE,
+                                expires_at TIMESTAMPTZ NOT NULL,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                used_at TIMESTAMPTZ,
+                                replaced_by UUID,
+                                revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_idx ON refresh_tokens (user_id);
+
+-- access-токены, выпущенные раньше этого момента, недействительны (logout)
+ALTER TABLE users
+    ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN tokens_revoked_at;
+
+DROP TABLE refresh_tokens;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Гостевые аккаунты: без email/пароля до апгрейда. UNIQUE(email) допускает несколько NULL.
+ALTER TABLE users
+    ALTER COLUMN email DROP NOT NULL,
+    ALTER COLUMN password_hash DROP NOT NULL,
+    ADD COLUMN is_guest BOOLEAN NOT NULL DEFAULT false;
+
+-- товарищеские (unranked) партии пишем в историю, но с пометкой
+ALTER TABLE match_games
+    ADD COLUMN ranked BOOLEAN NOT NULL DEFAULT true;
+
+-- +goose Down
+ALTER TABLE match_games
+    DROP COLUMN ranked;
+
+-- без email или пароля пользователю до этой миграции не жить: гости и OIDC-аккаунты (00009)
+DELETE FROM users WHERE is_guest OR email IS NULL OR password_hash IS NULL;
+
+ALTER TABLE users
+    DROP COLUMN is_guest,
+    ALTER COLUMN email SET NOT NULL,
+    ALTER COLUMN password_hash SET NOT NULL;
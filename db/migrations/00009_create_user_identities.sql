@@ -0,0 +1,18 @@
+-- +goose Up
+-- Внешние аккаунты (OIDC): (provider, subject) => users.id. Пользователь, созданный через OIDC,
+-- живёт без пароля. Email провайдера храним здесь; новому пользователю он же идёт в users.email,
+-- если этот адрес ещё ни за кем не закреплён.
+CREATE TABLE user_identities (
+                                 provider TEXT NOT NULL,
+                                 subject TEXT NOT NULL,
+                                 user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                 email TEXT,
+                                 created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                 last_login_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                 PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX user_identities_user_idx ON user_identities (user_id);
+
+-- +goose Down
+DROP TABLE user_identities;
@@ -0,0 +1,41 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Одноразовые токены из писем (сброс пароля, подтверждение email). Храним только sha256.
+-- email — адрес, на который ушло письмо: подтверждение действует, только если email с тех пор не менялся.
+CREATE TABLE user_tokens (
+                             id UUID PRIMARY KEY,
+                             user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                             purpose TEXT NOT NULL,
+                             token_hash TEXT NOT NULL UNIQUE,
+                             email TEXT NOT NULL,
+                             expires_at TIMESTAMPTZ NOT NULL,
+                             created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                             used_at TIMESTAMPTZ
+);
+
+CREATE INDEX user_tokens_user_idx ON user_tokens (user_id, purpose);
+
+-- Transactional outbox: письмо пишется в той же транзакции, что и токен,
+-- фоновый sender забирает due-письма (next_attempt_at) и отправляет через Mailer.
+CREATE TABLE email_outbox (
+                              id BIGSERIAL PRIMARY KEY,
+                              recipient TEXT NOT NULL,
+                              subject TEXT NOT NULL,
+                              body TEXT NOT NULL,
+                              created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                              next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                              attempts INT NOT NULL DEFAULT 0,
+                              last_error TEXT,
+                              sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX email_outbox_due_idx ON email_outbox (next_attempt_at) WHERE sent_at IS NULL;
+
+-- +goose Down
+DROP TABLE email_outbox;
+DROP TABLE user_tokens;
+
+ALTER TABLE users
+    DROP COLUMN email_verified_at;
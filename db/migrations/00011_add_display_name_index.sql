@@ -0,0 +1,7 @@
+-- +goose Up
+-- Уникальность display_name проверяется без учёта регистра при смене имени и регистрации.
+-- Индекс не UNIQUE: у существующих пользователей (и гостей со случайными именами) могут быть совпадения.
+CREATE INDEX users_display_name_lower_idx ON users (lower(display_name));
+
+-- +goose Down
+DROP INDEX users_display_name_lower_idx;
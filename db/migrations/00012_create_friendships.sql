@@ -0,0 +1,20 @@
+-- +goose Up
+-- Друзья: одна строка на пару. pending — заявка requester => addressee, accepted — дружба
+-- (направление после принятия значения не имеет). Отклонение и удаление — DELETE.
+CREATE TABLE friendships (
+                             requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                             addressee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                             status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted')),
+                             created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                             accepted_at TIMESTAMPTZ,
+                             PRIMARY KEY (requester_id, addressee_id),
+                             CHECK (requester_id <> addressee_id)
+);
+
+-- не больше одной строки на пару в любом направлении
+CREATE UNIQUE INDEX friendships_pair_idx
+    ON friendships (LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id));
+CREATE INDEX friendships_addressee_idx ON friendships (addressee_id);
+
+-- +goose Down
+DROP TABLE friendships;
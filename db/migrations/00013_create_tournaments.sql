@@ -0,0 +1,47 @@
+-- +goose Up
+-- Турниры: swiss (фиксированное число туров, пары по очкам) или single_elimination (сетка на выбывание).
+-- registering -> running (start) -> finished (после последнего тура).
+CREATE TABLE tournaments (
+                             id UUID PRIMARY KEY,
+                             name TEXT NOT NULL,
+                             format TEXT NOT NULL CHECK (format IN ('swiss', 'single_elimination')),
+                             status TEXT NOT NULL DEFAULT 'registering' CHECK (status IN ('registering', 'running', 'finished')),
+                             rules JSONB NOT NULL,
+                             max_players INT NOT NULL,
+                             rounds INT NOT NULL DEFAULT 0, -- число туров; известно после start
+                             current_round INT NOT NULL DEFAULT 0,
+                             created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+                             created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                             started_at TIMESTAMPTZ,
+                             finished_at TIMESTAMPTZ
+);
+CREATE INDEX tournaments_status_idx ON tournaments (status, created_at DESC);
+
+-- seed — номер посева (порядок регистрации), назначается при start
+CREATE TABLE tournament_players (
+                                    tournament_id UUID NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+                                    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                    seed INT,
+                                    registered_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                    PRIMARY KEY (tournament_id, user_id)
+);
+
+-- Пары тура. p2_id IS NULL — bye (match_id тоже NULL, result сразу 'p1').
+-- result: NULL — матч идёт, p1|p2|draw — относительно p1_id/p2_id этой строки
+-- (слоты внутри игрового матча зависят от порядка подключения и могут не совпадать).
+CREATE TABLE tournament_matches (
+                                    tournament_id UUID NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+                                    round INT NOT NULL,
+                                    board INT NOT NULL,
+                                    match_id TEXT UNIQUE,
+                                    p1_id UUID REFERENCES users(id) ON DELETE SET NULL,
+                                    p2_id UUID REFERENCES users(id) ON DELETE SET NULL,
+                                    result TEXT CHECK (result IN ('p1', 'p2', 'draw')),
+                                    finished_at TIMESTAMPTZ,
+                                    PRIMARY KEY (tournament_id, round, board)
+);
+
+-- +goose Down
+DROP TABLE tournament_matches;
+DROP TABLE tournament_players;
+DROP TABLE tournaments;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Данные для достижений: пропущенные по таймеру раунды и остаток таймера на отгадавшей попытке
+-- (NULL — без таймера или не отгадал).
+ALTER TABLE match_game_players
+    ADD COLUMN missed_rounds INT NOT NULL DEFAULT 0,
+    ADD COLUMN time_left_ms INT;
+
+-- Открытые достижения; achievement — id из каталога (internal/achievements).
+-- match_id — матч, в котором достижение открыто.
+CREATE TABLE user_achievements (
+                                   user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                   achievement TEXT NOT NULL,
+                                   match_id TEXT,
+                                   unlocked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                   PRIMARY KEY (user_id, achievement)
+);
+
+-- +goose Down
+DROP TABLE user_achievements;
+
+ALTER TABLE match_game_players
+    DROP COLUMN missed_rounds,
+    DROP COLUMN time_left_ms;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Ежедневная головоломка: одна попытка пользователя на день (UTC).
+-- history — догадки в формате раундов матча (game.RoundHistoryItem, попытка в p1).
+-- finished_at ставится, когда отгадал или кончились догадки.
+CREATE TABLE daily_results (
+                               day DATE NOT NULL,
+                               user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                               history JSONB NOT NULL DEFAULT '[]',
+                               rounds INT NOT NULL DEFAULT 0,
+                               solved BOOLEAN NOT NULL DEFAULT false,
+                               started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                               finished_at TIMESTAMPTZ,
+                               PRIMARY KEY (day, user_id)
+);
+-- таблица лидеров дня: отгадавшие по числу догадок
+CREATE INDEX daily_results_leaderboard_idx ON daily_results (day, rounds, finished_at) WHERE solved;
+
+-- +goose Down
+DROP TABLE daily_results;
@@ -0,0 +1,172 @@
+// Package achievements defines the badges players unlock by playing and the rules that
+// award them. Rules are evaluated whenever a game finishes (Recorder, recorder.go), on the
+// player's latest games from the match history with the game just finished first.
+package achievements
+
+import (
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"example.com/bc-mvp/internal/store"
+)
+
+// Achievement IDs. They are stored in user_achievements, so never rename one.
+const (
+	QuickSolver = "quick_solver"
+	WinStreak5  = "win_streak_5"
+	IronWill    = "iron_will"
+	PhotoFinish = "photo_finish"
+)
+
+// Thresholds of the built-in rules.
+const (
+	quickSolveRounds  = 3
+	winStreakLen      = 5
+	ironWillRounds    = 20
+	photoFinishMargin = 5 * time.Second
+)
+
+// Achievement is a badge as shown to players.
+type Achievement struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+// Game is one finished game from a player's point of view.
+type Game struct {
+	MatchID      string
+	GameNo       int
+	Result       string // win|loss|draw
+	Rounds       int
+	SolvedRound  int           // 0: did not solve
+	MissedRounds int           // rounds (turns in alternating mode) lost to the timer
+	TimeLeft     time.Duration // on the clock when the solving guess came in; 0: no timer or not solved
+}
+
+// Rule awards an achievement. History is how many of the player's latest games Check
+// needs; Check gets at most that many, newest first, the game just finished at [0].
+type Rule struct {
+	Achievement
+	History int
+	Check   func(games []Game) bool
+}
+
+// Rules is the catalog, in display order.
+var Rules = []Rule{
+	{
+		Achievement: Achievement{QuickSolver, "Sharpshooter", "Crack the secret within 3 rounds"},
+		History:     1,
+		Check: func(games []Game) bool {
+			g := games[0]
+			return g.SolvedRound > 0 && g.SolvedRound <= quickSolveRounds
+		},
+	},
+	{
+		Achievement: Achievement{WinStreak5, "On a roll", "Win 5 games in a row"},
+		History:     winStreakLen,
+		Check: func(games []Game) bool {
+			if len(games) < winStreakLen {
+				return false
+			}
+			for _, g := range games {
+				if g.Result != "win" {
+					return false
+				}
+			}
+			return true
+		},
+	},
+	{
+		Achievement: Achievement{IronWill, "Iron will", "Play a game of 20 rounds or more without missing a round"},
+		History:     1,
+		Check: func(games []Game) bool {
+			g := games[0]
+			return g.Rounds >= ironWillRounds && g.MissedRounds == 0
+		},
+	},
+	{
+		Achievement: Achievement{PhotoFinish, "Photo finish", "Win with less than 5 seconds left on the clock"},
+		History:     1,
+		Check: func(games []Game) bool {
+			g := games[0]
+			return g.Result == "win" && g.TimeLeft > 0 && g.TimeLeft < photoFinishMargin
+		},
+	},
+}
+
+// Lookup finds a catalog entry by ID.
+func Lookup(id string) (Achievement, bool) {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r.Achievement, true
+		}
+	}
+	return Achievement{}, false
+}
+
+// Evaluate returns the achievements whose rules hold for games (newest first).
+// Already unlocked ones are included too; storing them again is a no-op.
+func Evaluate(rules []Rule, games []Game) []Achievement {
+	if len(games) == 0 {
+		return nil
+	}
+	var out []Achievement
+	for _, r := range rules {
+		if r.Check(games[:min(len(games), max(r.History, 1))]) {
+			out = append(out, r.Achievement)
+		}
+	}
+	return out
+}
+
+// historyDepth is how many latest games the rules need at most.
+func historyDepth(rules []Rule) int {
+	n := 1
+	for _, r := range rules {
+		n = max(n, r.History)
+	}
+	return n
+}
+
+// fromResult is the finished game as seen by player p; the result follows the same
+// rules as the player stats: a shared first place is a draw, in teams the team decides.
+func fromResult(r game.GameResult, p game.PlayerResult) Game {
+	result := "loss"
+	switch {
+	case p.Team != "" && r.Winner == "draw":
+		result = "draw"
+	case p.Team != "":
+		if r.Winner == p.Team {
+			result = "win"
+		}
+	case p.Rank == 1:
+		result = "win"
+		for _, o := range r.Players {
+			if o.Slot != p.Slot && o.Rank == 1 {
+				result = "draw"
+			}
+		}
+	}
+	return Game{
+		MatchID:      r.MatchID,
+		GameNo:       r.GameNo,
+		Result:       result,
+		Rounds:       r.Rounds,
+		SolvedRound:  p.SolvedRound,
+		MissedRounds: p.MissedRounds,
+		TimeLeft:     p.TimeLeft,
+	}
+}
+
+func fromSummary(g store.GameSummary) Game {
+	return Game{
+		MatchID:      g.MatchID,
+		GameNo:       g.GameNo,
+		Result:       g.Result,
+		Rounds:       g.Rounds,
+		SolvedRound:  g.SolvedRound,
+		MissedRounds: g.MissedRounds,
+		TimeLeft:     g.TimeLeft,
+	}
+}
@@ -0,0 +1,117 @@
+package achievements
+
+import (
+	"testing"
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(list []Achievement) []string {
+	out := make([]string, 0, len(list))
+	for _, a := range list {
+		out = append(out, a.ID)
+	}
+	return out
+}
+
+func wins(n int) []Game {
+	out := make([]Game, n)
+	for i := range out {
+		out[i] = Game{Result: "win", Rounds: 6, SolvedRound: 6}
+	}
+	return out
+}
+
+func TestEvaluate(t *testing.T) {
+	cases := []struct {
+		name  string
+		games []Game // newest first
+		want  []string
+	}{
+		{"no games", nil, nil},
+		{"solved in 3 rounds", []Game{{Result: "loss", Rounds: 3, SolvedRound: 3}}, []string{QuickSolver}},
+		{"solved in 4 rounds", []Game{{Result: "win", Rounds: 4, SolvedRound: 4}}, nil},
+		{"five wins in a row", wins(5), []string{WinStreak5}},
+		{"four wins are not a streak", wins(4), nil},
+		{"streak broken by a draw", append(wins(4), Game{Result: "draw"}), nil},
+		{"older games do not matter", append(wins(5), Game{Result: "loss"}), []string{WinStreak5}},
+		{
+			name:  "20 rounds without a miss",
+			games: []Game{{Result: "loss", Rounds: 20}},
+			want:  []string{IronWill},
+		},
+		{
+			name:  "20 rounds with a miss",
+			games: []Game{{Result: "loss", Rounds: 24, MissedRounds: 1}},
+		},
+		{
+			name:  "win with 4.9s left",
+			games: []Game{{Result: "win", Rounds: 5, SolvedRound: 5, TimeLeft: 4900 * time.Millisecond}},
+			want:  []string{PhotoFinish},
+		},
+		{
+			name:  "win without a timer",
+			games: []Game{{Result: "win", Rounds: 5, SolvedRound: 5}},
+		},
+		{
+			name:  "draw with 1s left",
+			games: []Game{{Result: "draw", Rounds: 5, SolvedRound: 5, TimeLeft: time.Second}},
+		},
+		{
+			name:  "several at once",
+			games: []Game{{Result: "win", Rounds: 2, SolvedRound: 2, TimeLeft: time.Second}},
+			want:  []string{QuickSolver, PhotoFinish},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ids(Evaluate(Rules, tc.games))
+			if len(tc.want) == 0 {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFromResult(t *testing.T) {
+	duel := game.GameResult{
+		MatchID: "m1",
+		GameNo:  2,
+		Winner:  "p1",
+		Rounds:  7,
+		Players: []game.PlayerResult{
+			{Slot: "p1", UserID: "u1", Rank: 1, SolvedRound: 7, TimeLeft: 3 * time.Second},
+			{Slot: "p2", UserID: "u2", Rank: 2, MissedRounds: 2},
+		},
+	}
+	assert.Equal(t, Game{MatchID: "m1", GameNo: 2, Result: "win", Rounds: 7, SolvedRound: 7, TimeLeft: 3 * time.Second},
+		fromResult(duel, duel.Players[0]))
+	assert.Equal(t, Game{MatchID: "m1", GameNo: 2, Result: "loss", Rounds: 7, MissedRounds: 2},
+		fromResult(duel, duel.Players[1]))
+
+	shared := game.GameResult{Winner: "draw", Players: []game.PlayerResult{
+		{Slot: "p1", Rank: 1}, {Slot: "p2", Rank: 1}, {Slot: "p3", Rank: 3},
+	}}
+	assert.Equal(t, "draw", fromResult(shared, shared.Players[0]).Result)
+	assert.Equal(t, "loss", fromResult(shared, shared.Players[2]).Result)
+
+	teams := game.GameResult{Winner: "t2", Players: []game.PlayerResult{
+		{Slot: "p1", Team: "t1", Rank: 2}, {Slot: "p3", Team: "t2", Rank: 1}, {Slot: "p4", Team: "t2", Rank: 1},
+	}}
+	assert.Equal(t, "loss", fromResult(teams, teams.Players[0]).Result)
+	assert.Equal(t, "win", fromResult(teams, teams.Players[2]).Result, "teammates share the first place")
+}
+
+func TestLookup(t *testing.T) {
+	for _, r := range Rules {
+		a, ok := Lookup(r.ID)
+		assert.True(t, ok)
+		assert.Equal(t, r.Achievement, a)
+	}
+	_, ok := Lookup("nope")
+	assert.False(t, ok)
+}
@@ -0,0 +1,99 @@
+package achievements
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"example.com/bc-mvp/internal/notify"
+	"example.com/bc-mvp/internal/store"
+)
+
+// UnlockedPayload is the achievement_unlocked notification.
+type UnlockedPayload struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MatchID     string    `json:"matchId"`
+	UnlockedAt  time.Time `json:"unlockedAt"`
+}
+
+// Recorder is a game.ResultRecorder that evaluates the rules for every player of a
+// finished game and stores what they unlocked.
+type Recorder struct {
+	achievements *store.AchievementStore
+	history      *store.HistoryStore
+	rules        []Rule
+	notify       game.Notifier // optional: nil => no achievement_unlocked notifications
+}
+
+func NewRecorder(achievements *store.AchievementStore, history *store.HistoryStore) *Recorder {
+	return &Recorder{achievements: achievements, history: history, rules: Rules}
+}
+
+// SetNotifier sends achievement_unlocked to the player for each new achievement.
+func (r *Recorder) SetNotifier(n game.Notifier) {
+	r.notify = n
+}
+
+func (r *Recorder) RecordGame(ctx context.Context, res game.GameResult) error {
+	var errs []error
+	for _, p := range res.Players {
+		if p.UserID == "" {
+			continue
+		}
+		if err := r.recordPlayer(ctx, res, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Recorder) RecordSeries(ctx context.Context, res game.SeriesResult) error {
+	return nil
+}
+
+func (r *Recorder) recordPlayer(ctx context.Context, res game.GameResult, p game.PlayerResult) error {
+	games := []Game{fromResult(res, p)}
+	if depth := historyDepth(r.rules); depth > 1 {
+		// the result store may or may not have written this game yet: skip it in the history
+		recent, err := r.history.RecentGames(ctx, p.UserID, depth)
+		if err != nil {
+			return err
+		}
+		for _, g := range recent {
+			if len(games) == depth {
+				break
+			}
+			if g.MatchID == res.MatchID && g.GameNo == res.GameNo {
+				continue
+			}
+			games = append(games, fromSummary(g))
+		}
+	}
+
+	earned := Evaluate(r.rules, games)
+	if len(earned) == 0 {
+		return nil
+	}
+	ids := make([]string, len(earned))
+	for i, a := range earned {
+		ids[i] = a.ID
+	}
+	unlocked, err := r.achievements.Unlock(ctx, p.UserID, res.MatchID, ids)
+	if err != nil || r.notify == nil {
+		return err
+	}
+	for _, u := range unlocked {
+		a, _ := Lookup(u.ID)
+		r.notify.Publish(p.UserID, notify.Event{Type: "achievement_unlocked", Payload: UnlockedPayload{
+			ID:          a.ID,
+			Name:        a.Name,
+			Description: a.Description,
+			MatchID:     u.MatchID,
+			UnlockedAt:  u.UnlockedAt,
+		}})
+	}
+	return nil
+}
@@ -6,13 +6,20 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
+	"example.com/bc-mvp/internal/achievements"
 	"example.com/bc-mvp/internal/auth"
 	"example.com/bc-mvp/internal/config"
 	"example.com/bc-mvp/internal/game"
 	"example.com/bc-mvp/internal/httpapi"
+	"example.com/bc-mvp/internal/mail"
+	"example.com/bc-mvp/internal/notify"
+	"example.com/bc-mvp/internal/presence"
+	"example.com/bc-mvp/internal/ratelimit"
 	"example.com/bc-mvp/internal/store"
+	"example.com/bc-mvp/internal/tournament"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/sync/errgroup"
@@ -25,7 +32,8 @@ type App struct {
 	db  *pgxpool.Pool
 	rdb *redis.Client
 
-	srv *http.Server
+	srv  *http.Server
+	mail *mail.Sender
 }
 
 type Options struct {
@@ -64,24 +72,168 @@ func New(ctx context.Context, cfg config.Config, log *slog.Logger, opts Options)
 	}
 
 	// --- Auth service ---
-	authSvc := auth.NewService([]byte(cfg.Auth.Secret))
+	authKeys, err := loadJWTKeys(cfg)
+	if err != nil {
+		dbpool.Close()
+		_ = rdb.Close()
+		return nil, fmt.Errorf("jwt keys: %w", err)
+	}
+	authSvc := auth.NewServiceWithKeys(authKeys, cfg.Auth.Issuer, cfg.Auth.Audience)
 
 	// --- Stores ---
 	users := store.NewUserStore(dbpool)
 	stats := store.NewStatsStore(dbpool)
+	results := store.NewResultStore(dbpool)
+	tokens := store.NewTokenStore(dbpool)
+	identities := store.NewIdentityStore(dbpool)
+	userTokens := store.NewUserTokenStore(dbpool)
+	history := store.NewHistoryStore(dbpool)
+	friends := store.NewFriendStore(dbpool)
+	tournaments := store.NewTournamentStore(dbpool)
+	achievementStore := store.NewAchievementStore(dbpool)
+	dailyResults := store.NewDailyStore(dbpool)
+
+	// --- Mail (transactional outbox) ---
+	var mailer mail.Mailer = mail.LogMailer{Log: log}
+	switch cfg.Mail.Driver {
+	case "file":
+		fm, err := mail.NewFileMailer(cfg.Mail.Dir)
+		if err != nil {
+			dbpool.Close()
+			_ = rdb.Close()
+			return nil, fmt.Errorf("file mailer: %w", err)
+		}
+		mailer = fm
+	case "smtp":
+		mailer = mail.SMTPMailer{Addr: cfg.Mail.SMTPAddr, Username: cfg.Mail.SMTPUsername, Password: cfg.Mail.SMTPPassword}
+	}
+	outbox := store.NewOutboxStore(dbpool)
+	mailSender := &mail.Sender{
+		Outbox:   outbox,
+		Mailer:   mailer,
+		From:     cfg.Mail.From,
+		Interval: cfg.Mail.PollInterval,
+		Batch:    20,
+		Log:      log,
+	}
+
+	// logout everywhere and refresh token reuse revoke access tokens via users.tokens_revoked_at
+	authSvc.SetRevocationChecker(users)
 
 	authH := &httpapi.AuthHandler{
-		Users:    users,
-		Stats:    stats,
-		Auth:     authSvc,
-		TokenTTL: cfg.Auth.TokenTTL,
+		Users:        users,
+		Stats:        stats,
+		Achievements: achievementStore,
+		Tokens:       tokens,
+		Auth:         authSvc,
+		TokenTTL:     cfg.Auth.TokenTTL,
+		RefreshTTL:   cfg.Auth.RefreshTTL,
+
+		OIDC:       make(map[string]*auth.OIDCProvider, len(cfg.Auth.OIDC)),
+		Identities: identities,
+		// the pending-login cookie has its own key, so rotating JWT_SECRET does not affect it
+		OIDCStateKey: []byte(cfg.Auth.OIDCStateKey),
+
+		UserTokens: userTokens,
+		BaseURL:    cfg.Mail.BaseURL,
+		ResetTTL:   cfg.Mail.ResetTTL,
+		VerifyTTL:  cfg.Mail.VerifyTTL,
+
+		Limiter: ratelimit.NewLoginLimiter(rdb,
+			ratelimit.Policy{
+				Free:      cfg.Auth.LoginIPFree,
+				BaseDelay: time.Second,
+				MaxDelay:  cfg.Auth.LoginLockoutFor,
+				Window:    cfg.Auth.LoginFailureWindow,
+			},
+			ratelimit.Policy{
+				Free:         cfg.Auth.LoginAccountFree,
+				BaseDelay:    time.Second,
+				MaxDelay:     cfg.Auth.LoginLockoutFor,
+				LockoutAfter: cfg.Auth.LoginLockoutAfter,
+				LockoutFor:   cfg.Auth.LoginLockoutFor,
+				Window:       cfg.Auth.LoginFailureWindow,
+			}),
+		TrustProxy: cfg.HTTP.TrustProxy,
+	}
+	for _, p := range cfg.Auth.OIDC {
+		authH.OIDC[p.Name] = auth.NewOIDCProvider(auth.OIDCConfig{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
 	}
 
 	// --- Game ---
 	persist := game.NewRedisMatchStore(rdb, cfg.Redis.MatchTTL)
-	gameCfg := game.Config{RoundDuration: cfg.Game.RoundDuration}
+	if len(cfg.Redis.SnapshotKeys) > 0 {
+		snapCipher, err := game.NewSnapshotCipher(cfg.Redis.SnapshotKeyID, cfg.Redis.SnapshotKeys)
+		if err != nil {
+			dbpool.Close()
+			_ = rdb.Close()
+			return nil, fmt.Errorf("snapshot cipher: %w", err)
+		}
+		persist.SetCipher(snapCipher)
+
+		if cfg.Redis.SnapshotReseal {
+			n, err := persist.Reseal(ctx)
+			if err != nil {
+				dbpool.Close()
+				_ = rdb.Close()
+				return nil, fmt.Errorf("snapshot reseal: %w", err)
+			}
+			log.Info("match snapshots resealed", "count", n, "kid", cfg.Redis.SnapshotKeyID)
+		}
+	} else {
+		log.Warn("SNAPSHOT_KEYS not set: match secrets are stored in Redis in plaintext")
+	}
+	gameCfg := game.Config{
+		RoundDuration: cfg.Game.RoundDuration,
+		Rules: game.Rules{
+			BestOf:    cfg.Game.SeriesBestOf,
+			MaxRounds: cfg.Game.MaxRounds,
+			Tiebreak:  cfg.Game.Tiebreak,
+			Mode:      cfg.Game.Mode,
+			Players:   cfg.Game.Players,
+			Target:    cfg.Game.Target,
+		},
+	}
+	if err := gameCfg.Rules.Validate(); err != nil {
+		dbpool.Close()
+		_ = rdb.Close()
+		return nil, fmt.Errorf("game rules: %w", err)
+	}
+	// out-of-match notifications: /ws/user and /api/notifications/stream share one hub
+	hub := notify.NewHub()
 	matchSvc := game.NewMatchService(gameCfg, persist)
+	// your_move also goes by email: the hub only reaches users connected to this instance
+	matchSvc.SetNotifier(&game.DurableNotifier{Live: hub, Mail: outbox, BaseURL: cfg.Mail.BaseURL, Log: log})
+	// tournaments advance as their matches' series finish
+	tournamentSvc := tournament.NewService(tournaments, matchSvc)
+	tournamentSvc.SetNotifier(hub)
+	tournamentSvc.SetJoinTimeout(cfg.Game.JoinTimeout)
+	// achievements read the match history, so they go after the result store
+	achievementRec := achievements.NewRecorder(achievementStore, history)
+	achievementRec.SetNotifier(hub)
+	matchSvc.SetResultRecorder(game.ResultRecorders{results, tournamentSvc, achievementRec})
+	lobby := game.NewLobby(cfg.Game.LobbyTTL)
+	matchSvc.SetLobby(lobby)
+	// correspondence matches wait hours or days for a move: their round timers must fire
+	// after a restart even if nobody opens the match, so load them once everything is wired
+	if n, err := matchSvc.ResumeDeadlines(ctx); err != nil {
+		log.Warn("failed to resume correspondence matches", "resumed", n, "err", err)
+	} else if n > 0 {
+		log.Info("correspondence matches resumed", "count", n)
+	}
 	gameSrv := game.NewServer(gameCfg, matchSvc, authSvc)
+	gameSrv.SetNotifications(hub)
+	presenceTracker := presence.NewTracker(rdb, cfg.Redis.PresenceTTL, cfg.Redis.PresenceIdleAfter)
+	gameSrv.SetPresence(presenceTracker)
+	// join links point at the frontend origin, which serves /J/{code} from this app
+	gameSrv.SetInvites(game.NewRedisInviteStore(rdb, cfg.Game.InviteTTL), cfg.Mail.BaseURL)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
@@ -93,9 +245,61 @@ func New(ctx context.Context, cfg config.Config, log *slog.Logger, opts Options)
 	gameSrv.RegisterRoutes(mux)
 
 	// --- auth routes ---
+	mux.HandleFunc("/.well-known/jwks.json", authH.JWKS)
 	mux.HandleFunc("/api/auth/register", authH.Register)
 	mux.HandleFunc("/api/auth/login", authH.Login)
-	mux.Handle("/api/me", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(authH.Me)))
+	mux.HandleFunc("/api/auth/refresh", authH.Refresh)
+	mux.HandleFunc("/api/auth/logout", authH.Logout)
+	mux.HandleFunc("/api/auth/guest", authH.Guest)
+	mux.HandleFunc("/api/auth/password/forgot", authH.ForgotPassword)
+	mux.HandleFunc("/api/auth/password/reset", authH.ResetPassword)
+	mux.HandleFunc("/api/auth/email/verify", authH.VerifyEmail)
+	mux.Handle("/api/auth/email/resend", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(authH.ResendVerification)))
+	mux.HandleFunc("/api/auth/oidc/{provider}/start", authH.OIDCStart)
+	mux.HandleFunc("/api/auth/oidc/{provider}/callback", authH.OIDCCallback)
+	mux.Handle("/api/auth/upgrade", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(authH.UpgradeGuest)))
+	mux.Handle("/api/me", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(authH.MeRoutes)))
+	mux.Handle("/api/me/password", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(authH.ChangePassword)))
+	mux.Handle("/api/me/export", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(authH.Export)))
+
+	// --- public profiles ---
+	usersH := &httpapi.UsersHandler{Users: users, Stats: stats, History: history, Achievements: achievementStore, Presence: presenceTracker}
+	mux.HandleFunc("/api/users/{id}", usersH.Profile)
+	mux.HandleFunc("/api/users/{id}/matches", usersH.Matches)
+	mux.HandleFunc("/api/users/{id}/presence", usersH.PresenceStatus)
+	mux.HandleFunc("/api/achievements", httpapi.AchievementCatalog)
+
+	// --- daily puzzle ---
+	// the seed must stay the same across restarts and instances, or the day's secret changes
+	dailyH := &httpapi.DailyHandler{Daily: game.NewDaily([]byte(cfg.Game.DailySeed)), Results: dailyResults}
+	mux.Handle("/api/daily", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(dailyH.Get)))
+	mux.Handle("/api/daily/guess", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(dailyH.Guess)))
+	mux.HandleFunc("/api/daily/leaderboard", dailyH.Leaderboard)
+
+	// --- friends, challenges and notifications ---
+	friendsH := &httpapi.FriendsHandler{Users: users, Friends: friends, Matches: matchSvc, Hub: hub}
+	notifyH := &httpapi.NotificationsHandler{Hub: hub, Presence: presenceTracker}
+	mux.Handle("/api/friends", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(friendsH.List)))
+	mux.Handle("/api/friends/requests", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(friendsH.SendRequest)))
+	mux.Handle("/api/friends/requests/{id}/accept", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(friendsH.Accept)))
+	mux.Handle("/api/friends/requests/{id}/decline", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(friendsH.Decline)))
+	mux.Handle("/api/friends/{id}", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(friendsH.Remove)))
+	mux.Handle("/api/challenges", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(friendsH.Challenge)))
+	mux.Handle("/api/notifications/stream", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(notifyH.Stream)))
+
+	// --- public lobby ---
+	lobbyH := &httpapi.LobbyHandler{Lobby: lobby, Stats: stats}
+	mux.HandleFunc("/api/lobby", lobbyH.List)
+	mux.HandleFunc("/api/lobby/stream", lobbyH.Stream)
+
+	// --- tournaments ---
+	tournamentsH := &httpapi.TournamentsHandler{Users: users, Tournaments: tournaments, Service: tournamentSvc, Matches: matchSvc}
+	mux.HandleFunc("/api/tournaments", tournamentsH.Routes(httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(tournamentsH.Create))))
+	mux.HandleFunc("/api/tournaments/{id}", tournamentsH.Get)
+	mux.Handle("/api/tournaments/{id}/registration", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(tournamentsH.Registration)))
+	mux.Handle("/api/tournaments/{id}/start", httpapi.AuthMiddleware(authSvc)(http.HandlerFunc(tournamentsH.Start)))
+	mux.HandleFunc("/api/tournaments/{id}/standings", tournamentsH.Standings)
+	mux.HandleFunc("/api/tournaments/{id}/bracket", tournamentsH.Bracket)
 
 	if opts.Static != nil {
 		mux.Handle("/", opts.Static)
@@ -109,8 +313,37 @@ func New(ctx context.Context, cfg config.Config, log *slog.Logger, opts Options)
 		WriteTimeout:      cfg.HTTP.WriteTimeout,
 		IdleTimeout:       cfg.HTTP.IdleTimeout,
 	}
+	// Shutdown waits for active requests: end the notification and lobby streams so it does not hang on them
+	srv.RegisterOnShutdown(hub.Shutdown)
+	srv.RegisterOnShutdown(lobby.Shutdown)
 
-	return &App{cfg: cfg, log: log, db: dbpool, rdb: rdb, srv: srv}, nil
+	return &App{cfg: cfg, log: log, db: dbpool, rdb: rdb, srv: srv, mail: mailSender}, nil
+}
+
+// loadJWTKeys builds the JWT key set: JWT_SECRET (HS256, kid "legacy", unless JWT_LEGACY_VERIFY=false)
+// plus the PEM files from JWT_KEYS.
+func loadJWTKeys(cfg config.Config) (*auth.KeySet, error) {
+	var keys []*auth.Key
+	if cfg.Auth.LegacyVerify {
+		keys = append(keys, auth.NewHMACKey(auth.LegacyKID, []byte(cfg.Auth.Secret)))
+	}
+	for kid, path := range cfg.Auth.KeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		k, err := auth.ParseKeyPEM(kid, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	signing := cfg.Auth.SigningKeyID
+	if signing == "" {
+		signing = auth.LegacyKID
+	}
+	return auth.NewKeySet(signing, keys...)
 }
 
 func (a *App) Run(ctx context.Context) error {
@@ -126,6 +359,10 @@ func (a *App) Run(ctx context.Context) error {
 		return err
 	})
 
+	g.Go(func() error {
+		return a.mail.Run(gctx)
+	})
+
 	g.Go(func() error {
 		<-gctx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.HTTP.ShutdownTimeout)
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,54 +11,149 @@ import (
 type Claims struct {
 	UserID      string `json:"uid"`
 	DisplayName string `json:"name,omitempty"`
+	Guest       bool   `json:"guest,omitempty"` // guest account: unranked matches only
+	// IssuedAtMs is iat in unix milliseconds: revocation needs finer than iat's one second.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// Service is a small auth helper that encapsulates a signing secret.
-// It makes it easier to inject auth into handlers without passing raw []byte everywhere.
+// ErrTokenRevoked is returned by Service.Verify for tokens issued before the user's revocation timestamp.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// RevocationChecker reports when a user's tokens were last revoked (zero time = never).
+type RevocationChecker interface {
+	TokensRevokedAt(ctx context.Context, userID string) (time.Time, error)
+}
+
+// revocationTimeout bounds the revocation lookup done on every Verify.
+const revocationTimeout = 2 * time.Second
+
+// Service is a small auth helper that encapsulates the signing keys.
+// It makes it easier to inject auth into handlers without passing raw keys everywhere.
 type Service struct {
-	secret []byte
+	keys     *KeySet
+	issuer   string // "iss" claim; empty => not set and not checked
+	audience string // "aud" claim; empty => not set and not checked
+	revoked  RevocationChecker
 }
 
+// NewService signs and verifies HS256 tokens with a single secret (LegacyKID).
 func NewService(secret []byte) *Service {
-	// Copy to avoid accidental mutation from outside.
-	s := make([]byte, len(secret))
-	copy(s, secret)
-	return &Service{secret: s}
+	ks, _ := NewKeySet(LegacyKID, NewHMACKey(LegacyKID, secret))
+	return &Service{keys: ks}
+}
+
+// NewServiceWithKeys signs with the key set's signing key and verifies with any key in the set.
+func NewServiceWithKeys(keys *KeySet, issuer, audience string) *Service {
+	return &Service{keys: keys, issuer: issuer, audience: audience}
 }
 
 func (s *Service) Sign(userID string, ttl time.Duration) (string, error) {
-	return Sign(s.secret, userID, "", ttl)
+	return s.sign(Claims{UserID: userID}, ttl)
 }
 
 // SignWithName signs a token and embeds a displayName into claims.
 // Useful when you want to render nicknames client-side without an extra DB call.
 func (s *Service) SignWithName(userID, displayName string, ttl time.Duration) (string, error) {
-	return Sign(s.secret, userID, displayName, ttl)
+	return s.sign(Claims{UserID: userID, DisplayName: displayName}, ttl)
 }
 
-func (s *Service) Verify(token string) (*Claims, error) {
-	return Verify(s.secret, token)
+// JWKS returns the public verification keys for /.well-known/jwks.json.
+func (s *Service) JWKS() JWKS {
+	return s.keys.JWKS()
 }
 
-func Sign(secret []byte, userID, displayName string, ttl time.Duration) (string, error) {
-	claims := Claims{
-		UserID:      userID,
-		DisplayName: displayName,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// SetRevocationChecker makes Verify reject tokens issued before the user's revocation timestamp.
+func (s *Service) SetRevocationChecker(c RevocationChecker) {
+	s.revoked = c
+}
+
+// SignGuest signs a token for a guest account (Claims.Guest = true).
+func (s *Service) SignGuest(userID, displayName string, ttl time.Duration) (string, error) {
+	return s.sign(Claims{UserID: userID, DisplayName: displayName, Guest: true}, ttl)
+}
+
+func (s *Service) sign(claims Claims, ttl time.Duration) (string, error) {
+	k := s.keys.signing
+	claims.RegisteredClaims = registered(ttl)
+	claims.IssuedAtMs = claims.IssuedAt.UnixMilli()
+	claims.Issuer = s.issuer
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+
+	t := jwt.NewWithClaims(k.method(), claims)
+	t.Header["kid"] = k.ID
+	return t.SignedString(k.signingKey())
+}
+
+// Verify checks the token and, with a RevocationChecker set, that it wasn't revoked.
+// ctx bounds the revocation lookup (usually the request context).
+func (s *Service) Verify(ctx context.Context, token string) (*Claims, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods(s.keys.algs()), jwt.WithExpirationRequired()}
+	if s.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.issuer))
+	}
+	if s.audience != "" {
+		opts = append(opts, jwt.WithAudience(s.audience))
+	}
+	claims, err := parse(token, s.keys.keyFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if s.revoked == nil {
+		return claims, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, revocationTimeout)
+	defer cancel()
+	at, err := s.revoked.TokensRevokedAt(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !at.IsZero() && issuedBefore(claims, at) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// issuedBefore reports whether the token was issued no later than the revocation at.
+// Tokens without iat_ms (signed before it was added) only have iat's one-second precision:
+// those issued in the same second as a logout are still accepted until they expire.
+func issuedBefore(claims *Claims, at time.Time) bool {
+	switch {
+	case claims.IssuedAtMs > 0:
+		return claims.IssuedAtMs <= at.UnixMilli()
+	case claims.IssuedAt != nil:
+		return claims.IssuedAt.Time.Before(at.Truncate(time.Second))
+	default:
+		return true
 	}
+}
 
+func Sign(secret []byte, userID, displayName string, ttl time.Duration) (string, error) {
+	claims := Claims{UserID: userID, DisplayName: displayName, RegisteredClaims: registered(ttl)}
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return t.SignedString(secret)
 }
 
+// Verify checks an HS256 token; any other algorithm is rejected.
 func Verify(secret []byte, token string) (*Claims, error) {
-	t, err := jwt.ParseWithClaims(token, &Claims{}, func(t *jwt.Token) (any, error) {
+	return parse(token, func(*jwt.Token) (any, error) {
 		return secret, nil
-	})
+	}, jwt.WithValidMethods([]string{AlgHS256}))
+}
+
+func registered(ttl time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+}
+
+func parse(token string, keyFunc jwt.Keyfunc, opts ...jwt.ParserOption) (*Claims, error) {
+	t, err := jwt.ParseWithClaims(token, &Claims{}, keyFunc, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+// stubRevocations answers TokensRevokedAt and remembers the context it was called with.
+type stubRevocations struct {
+	at  time.Time
+	ctx context.Context
+}
+
+func (s *stubRevocations) TokensRevokedAt(ctx context.Context, userID string) (time.Time, error) {
+	s.ctx = ctx
+	return s.at, nil
+}
+
+func TestService_VerifyRevoked(t *testing.T) {
+	secret := []byte("test-secret")
+	// signAt signs a token as if issued at iat; withMs=false mimics tokens signed before iat_ms.
+	signAt := func(t *testing.T, iat time.Time, withMs bool) string {
+		claims := Claims{UserID: "u1", RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(iat),
+			ExpiresAt: jwt.NewNumericDate(iat.Add(time.Hour)),
+		}}
+		if withMs {
+			claims.IssuedAtMs = iat.UnixMilli()
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		tok.Header["kid"] = LegacyKID
+		s, err := tok.SignedString(secret)
+		require.NoError(t, err)
+		return s
+	}
+
+	logout := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	cases := []struct {
+		name      string
+		revokedAt time.Time
+		token     func(t *testing.T) string
+		wantErr   error
+	}{
+		{name: "never revoked", token: func(t *testing.T) string { return signAt(t, logout, true) }},
+		{name: "issued before logout", revokedAt: logout, token: func(t *testing.T) string { return signAt(t, logout.Add(-time.Minute), true) }, wantErr: ErrTokenRevoked},
+		{name: "issued earlier in the logout second", revokedAt: logout, token: func(t *testing.T) string { return signAt(t, logout.Add(-100*time.Millisecond), true) }, wantErr: ErrTokenRevoked},
+		{name: "issued later in the logout second", revokedAt: logout, token: func(t *testing.T) string { return signAt(t, logout.Add(100*time.Millisecond), true) }},
+		{name: "legacy token from the logout second is still accepted", revokedAt: logout, token: func(t *testing.T) string { return signAt(t, logout.Add(-100*time.Millisecond), false) }},
+		{name: "legacy token from an earlier second", revokedAt: logout, token: func(t *testing.T) string { return signAt(t, logout.Add(-time.Second), false) }, wantErr: ErrTokenRevoked},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rev := &stubRevocations{at: tc.revokedAt}
+			svc := NewService(secret)
+			svc.SetRevocationChecker(rev)
+
+			ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+			claims, err := svc.Verify(ctx, tc.token(t))
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "u1", claims.UserID)
+			assert.Equal(t, "request", rev.ctx.Value(ctxKey{}), "the lookup runs on the caller's context")
+		})
+	}
+}
+
+func TestService_SignSetsIssuedAtMs(t *testing.T) {
+	svc := NewService([]byte("test-secret"))
+	tok, err := svc.Sign("u1", time.Hour)
+	require.NoError(t, err)
+
+	claims, err := svc.Verify(context.Background(), tok)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().UnixMilli(), claims.IssuedAtMs, float64(time.Minute.Milliseconds()))
+	assert.Equal(t, claims.IssuedAt.Unix(), claims.IssuedAtMs/1000)
+}
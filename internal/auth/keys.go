@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// LegacyKID is the key used for tokens without a "kid" header
+// (issued before key rotation was introduced).
+const LegacyKID = "legacy"
+
+// Key is a single JWT key. Private (or HMAC) keys can sign; public-only keys can only verify,
+// which is how retired keys are kept around during rotation.
+type Key struct {
+	ID  string
+	Alg string
+
+	secret  []byte           // HS256
+	private crypto.Signer    // RS256/EdDSA; nil => verify-only
+	public  crypto.PublicKey // RS256/EdDSA
+}
+
+func NewHMACKey(id string, secret []byte) *Key {
+	s := make([]byte, len(secret))
+	copy(s, secret)
+	return &Key{ID: id, Alg: AlgHS256, secret: s}
+}
+
+// NewPrivateKey wraps an RSA or Ed25519 private key; the algorithm follows from the key type.
+func NewPrivateKey(id string, priv crypto.Signer) (*Key, error) {
+	k, err := NewPublicKey(id, priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	k.private = priv
+	return k, nil
+}
+
+// NewPublicKey makes a verify-only key.
+func NewPublicKey(id string, pub crypto.PublicKey) (*Key, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("key %q: RSA key must be at least 2048 bits", id)
+		}
+		return &Key{ID: id, Alg: AlgRS256, public: pub}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Alg: AlgEdDSA, public: pub}, nil
+	default:
+		return nil, fmt.Errorf("key %q: unsupported key type %T", id, pub)
+	}
+}
+
+// ParseKeyPEM reads a PKCS#8/PKCS#1 private key or a PKIX public key.
+func ParseKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("key %q: unsupported private key type %T", id, priv)
+		}
+		return NewPrivateKey(id, signer)
+	case "RSA PRIVATE KEY":
+		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewPrivateKey(id, priv)
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewPublicKey(id, pub)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported PEM block %q", id, block.Type)
+	}
+}
+
+func (k *Key) canSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func (k *Key) signingKey() any {
+	if k.secret != nil {
+		return k.secret
+	}
+	return k.private
+}
+
+func (k *Key) verifyKey() any {
+	if k.secret != nil {
+		return k.secret
+	}
+	return k.public
+}
+
+// KeySet holds every key that may verify tokens and the one key that signs new tokens.
+// Rotation: add the new key, make it the signing key, keep the old one (public part is enough)
+// until all tokens it signed have expired.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+func NewKeySet(signingKID string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, k := range keys {
+		if _, dup := ks.keys[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		ks.keys[k.ID] = k
+	}
+	signing, ok := ks.keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not in the key set", signingKID)
+	}
+	if !signing.canSign() {
+		return nil, fmt.Errorf("signing key %q has no private part", signingKID)
+	}
+	ks.signing = signing
+	return ks, nil
+}
+
+// keyFunc resolves the verification key by "kid" and rejects any algorithm
+// other than the one the key was configured with.
+func (ks *KeySet) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKID
+	}
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if t.Method.Alg() != k.Alg {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", t.Method.Alg(), kid)
+	}
+	return k.verifyKey(), nil
+}
+
+func (ks *KeySet) algs() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, k := range ks.keys {
+		if !seen[k.Alg] {
+			seen[k.Alg] = true
+			out = append(out, k.Alg)
+		}
+	}
+	return out
+}
+
+// JWKS is the /.well-known/jwks.json document.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+
+	N string `json:"n,omitempty"` // RSA
+	E string `json:"e,omitempty"` // RSA
+
+	Crv string `json:"crv,omitempty"` // OKP/EC
+	X   string `json:"x,omitempty"`   // OKP/EC
+	Y   string `json:"y,omitempty"`   // EC
+}
+
+// PublicKey decodes an RSA, EC (P-256) or Ed25519 public key from a JWK.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: n: %w", j.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: e: %w", j.Kid, err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("jwk %q: bad RSA exponent", j.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("jwk %q: unsupported curve %q", j.Kid, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: x: %w", j.Kid, err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: y: %w", j.Kid, err)
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("jwk %q: bad P-256 point", j.Kid)
+		}
+		// ecdh validates that the point (uncompressed SEC 1: 0x04 || X || Y) is on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", j.Kid, err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("jwk %q: unsupported curve %q", j.Kid, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk %q: bad Ed25519 key", j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("jwk %q: unsupported key type %q", j.Kid, j.Kty)
+	}
+}
+
+// JWKS publishes the public keys. HMAC keys are secret and never published.
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			out.Keys = append(out.Keys, JWK{
+				Kty: "RSA", Kid: k.ID, Alg: k.Alg, Use: "sig",
+				N: b64(pub.N.Bytes()),
+				E: b64(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			out.Keys = append(out.Keys, JWK{
+				Kty: "OKP", Kid: k.ID, Alg: k.Alg, Use: "sig",
+				Crv: "Ed25519", X: b64(pub),
+			})
+		}
+	}
+	sort.Slice(out.Keys, func(i, j int) bool { return out.Keys[i].Kid < out.Keys[j].Kid })
+	return out
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
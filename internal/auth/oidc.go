@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrOIDCNonce is returned when the ID token's nonce does not match the login request.
+var ErrOIDCNonce = errors.New("oidc: nonce mismatch")
+
+// oidcKeysRefresh limits how often an unknown "kid" triggers a JWKS refetch.
+const oidcKeysRefresh = time.Minute
+
+// OIDCConfig describes an external OpenID Connect provider.
+type OIDCConfig struct {
+	Name         string
+	Issuer       string // discovery: Issuer + "/.well-known/openid-configuration"
+	ClientID     string
+	ClientSecret string // empty => public client, PKCE only
+	RedirectURL  string // our /api/auth/oidc/{provider}/callback
+	Scopes       []string
+}
+
+// OIDCAuthRequest is a pending login kept by the caller between start and callback.
+type OIDCAuthRequest struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code_verifier
+}
+
+// OIDCIdentity is the verified subject of an ID token.
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type idTokenClaims struct {
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"` // bool, but some providers send "true"
+	Name          string `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// OIDCProvider runs the authorization code flow with PKCE (S256) against one provider.
+// Discovery and JWKS are fetched lazily and cached.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	disc        *oidcDiscovery
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+// NewOIDCProvider: client nil => a plain http.Client with a 10s timeout.
+func NewOIDCProvider(cfg OIDCConfig, client *http.Client) *OIDCProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &OIDCProvider{cfg: cfg, client: client}
+}
+
+func (p *OIDCProvider) Name() string { return p.cfg.Name }
+
+// NewOIDCAuthRequest generates state, nonce and the PKCE verifier for one login.
+func NewOIDCAuthRequest() (OIDCAuthRequest, error) {
+	var out OIDCAuthRequest
+	for _, f := range []*string{&out.State, &out.Nonce, &out.Verifier} {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return OIDCAuthRequest{}, err
+		}
+		*f = b64(b)
+	}
+	return out, nil
+}
+
+// AuthURL is where the browser is redirected to sign in.
+func (p *OIDCProvider) AuthURL(ctx context.Context, req OIDCAuthRequest) (string, error) {
+	disc, err := p.discovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(req.Verifier))
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", req.State)
+	q.Set("nonce", req.Nonce)
+	q.Set("code_challenge", b64(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(disc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return disc.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades the authorization code for tokens and verifies the ID token.
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, req OIDCAuthRequest) (OIDCIdentity, error) {
+	disc, err := p.discovery(ctx)
+	if err != nil {
+		return OIDCIdentity{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", req.Verifier)
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, disc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return OIDCIdentity{}, err
+	}
+	hreq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	hreq.Header.Set("Accept", "application/json")
+
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.doJSON(hreq, &tok); err != nil {
+		return OIDCIdentity{}, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	if tok.IDToken == "" {
+		return OIDCIdentity{}, errors.New("oidc token exchange: no id_token in response")
+	}
+	return p.verifyIDToken(ctx, tok.IDToken, req.Nonce)
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, nonce string) (OIDCIdentity, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return OIDCIdentity{}, fmt.Errorf("oidc id_token: %w", err)
+	}
+	if claims.Nonce != nonce {
+		return OIDCIdentity{}, ErrOIDCNonce
+	}
+	if claims.Subject == "" {
+		return OIDCIdentity{}, errors.New("oidc id_token: empty sub")
+	}
+
+	verified := claims.EmailVerified == true || claims.EmailVerified == "true"
+	return OIDCIdentity{
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: verified,
+		Name:          claims.Name,
+	}, nil
+}
+
+func (p *OIDCProvider) discovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.disc != nil {
+		return p.disc, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var d oidcDiscovery
+	if err := p.doJSON(req, &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing endpoints")
+	}
+	p.disc = &d
+	return p.disc, nil
+}
+
+// key returns the provider's verification key by kid; an unknown kid refetches JWKS
+// (the provider rotated its keys), at most once per oidcKeysRefresh.
+func (p *OIDCProvider) key(ctx context.Context, kid string) (any, error) {
+	disc, err := p.discovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.lookupKeyLocked(kid); ok {
+		return k, nil
+	}
+	if time.Since(p.keysFetched) < oidcKeysRefresh {
+		return nil, fmt.Errorf("oidc: unknown key id %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, disc.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set JWKS
+	if err := p.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		pub, err := j.PublicKey()
+		if err != nil {
+			continue // skip keys we do not support, use the rest
+		}
+		keys[j.Kid] = pub
+	}
+	p.keys, p.keysFetched = keys, time.Now()
+
+	if k, ok := p.lookupKeyLocked(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("oidc: unknown key id %q", kid)
+}
+
+// lookupKeyLocked: a token without kid is accepted only if the provider has exactly one key.
+func (p *OIDCProvider) lookupKeyLocked(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func (p *OIDCProvider) doJSON(req *http.Request, v any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// EncodeOIDCAuthRequest packs a pending login into an HMAC-signed string (for a short-lived cookie),
+// so start and callback may hit different instances without shared storage.
+func EncodeOIDCAuthRequest(key []byte, provider string, req OIDCAuthRequest, ttl time.Duration) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, oidcStateClaims{
+		OIDCAuthRequest: req,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   provider,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}).SignedString(key)
+}
+
+// DecodeOIDCAuthRequest verifies and unpacks EncodeOIDCAuthRequest for the given provider.
+func DecodeOIDCAuthRequest(key []byte, provider, s string) (OIDCAuthRequest, error) {
+	var claims oidcStateClaims
+	_, err := jwt.ParseWithClaims(s, &claims, func(*jwt.Token) (any, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{AlgHS256}), jwt.WithSubject(provider), jwt.WithExpirationRequired())
+	if err != nil {
+		return OIDCAuthRequest{}, err
+	}
+	return claims.OIDCAuthRequest, nil
+}
+
+type oidcStateClaims struct {
+	OIDCAuthRequest
+	jwt.RegisteredClaims
+}
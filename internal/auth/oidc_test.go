@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOIDC is a minimal local OIDC provider: discovery, JWKS, authorize (issues a code right away)
+// and a token endpoint that checks PKCE.
+type fakeOIDC struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	audience string // id_token aud; "" => clientID
+
+	mu    sync.Mutex
+	codes map[string]url.Values // code => authorize params
+}
+
+func newFakeOIDC(t *testing.T) *fakeOIDC {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	f := &fakeOIDC{key: key, clientID: "bc-client", codes: make(map[string]url.Values)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub, err := NewPublicKey("fk1", &key.PublicKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ks := &KeySet{keys: map[string]*Key{"fk1": pub}}
+		_ = json.NewEncoder(w).Encode(ks.JWKS())
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.codes["code-1"] = r.URL.Query()
+		f.mu.Unlock()
+		to, _ := url.Parse(r.URL.Query().Get("redirect_uri"))
+		q := to.Query()
+		q.Set("code", "code-1")
+		q.Set("state", r.URL.Query().Get("state"))
+		to.RawQuery = q.Encode()
+		http.Redirect(w, r, to.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		f.mu.Lock()
+		auth, ok := f.codes[r.PostForm.Get("code")]
+		delete(f.codes, r.PostForm.Get("code"))
+		f.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || b64(sum[:]) != auth.Get("code_challenge") || r.PostForm.Get("client_id") != f.clientID {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		aud := f.audience
+		if aud == "" {
+			aud = f.clientID
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            f.URL,
+			"sub":            "ext-42",
+			"aud":            aud,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          auth.Get("nonce"),
+			"email":          "Alice@Example.com",
+			"email_verified": true,
+			"name":           "Alice",
+		})
+		tok.Header["kid"] = "fk1"
+		idToken, err := tok.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "id_token": idToken})
+	})
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// authorize follows the redirect to the provider and returns the code it sends back.
+func (f *fakeOIDC) authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestOIDCProvider(t *testing.T) {
+	ctx := context.Background()
+	newProvider := func(f *fakeOIDC) *OIDCProvider {
+		return NewOIDCProvider(OIDCConfig{
+			Name:        "fake",
+			Issuer:      f.URL,
+			ClientID:    f.clientID,
+			RedirectURL: "http://bc.local/api/auth/oidc/fake/callback",
+		}, f.Client())
+	}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "code flow with PKCE returns the verified identity",
+			run: func(t *testing.T) {
+				f := newFakeOIDC(t)
+				p := newProvider(f)
+				req, err := NewOIDCAuthRequest()
+				require.NoError(t, err)
+
+				authURL, err := p.AuthURL(ctx, req)
+				require.NoError(t, err)
+				code, state := f.authorize(t, authURL)
+				assert.Equal(t, req.State, state)
+
+				id, err := p.Exchange(ctx, code, req)
+				require.NoError(t, err)
+				assert.Equal(t, OIDCIdentity{Subject: "ext-42", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}, id)
+			},
+		},
+		{
+			name: "wrong code verifier is rejected by the provider",
+			run: func(t *testing.T) {
+				f := newFakeOIDC(t)
+				p := newProvider(f)
+				req, err := NewOIDCAuthRequest()
+				require.NoError(t, err)
+				authURL, err := p.AuthURL(ctx, req)
+				require.NoError(t, err)
+				code, _ := f.authorize(t, authURL)
+
+				req.Verifier = "not-the-verifier"
+				_, err = p.Exchange(ctx, code, req)
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "nonce from another login is rejected",
+			run: func(t *testing.T) {
+				f := newFakeOIDC(t)
+				p := newProvider(f)
+				req, err := NewOIDCAuthRequest()
+				require.NoError(t, err)
+				authURL, err := p.AuthURL(ctx, req)
+				require.NoError(t, err)
+				code, _ := f.authorize(t, authURL)
+
+				req.Nonce = "other"
+				_, err = p.Exchange(ctx, code, req)
+				require.ErrorIs(t, err, ErrOIDCNonce)
+			},
+		},
+		{
+			name: "id token for another client is rejected",
+			run: func(t *testing.T) {
+				f := newFakeOIDC(t)
+				f.audience = "someone-else"
+				p := newProvider(f)
+				req, err := NewOIDCAuthRequest()
+				require.NoError(t, err)
+				authURL, err := p.AuthURL(ctx, req)
+				require.NoError(t, err)
+				code, _ := f.authorize(t, authURL)
+
+				_, err = p.Exchange(ctx, code, req)
+				require.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+			},
+		},
+		{
+			name: "pending login cookie is bound to key and provider",
+			run: func(t *testing.T) {
+				req, err := NewOIDCAuthRequest()
+				require.NoError(t, err)
+				s, err := EncodeOIDCAuthRequest([]byte("k1"), "fake", req, time.Minute)
+				require.NoError(t, err)
+
+				got, err := DecodeOIDCAuthRequest([]byte("k1"), "fake", s)
+				require.NoError(t, err)
+				assert.Equal(t, req, got)
+
+				_, err = DecodeOIDCAuthRequest([]byte("k2"), "fake", s)
+				require.Error(t, err)
+				_, err = DecodeOIDCAuthRequest([]byte("k1"), "other", s)
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Password policy.
+const (
+	MinPasswordLen = 8
+	// MaxPasswordBytes: bcrypt ignores everything after 72 bytes.
+	MaxPasswordBytes = 72
+)
+
+var (
+	ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters", MinPasswordLen)
+	ErrPasswordTooLong  = fmt.Errorf("password must be at most %d bytes", MaxPasswordBytes)
+	ErrPasswordCommon   = errors.New("password is too common")
+	ErrPasswordPersonal = errors.New("password must not contain your email or display name")
+)
+
+//go:embed data/common_passwords.txt
+var commonPasswordsRaw string
+
+var commonPasswords = parseWordList(commonPasswordsRaw)
+
+// ValidatePassword checks a new password against the policy: length, the embedded list
+// of breached/common passwords and the user's own email and display name.
+func ValidatePassword(password, email, displayName string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLen {
+		return ErrPasswordTooShort
+	}
+	if len(password) > MaxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := commonPasswords[lower]; ok {
+		return ErrPasswordCommon
+	}
+	// a single repeated character ("aaaaaaaa") is as weak as anything on the list
+	if first, _ := utf8.DecodeRuneInString(lower); strings.Trim(lower, string(first)) == "" {
+		return ErrPasswordCommon
+	}
+
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	for _, personal := range []string{local, strings.ToLower(strings.TrimSpace(displayName))} {
+		if utf8.RuneCountInString(personal) >= 4 && strings.Contains(lower, personal) {
+			return ErrPasswordPersonal
+		}
+	}
+	return nil
+}
+
+func parseWordList(raw string) map[string]struct{} {
+	out := make(map[string]struct{})
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out[strings.ToLower(line)] = struct{}{}
+	}
+	return out
+}
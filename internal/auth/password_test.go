@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePassword(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{name: "strong password", password: "correct horse battery", want: nil},
+		{name: "too short", password: "x7#kq2", want: ErrPasswordTooShort},
+		{name: "length counts characters, not bytes", password: "пароль", want: ErrPasswordTooShort},
+		{name: "too long for bcrypt", password: strings.Repeat("ab1", 25), want: ErrPasswordTooLong},
+		{name: "common password", password: "password123", want: ErrPasswordCommon},
+		{name: "common list is case-insensitive", password: "QwertyUiop", want: ErrPasswordCommon},
+		{name: "single repeated character", password: "zzzzzzzzzz", want: ErrPasswordCommon},
+		{name: "contains email name", password: "alice.smith-2024", want: ErrPasswordPersonal},
+		{name: "contains display name", password: "xXBullKingXx", want: ErrPasswordPersonal},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ValidatePassword(tc.password, "alice.smith@example.com", "BullKing"))
+		})
+	}
+}
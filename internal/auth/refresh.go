@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewRefreshToken returns an opaque refresh token for the client and its hash for storage.
+// Refresh tokens are random (not JWT): they are only ever checked against the database.
+func NewRefreshToken() (token, hash string, err error) {
+	return NewOpaqueToken()
+}
+
+// HashRefreshToken is the value stored in refresh_tokens.token_hash.
+func HashRefreshToken(token string) string {
+	return HashOpaqueToken(token)
+}
+
+// NewOpaqueToken returns a random 256-bit token (base64url) and its hash.
+// Used for refresh tokens and for single-use links (password reset, email verification).
+func NewOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken is the value stored instead of the token.
+// A fast hash is fine here: tokens have 256 bits of entropy, unlike passwords.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
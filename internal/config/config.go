@@ -1,10 +1,12 @@
 package config
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,6 +30,7 @@ type Config struct {
 		WriteTimeout      time.Duration
 		IdleTimeout       time.Duration
 		ShutdownTimeout   time.Duration
+		TrustProxy        bool // take client IP from X-Forwarded-For (set only behind a reverse proxy)
 	}
 
 	Postgres struct {
@@ -40,18 +43,88 @@ type Config struct {
 		Addr     string
 		DB       int
 		MatchTTL time.Duration
+
+		// Encryption of secrets in match snapshots (AES-GCM).
+		// SnapshotKeys: kid => AES key (16/24/32 bytes); SnapshotKeyID is the key new snapshots are sealed with.
+		// Old keys stay in SnapshotKeys for decryption during rotation. Empty => plaintext snapshots.
+		SnapshotKeyID  string
+		SnapshotKeys   map[string][]byte
+		SnapshotReseal bool // re-encrypt existing snapshots with SnapshotKeyID on startup
+
+		// Presence (internal/presence): a session expires unless its connection's heartbeat
+		// (every 25s) refreshes it within PresenceTTL; online turns idle after PresenceIdleAfter
+		// without client messages.
+		PresenceTTL       time.Duration
+		PresenceIdleAfter time.Duration
 	}
 
 	Auth struct {
-		Secret   string
-		TokenTTL time.Duration
+		Secret     string
+		TokenTTL   time.Duration // access token lifetime
+		RefreshTTL time.Duration // refresh token lifetime
+
+		// Asymmetric JWT keys: kid => PEM file (RSA >= 2048 bits or Ed25519).
+		// A private key can sign, a public key only verifies: to rotate, add the new key,
+		// point SigningKeyID at it and keep the old one (public part is enough) until its tokens expire.
+		// Secret stays as the HS256 key with kid "legacy"; SigningKeyID empty => sign with it.
+		// Once its tokens have expired, LegacyVerify=false drops it so JWT_SECRET can no longer mint tokens.
+		KeyFiles     map[string]string
+		SigningKeyID string
+		LegacyVerify bool
+		Issuer       string
+		Audience     string
+
+		// Login brute-force protection (per IP and per account, see internal/ratelimit).
+		LoginIPFree        int           // failures per IP before backoff starts
+		LoginAccountFree   int           // failures per account before backoff starts
+		LoginLockoutAfter  int           // failures per account that lock it for LoginLockoutFor
+		LoginLockoutFor    time.Duration // also the cap for the exponential backoff
+		LoginFailureWindow time.Duration // failures older than this are forgotten
+
+		// External OpenID Connect providers: OIDC_PROVIDERS=google,dev plus OIDC_<NAME>_* per provider.
+		OIDC []OIDCProvider
+		// OIDCStateKey signs the pending-login cookie; independent of JWT_SECRET.
+		OIDCStateKey string
+	}
+
+	Mail struct {
+		Driver       string // log|file (dev only) or smtp
+		Dir          string // FileMailer output dir
+		SMTPAddr     string // host:port of the SMTP relay
+		SMTPUsername string // optional: PLAIN auth
+		SMTPPassword string
+		From         string
+		BaseURL      string        // frontend origin for links in emails
+		PollInterval time.Duration // outbox poll interval
+		ResetTTL     time.Duration // password reset link lifetime
+		VerifyTTL    time.Duration // email verification link lifetime
 	}
 
 	Game struct {
 		RoundDuration time.Duration
+		SeriesBestOf  int           // 0 => unlimited rematches
+		MaxRounds     int           // 0 => no round limit
+		Tiebreak      string        // last_round_bulls|total_score|draw
+		Mode          string        // simultaneous|alternating
+		Players       int           // 2..8 (2 = duel)
+		Target        string        // next|shared
+		LobbyTTL      time.Duration // public matches drop out of the lobby after this long without an opponent
+		InviteTTL     time.Duration // lifetime of short invite codes (GET /api/invite/{code})
+		JoinTimeout   time.Duration // tournament players who haven't joined their match by then forfeit it
+		DailySeed     string        // picks the daily puzzle secrets; changing it changes today's secret
 	}
 }
 
+// OIDCProvider is one external identity provider (authorization code flow with PKCE).
+type OIDCProvider struct {
+	Name         string // path segment: /api/auth/oidc/{name}/start
+	Issuer       string
+	ClientID     string
+	ClientSecret string // optional: public clients rely on PKCE alone
+	RedirectURL  string // must point at /api/auth/oidc/{name}/callback
+	Scopes       []string
+}
+
 func LoadFromEnv() (Config, error) {
 	var c Config
 
@@ -65,6 +138,7 @@ func LoadFromEnv() (Config, error) {
 	c.HTTP.WriteTimeout = envDuration("HTTP_WRITE_TIMEOUT", 0)
 	c.HTTP.IdleTimeout = envDuration("HTTP_IDLE_TIMEOUT", 60*time.Second)
 	c.HTTP.ShutdownTimeout = envDuration("HTTP_SHUTDOWN_TIMEOUT", 10*time.Second)
+	c.HTTP.TrustProxy = envBool("HTTP_TRUST_PROXY", false)
 
 	c.Postgres.URL = envString("DATABASE_URL", "postgres://bc:bc@localhost:5432/bc?sslmode=disable")
 	c.Postgres.RunMigrations = envBool("RUN_MIGRATIONS", false)
@@ -73,11 +147,58 @@ func LoadFromEnv() (Config, error) {
 	c.Redis.Addr = envString("REDIS_ADDR", "localhost:6379")
 	c.Redis.DB = envInt("REDIS_DB", 0)
 	c.Redis.MatchTTL = envDuration("MATCH_TTL", 24*time.Hour)
+	c.Redis.SnapshotKeyID = envString("SNAPSHOT_KEY_ID", "")
+	keys, err := parseKeys(envString("SNAPSHOT_KEYS", ""))
+	if err != nil {
+		return Config{}, fmt.Errorf("SNAPSHOT_KEYS: %w", err)
+	}
+	c.Redis.SnapshotKeys = keys
+	c.Redis.SnapshotReseal = envBool("SNAPSHOT_RESEAL", false)
+	c.Redis.PresenceTTL = envDuration("PRESENCE_TTL", 90*time.Second)
+	c.Redis.PresenceIdleAfter = envDuration("PRESENCE_IDLE_AFTER", 5*time.Minute)
 
 	c.Auth.Secret = envString("JWT_SECRET", "dev-secret-change-me")
-	c.Auth.TokenTTL = envDuration("JWT_TTL", 24*time.Hour)
+	c.Auth.TokenTTL = envDuration("JWT_TTL", 15*time.Minute)
+	c.Auth.RefreshTTL = envDuration("JWT_REFRESH_TTL", 30*24*time.Hour)
+	keyFiles, err := parseKeyFiles(envString("JWT_KEYS", ""))
+	if err != nil {
+		return Config{}, fmt.Errorf("JWT_KEYS: %w", err)
+	}
+	c.Auth.KeyFiles = keyFiles
+	c.Auth.SigningKeyID = envString("JWT_SIGNING_KEY_ID", "")
+	c.Auth.LegacyVerify = envBool("JWT_LEGACY_VERIFY", true)
+	c.Auth.Issuer = envString("JWT_ISSUER", "bc-mvp")
+	c.Auth.Audience = envString("JWT_AUDIENCE", "bc-mvp")
+	c.Auth.LoginIPFree = envInt("LOGIN_IP_FREE_ATTEMPTS", 20)
+	c.Auth.LoginAccountFree = envInt("LOGIN_ACCOUNT_FREE_ATTEMPTS", 5)
+	c.Auth.LoginLockoutAfter = envInt("LOGIN_LOCKOUT_AFTER", 10)
+	c.Auth.LoginLockoutFor = envDuration("LOGIN_LOCKOUT", 15*time.Minute)
+	c.Auth.LoginFailureWindow = envDuration("LOGIN_FAILURE_WINDOW", time.Hour)
+	c.Auth.OIDC = loadOIDCProviders(envString("OIDC_PROVIDERS", ""))
+	c.Auth.OIDCStateKey = envString("OIDC_STATE_KEY", "dev-oidc-state-change-me")
+
+	c.Mail.Driver = envString("MAIL_DRIVER", "log")
+	c.Mail.Dir = envString("MAIL_DIR", "./tmp/mail")
+	c.Mail.SMTPAddr = envString("SMTP_ADDR", "")
+	c.Mail.SMTPUsername = envString("SMTP_USERNAME", "")
+	c.Mail.SMTPPassword = envString("SMTP_PASSWORD", "")
+	c.Mail.From = envString("MAIL_FROM", "Bulls & Cows <no-reply@localhost>")
+	c.Mail.BaseURL = envString("APP_BASE_URL", "http://localhost:8080")
+	c.Mail.PollInterval = envDuration("MAIL_POLL_INTERVAL", 5*time.Second)
+	c.Mail.ResetTTL = envDuration("PASSWORD_RESET_TTL", time.Hour)
+	c.Mail.VerifyTTL = envDuration("EMAIL_VERIFY_TTL", 48*time.Hour)
 
 	c.Game.RoundDuration = envDuration("ROUND_DURATION", 0)
+	c.Game.SeriesBestOf = envInt("SERIES_BEST_OF", 0)
+	c.Game.MaxRounds = envInt("MAX_ROUNDS", 0)
+	c.Game.Tiebreak = envString("TIEBREAK", "draw")
+	c.Game.Mode = envString("GAME_MODE", "simultaneous")
+	c.Game.Players = envInt("MATCH_PLAYERS", 2)
+	c.Game.Target = envString("MATCH_TARGET", "next")
+	c.Game.LobbyTTL = envDuration("LOBBY_TTL", 30*time.Minute)
+	c.Game.InviteTTL = envDuration("INVITE_TTL", 24*time.Hour)
+	c.Game.JoinTimeout = envDuration("TOURNAMENT_JOIN_TIMEOUT", 10*time.Minute)
+	c.Game.DailySeed = envString("DAILY_SEED", "dev-daily-seed-change-me")
 
 	if err := c.Validate(); err != nil {
 		return Config{}, err
@@ -95,11 +216,98 @@ func (c Config) Validate() error {
 	if c.Redis.Addr == "" {
 		return errors.New("REDIS_ADDR is empty")
 	}
-	if c.Auth.Secret == "" {
-		return errors.New("JWT_SECRET is empty")
+	if c.Redis.PresenceTTL < 30*time.Second {
+		return fmt.Errorf("PRESENCE_TTL must be at least 30s (heartbeat is 25s), got %s", c.Redis.PresenceTTL)
+	}
+	if c.Auth.LegacyVerify {
+		if c.Auth.Secret == "" {
+			return errors.New("JWT_SECRET is empty")
+		}
+		if c.Env != "dev" && c.Auth.Secret == "dev-secret-change-me" {
+			return fmt.Errorf("refuse to run with default JWT_SECRET in %s", c.Env)
+		}
+	} else if c.Auth.SigningKeyID == "" || c.Auth.SigningKeyID == "legacy" {
+		return errors.New("JWT_LEGACY_VERIFY=false requires JWT_SIGNING_KEY_ID from JWT_KEYS")
+	}
+	if c.Auth.TokenTTL <= 0 || c.Auth.RefreshTTL < c.Auth.TokenTTL {
+		return fmt.Errorf("want 0 < JWT_TTL <= JWT_REFRESH_TTL, got %s and %s", c.Auth.TokenTTL, c.Auth.RefreshTTL)
+	}
+	if c.Auth.SigningKeyID != "" && c.Auth.SigningKeyID != "legacy" {
+		if _, ok := c.Auth.KeyFiles[c.Auth.SigningKeyID]; !ok {
+			return fmt.Errorf("JWT_SIGNING_KEY_ID=%q is not in JWT_KEYS", c.Auth.SigningKeyID)
+		}
+	}
+	if _, ok := c.Auth.KeyFiles["legacy"]; ok {
+		return errors.New(`JWT_KEYS: kid "legacy" is reserved for JWT_SECRET`)
+	}
+	if c.Auth.LoginIPFree < 0 || c.Auth.LoginAccountFree < 0 || c.Auth.LoginLockoutAfter < 0 {
+		return errors.New("LOGIN_*_FREE_ATTEMPTS and LOGIN_LOCKOUT_AFTER must be >= 0")
+	}
+	if c.Auth.LoginLockoutFor <= 0 || c.Auth.LoginFailureWindow <= 0 {
+		return errors.New("LOGIN_LOCKOUT and LOGIN_FAILURE_WINDOW must be > 0")
+	}
+	seen := make(map[string]bool)
+	for _, p := range c.Auth.OIDC {
+		env := "OIDC_" + strings.ToUpper(p.Name)
+		if seen[p.Name] {
+			return fmt.Errorf("OIDC_PROVIDERS: duplicate provider %q", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			return fmt.Errorf("%s_ISSUER, %s_CLIENT_ID and %s_REDIRECT_URL are required", env, env, env)
+		}
+	}
+	if len(c.Auth.OIDC) > 0 {
+		if c.Auth.OIDCStateKey == "" {
+			return errors.New("OIDC_STATE_KEY is empty")
+		}
+		if c.Env != "dev" && c.Auth.OIDCStateKey == "dev-oidc-state-change-me" {
+			return fmt.Errorf("refuse to run with default OIDC_STATE_KEY in %s", c.Env)
+		}
+	}
+	if c.Game.DailySeed == "" {
+		return errors.New("DAILY_SEED is empty")
 	}
-	if c.Env != "dev" && c.Auth.Secret == "dev-secret-change-me" {
-		return fmt.Errorf("refuse to run with default JWT_SECRET in %s", c.Env)
+	if c.Env != "dev" && c.Game.DailySeed == "dev-daily-seed-change-me" {
+		return fmt.Errorf("refuse to run with default DAILY_SEED in %s", c.Env)
+	}
+	if len(c.Redis.SnapshotKeys) > 0 {
+		if _, ok := c.Redis.SnapshotKeys[c.Redis.SnapshotKeyID]; !ok {
+			return fmt.Errorf("SNAPSHOT_KEY_ID=%q is not in SNAPSHOT_KEYS", c.Redis.SnapshotKeyID)
+		}
+	} else if c.Redis.SnapshotKeyID != "" {
+		return errors.New("SNAPSHOT_KEY_ID is set but SNAPSHOT_KEYS is empty")
+	}
+	if c.Game.SeriesBestOf < 0 || (c.Game.SeriesBestOf > 0 && c.Game.SeriesBestOf%2 == 0) {
+		return fmt.Errorf("SERIES_BEST_OF must be 0 or odd, got %d", c.Game.SeriesBestOf)
+	}
+	if c.Game.MaxRounds < 0 {
+		return fmt.Errorf("MAX_ROUNDS must be >= 0, got %d", c.Game.MaxRounds)
+	}
+	if c.Game.LobbyTTL <= 0 {
+		return errors.New("LOBBY_TTL must be > 0")
+	}
+	if c.Game.InviteTTL <= 0 || c.Game.InviteTTL > c.Redis.MatchTTL {
+		return errors.New("INVITE_TTL must be > 0 and not longer than MATCH_TTL")
+	}
+	if c.Game.JoinTimeout < 0 {
+		return errors.New("TOURNAMENT_JOIN_TIMEOUT must be >= 0")
+	}
+	switch c.Mail.Driver {
+	case "log", "file":
+		// both keep reset and verification links where anyone with access to logs or disk can use them
+		if c.Env != "dev" {
+			return fmt.Errorf("refuse to run with MAIL_DRIVER=%s in %s (use smtp)", c.Mail.Driver, c.Env)
+		}
+	case "smtp":
+		if c.Mail.SMTPAddr == "" {
+			return errors.New("SMTP_ADDR is required for MAIL_DRIVER=smtp")
+		}
+	default:
+		return fmt.Errorf("unsupported MAIL_DRIVER=%q (want log|file|smtp)", c.Mail.Driver)
+	}
+	if c.Mail.PollInterval <= 0 || c.Mail.ResetTTL <= 0 || c.Mail.VerifyTTL <= 0 {
+		return errors.New("MAIL_POLL_INTERVAL, PASSWORD_RESET_TTL and EMAIL_VERIFY_TTL must be > 0")
 	}
 	if c.Log.Format != "text" && c.Log.Format != "json" {
 		return fmt.Errorf("unsupported LOG_FORMAT=%q (want text|json)", c.Log.Format)
@@ -107,6 +315,72 @@ func (c Config) Validate() error {
 	return nil
 }
 
+// parseKeys parses "kid1:base64key,kid2:base64key" (standard base64).
+func parseKeys(s string) (map[string][]byte, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	keys := make(map[string][]byte)
+	for _, part := range strings.Split(s, ",") {
+		kid, b64, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("want kid:base64key, got %q", part)
+		}
+		key, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("key %q must be 16, 24 or 32 bytes, got %d", kid, len(key))
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
+
+// loadOIDCProviders reads OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL
+// and _SCOPES (space-separated) for every name in the comma-separated list.
+func loadOIDCProviders(names string) []OIDCProvider {
+	var out []OIDCProvider
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		env := "OIDC_" + strings.ToUpper(name)
+		out = append(out, OIDCProvider{
+			Name:         name,
+			Issuer:       envString(env+"_ISSUER", ""),
+			ClientID:     envString(env+"_CLIENT_ID", ""),
+			ClientSecret: envString(env+"_CLIENT_SECRET", ""),
+			RedirectURL:  envString(env+"_REDIRECT_URL", ""),
+			Scopes:       strings.Fields(envString(env+"_SCOPES", "")),
+		})
+	}
+	return out
+}
+
+// parseKeyFiles parses "kid1=/path/key1.pem,kid2=/path/key2.pem".
+func parseKeyFiles(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	files := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		kid, path, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("want kid=path, got %q", part)
+		}
+		if _, dup := files[kid]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", kid)
+		}
+		files[kid] = path
+	}
+	return files, nil
+}
+
 func envString(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -0,0 +1,192 @@
+package game
+
+import (
+	"errors"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"example.com/bc-mvp/internal/profile"
+)
+
+// Чат и эмоции внутри матча.
+//
+// chat {text} — сообщение до profile.MaxChatMessageLen символов, мат маскируется звёздочками;
+// emote {emote} — одна из фиксированных эмоций (chatEmotes). Оба делят один лимит частоты
+// на игрока. Сообщения уходят всем игрокам матча (включая автора — как подтверждение),
+// кроме тех, кто заглушил автора (mute {slot}). Последние chatLogLen сообщений хранятся
+// в snapshot и приходят переподключившемуся игроку событием chat_history.
+
+const (
+	chatLogLen = 50
+
+	// не больше chatRateBurst сообщений (chat + emote) за chatRateWindow
+	chatRateBurst  = 5
+	chatRateWindow = 10 * time.Second
+)
+
+var chatEmotes = []string{"gg", "gl_hf", "wow", "nice", "oops", "thinking", "thanks", "sorry"}
+
+var (
+	ErrChatEmpty       = errors.New("message is empty")
+	ErrChatTooLong     = errors.New("message is too long")
+	ErrChatRateLimited = errors.New("too many messages, slow down")
+	errUnknownEmote    = errors.New("unknown emote")
+	errMuteSelf        = errors.New("cannot mute yourself")
+)
+
+type ChatPayload struct {
+	Text string `json:"text"`
+}
+
+type EmotePayload struct {
+	Emote string `json:"emote"`
+}
+
+type MutePayload struct {
+	Slot string `json:"slot"`
+}
+
+// ChatMessage — событие chat/emote и элемент chat_history.
+type ChatMessage struct {
+	Slot  string `json:"slot"`
+	Name  string `json:"name,omitempty"`
+	Text  string `json:"text,omitempty"`  // chat
+	Emote string `json:"emote,omitempty"` // emote
+	AtMs  int64  `json:"atMs"`
+}
+
+type ChatHistoryPayload struct {
+	Messages []ChatMessage `json:"messages"`
+}
+
+func (m *Match) SendChat(slot Slot, text string) error {
+	text = profile.NormalizeChatMessage(text)
+	if text == "" {
+		return ErrChatEmpty
+	}
+	if utf8.RuneCountInString(text) > profile.MaxChatMessageLen {
+		return ErrChatTooLong
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.postChatLocked(slot, "chat", ChatMessage{Text: profile.CensorChatMessage(text)})
+}
+
+func (m *Match) SendEmote(slot Slot, emote string) error {
+	if !slices.Contains(chatEmotes, emote) {
+		return errUnknownEmote
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.postChatLocked(slot, "emote", ChatMessage{Emote: emote})
+}
+
+// SetMuted глушит (или снова включает) сообщения игрока target для slot. Только для этого матча.
+func (m *Match) SetMuted(slot, target Slot, muted bool) error {
+	if slot == target {
+		return errMuteSelf
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, t := m.playerLocked(slot), m.playerLocked(target)
+	if p == nil || t == nil {
+		return errors.New("invalid slot")
+	}
+	if muted {
+		if p.muted == nil {
+			p.muted = make(map[Slot]bool)
+		}
+		p.muted[target] = true
+	} else {
+		delete(p.muted, target)
+	}
+
+	m.persistLocked()
+	if p.conn != nil {
+		m.sendLocked(p.conn, Envelope{Type: "state", Payload: mustJSON(m.buildStateLocked(slot))})
+	}
+	return nil
+}
+
+// SendChatHistoryTo — лог чата игроку (после подключения), без заглушённых им авторов.
+func (m *Match) SendChatHistoryTo(slot Slot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.playerLocked(slot)
+	if p == nil || p.conn == nil {
+		return
+	}
+	msgs := make([]ChatMessage, 0, len(m.chat))
+	for _, msg := range m.chat {
+		if !p.muted[Slot(msg.Slot)] {
+			msgs = append(msgs, msg)
+		}
+	}
+	m.sendLocked(p.conn, Envelope{Type: "chat_history", Payload: mustJSON(ChatHistoryPayload{Messages: msgs})})
+}
+
+func (m *Match) postChatLocked(slot Slot, typ string, msg ChatMessage) error {
+	from := m.playerLocked(slot)
+	if from == nil {
+		return errors.New("invalid slot")
+	}
+
+	now := time.Now()
+	if !from.allowChatLocked(now) {
+		return ErrChatRateLimited
+	}
+
+	msg.Slot = string(slot)
+	msg.Name = from.name
+	msg.AtMs = now.UnixMilli()
+	m.chat = append(m.chat, msg)
+	if len(m.chat) > chatLogLen {
+		m.chat = slices.Clone(m.chat[len(m.chat)-chatLogLen:])
+	}
+	m.persistLocked()
+
+	env := Envelope{Type: typ, Payload: mustJSON(msg)}
+	for _, p := range m.players {
+		if p.conn != nil && !p.muted[slot] {
+			m.sendLocked(p.conn, env)
+		}
+	}
+	return nil
+}
+
+// allowChatLocked — скользящее окно: помним время последних chatRateBurst сообщений.
+func (p *Player) allowChatLocked(now time.Time) bool {
+	if len(p.chatSent) >= chatRateBurst && now.Sub(p.chatSent[0]) < chatRateWindow {
+		return false
+	}
+	p.chatSent = append(p.chatSent, now)
+	if len(p.chatSent) > chatRateBurst {
+		p.chatSent = p.chatSent[1:]
+	}
+	return true
+}
+
+// mutedSlotsLocked — кого заглушил игрок (state.muted и snapshot), по порядку слотов.
+func (m *Match) mutedSlotsLocked(p *Player) []string {
+	var out []string
+	for _, q := range m.players {
+		if p.muted[q.slot] {
+			out = append(out, string(q.slot))
+		}
+	}
+	return out
+}
+
+// chatErrorCode — код ошибки для клиента: превышение лимита отдельно, чтобы его можно было показать иначе.
+func chatErrorCode(err error) string {
+	if errors.Is(err, ErrChatRateLimited) {
+		return "rate_limited"
+	}
+	return "bad_input"
+}
@@ -0,0 +1,162 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chatEvents(envs []Envelope) []ChatMessage {
+	var out []ChatMessage
+	for _, env := range envs {
+		if env.Type != "chat" && env.Type != "emote" {
+			continue
+		}
+		var msg ChatMessage
+		if json.Unmarshal(env.Payload, &msg) == nil {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+func TestMatch_Chat(t *testing.T) {
+	setup := func(t *testing.T) (*Match, *ClientConn, *ClientConn) {
+		m := NewMatch("m1", 0)
+		c1, c2 := newTestConn(), newTestConn()
+		_, code, _ := m.Attach("u1", "Alice", c1)
+		require.Empty(t, code)
+		_, code, _ = m.Attach("u2", "Bob", c2)
+		require.Empty(t, code)
+		readEnvelopesNonBlocking(c1)
+		readEnvelopesNonBlocking(c2)
+		return m, c1, c2
+	}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "message reaches both players, censored",
+			run: func(t *testing.T) {
+				m, c1, c2 := setup(t)
+				require.NoError(t, m.SendChat(P1, "  oh   shit, gl "))
+
+				for _, c := range []*ClientConn{c1, c2} {
+					got := chatEvents(readEnvelopesNonBlocking(c))
+					require.Len(t, got, 1)
+					assert.Equal(t, "p1", got[0].Slot)
+					assert.Equal(t, "Alice", got[0].Name)
+					assert.Equal(t, "oh ***** gl", got[0].Text)
+				}
+			},
+		},
+		{
+			name: "length limits and emote list",
+			run: func(t *testing.T) {
+				m, _, _ := setup(t)
+				assert.ErrorIs(t, m.SendChat(P1, "   "), ErrChatEmpty)
+				assert.ErrorIs(t, m.SendChat(P1, strings.Repeat("я", 201)), ErrChatTooLong)
+				assert.Error(t, m.SendEmote(P1, "rickroll"))
+				assert.NoError(t, m.SendEmote(P1, "gg"))
+			},
+		},
+		{
+			name: "rate limit is shared by chat and emotes",
+			run: func(t *testing.T) {
+				m, _, _ := setup(t)
+				for i := range chatRateBurst {
+					if i%2 == 0 {
+						require.NoError(t, m.SendChat(P1, "hi"))
+					} else {
+						require.NoError(t, m.SendEmote(P1, "wow"))
+					}
+				}
+				assert.ErrorIs(t, m.SendEmote(P1, "wow"), ErrChatRateLimited)
+				assert.Equal(t, "rate_limited", chatErrorCode(ErrChatRateLimited))
+				// лимит у каждого игрока свой
+				assert.NoError(t, m.SendChat(P2, "hi"))
+			},
+		},
+		{
+			name: "muted player is not delivered to the muter",
+			run: func(t *testing.T) {
+				m, c1, c2 := setup(t)
+				require.ErrorIs(t, m.SetMuted(P1, P1, true), errMuteSelf)
+				require.NoError(t, m.SetMuted(P1, P2, true))
+				st, ok := findLastState(readEnvelopesNonBlocking(c1))
+				require.True(t, ok)
+				assert.Equal(t, []string{"p2"}, st.Muted)
+
+				require.NoError(t, m.SendChat(P2, "hello?"))
+				assert.Empty(t, chatEvents(readEnvelopesNonBlocking(c1)))
+				assert.Len(t, chatEvents(readEnvelopesNonBlocking(c2)), 1)
+
+				require.NoError(t, m.SetMuted(P1, P2, false))
+				require.NoError(t, m.SendChat(P2, "hello!"))
+				assert.Len(t, chatEvents(readEnvelopesNonBlocking(c1)), 1)
+			},
+		},
+		{
+			name: "chat log and mutes survive snapshot restore",
+			run: func(t *testing.T) {
+				m, _, _ := setup(t)
+				require.NoError(t, m.SendChat(P1, "first"))
+				require.NoError(t, m.SendEmote(P2, "gg"))
+				require.NoError(t, m.SetMuted(P2, P1, true))
+
+				b, err := json.Marshal(m.snapshotLocked())
+				require.NoError(t, err)
+				var snap MatchSnapshot
+				require.NoError(t, json.Unmarshal(b, &snap))
+				m2 := NewMatch("m1", 0)
+				m2.restoreLocked(snap)
+
+				c1, c2 := newTestConn(), newTestConn()
+				m2.Attach("u1", "Alice", c1)
+				m2.Attach("u2", "Bob", c2)
+				m2.SendChatHistoryTo(P1)
+				m2.SendChatHistoryTo(P2)
+
+				history := func(c *ClientConn) []ChatMessage {
+					for _, env := range readEnvelopesNonBlocking(c) {
+						if env.Type == "chat_history" {
+							var p ChatHistoryPayload
+							require.NoError(t, json.Unmarshal(env.Payload, &p))
+							return p.Messages
+						}
+					}
+					t.Fatal("no chat_history")
+					return nil
+				}
+				h1 := history(c1)
+				require.Len(t, h1, 2)
+				assert.Equal(t, "first", h1[0].Text)
+				assert.Equal(t, "gg", h1[1].Emote)
+				// p2 заглушил p1 — в истории только своё
+				h2 := history(c2)
+				require.Len(t, h2, 1)
+				assert.Equal(t, "gg", h2[0].Emote)
+			},
+		},
+		{
+			name: "log keeps only the latest messages",
+			run: func(t *testing.T) {
+				m, _, _ := setup(t)
+				for i := range chatLogLen + 10 {
+					m.players[0].chatSent = nil // без лимита частоты
+					require.NoError(t, m.SendChat(P1, strings.Repeat("x", i%10+1)))
+				}
+				assert.Len(t, m.chat, chatLogLen)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
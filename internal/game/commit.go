@@ -0,0 +1,87 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Commit-reveal для секретов.
+//
+// При set_secret сервер генерирует nonce, клиент может прислать свою соль (salt).
+// Всем игрокам в state.commitments публикуется
+//
+//	commitment = hex(sha256(secret + ":" + nonce + ":" + salt))
+//
+// После finished в state приходят revealedSecrets, revealedNonces и revealedSalts:
+// любой клиент может пересчитать commitment и проверить, что каждый round_result
+// посчитан против загаданного секрета. Соль клиента не даёт серверу подобрать
+// другой секрет под тот же commitment заранее, nonce — перебрать 10^4 секретов по хешу.
+//
+// Секрет после старта партии менять нельзя (иначе commitment потерял бы смысл).
+
+// MaxSaltLen — ограничение на длину клиентской соли.
+const MaxSaltLen = 64
+
+// Commitment — хеш, который публикуется вместо секрета до конца партии.
+func Commitment(secret, nonce, salt string) string {
+	sum := sha256.Sum256([]byte(secret + ":" + nonce + ":" + salt))
+	return hex.EncodeToString(sum[:])
+}
+
+// randNonce — серверный nonce для commitment (128 бит, hex).
+func randNonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// commitmentsLocked — commitments всех загаданных секретов:
+// по слотам, по командам (t1/t2) или "shared" для общего секрета.
+func (m *Match) commitmentsLocked() map[string]string {
+	out := make(map[string]string)
+	switch {
+	case m.rules.sharedTarget():
+		if m.sharedSecret != "" {
+			out["shared"] = Commitment(m.sharedSecret, m.sharedNonce, "")
+		}
+	case m.rules.Teams:
+		for _, t := range m.teams {
+			if t.secretSet {
+				out[t.id] = Commitment(t.secret, t.nonce, t.salt)
+			}
+		}
+	default:
+		for _, p := range m.players {
+			if p.secretSet {
+				out[string(p.slot)] = Commitment(p.secret, p.nonce, p.salt)
+			}
+		}
+	}
+	return out
+}
+
+// revealLocked заполняет revealedSecrets/Nonces/Salts (только после finished).
+func (m *Match) revealLocked(st *StatePayload) {
+	st.RevealedSecrets = make(map[string]string)
+	st.RevealedNonces = make(map[string]string)
+	st.RevealedSalts = make(map[string]string)
+	add := func(key, secret, nonce, salt string) {
+		st.RevealedSecrets[key] = secret
+		st.RevealedNonces[key] = nonce
+		st.RevealedSalts[key] = salt
+	}
+
+	switch {
+	case m.rules.sharedTarget():
+		add("shared", m.sharedSecret, m.sharedNonce, "")
+	case m.rules.Teams:
+		for _, t := range m.teams {
+			add(t.id, t.secret, t.nonce, t.salt)
+		}
+	default:
+		for _, p := range m.players {
+			add(string(p.slot), p.secret, p.nonce, p.salt)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch_CommitReveal(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "commitments published at set_secret verify against the revealed opening",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				c2 := newTestConn()
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", c2)
+
+				require.NoError(t, m.SetSecretWithSalt(P1, "1111", "client-salt"))
+
+				st, ok := findLastState(readEnvelopesNonBlocking(c2))
+				require.True(t, ok)
+				require.Len(t, st.Commitments, 1)
+				committed := st.Commitments["p1"]
+				require.Len(t, committed, 64)
+				assert.Empty(t, st.RevealedSecrets)
+				assert.Empty(t, st.RevealedNonces)
+
+				require.NoError(t, m.SetSecret(P2, "2222"))
+				require.Error(t, m.SetSecret(P1, "3333"), "secret is locked after start")
+
+				require.NoError(t, m.SubmitGuess(P1, "2222"))
+				require.NoError(t, m.SubmitGuess(P2, "0000"))
+
+				st, ok = findLastState(readEnvelopesNonBlocking(c2))
+				require.True(t, ok)
+				require.Equal(t, "finished", st.Phase)
+				assert.Equal(t, committed, st.Commitments["p1"])
+				assert.Equal(t, "client-salt", st.RevealedSalts["p1"])
+				for _, slot := range []string{"p1", "p2"} {
+					assert.Equal(t, st.Commitments[slot],
+						Commitment(st.RevealedSecrets[slot], st.RevealedNonces[slot], st.RevealedSalts[slot]))
+				}
+			},
+		},
+		{
+			name: "shared target commits the server secret at round start",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{Players: 3, Target: TargetShared})
+				for _, id := range []string{"u1", "u2", "u3"} {
+					m.Attach(id, id, newTestConn())
+				}
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				st := m.buildStateLocked("p1")
+				assert.Equal(t, Commitment(m.sharedSecret, m.sharedNonce, ""), st.Commitments["shared"])
+				assert.NotEmpty(t, m.sharedNonce)
+			},
+		},
+		{
+			name: "salt is bounded",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				m.Attach("u1", "Alice", newTestConn())
+				require.Error(t, m.SetSecretWithSalt(P1, "1111", strings.Repeat("a", MaxSaltLen+1)))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -0,0 +1,42 @@
+package game
+
+import "example.com/bc-mvp/internal/notify"
+
+// Игра по переписке (Rules.Correspondence).
+//
+// На раунд (в alternating — на ход) даются часы или дни (Rules.MoveHours), поэтому:
+//   - отключение игрока не возвращает матч в waiting_players: каждый ходит, когда ему удобно;
+//   - дедлайн живёт в snapshot-е, а после рестарта MatchService.ResumeDeadlines поднимает
+//     такие матчи и заново заводит таймеры, даже если в матч никто не зайдёт;
+//   - тем, кто сейчас не подключён к матчу, приходит your_move через Notifier; DurableNotifier
+//     (notify.go) отправляет его письмом, если игрока нет онлайн на этом инстансе.
+
+// YourMovePayload — уведомление your_move: в матче по переписке ждут хода игрока.
+type YourMovePayload struct {
+	MatchID    string `json:"matchId"`
+	Round      int    `json:"round"`
+	Turn       string `json:"turn,omitempty"` // alternating: слот, чей ход
+	DeadlineMs int64  `json:"deadlineMs"`
+}
+
+// notifyMoveLocked шлёт your_move тем, от кого сейчас ждут хода: в alternating — игроку m.turn,
+// иначе всем, кто ещё угадывает. Подключённые и так получают round_started/turn_started.
+func (m *Match) notifyMoveLocked() {
+	if !m.rules.Correspondence || m.onNotify == nil {
+		return
+	}
+	for _, p := range m.players {
+		if p.id == "" || p.connected || p.solvedRound > 0 {
+			continue
+		}
+		if m.rules.alternating() && p.slot != m.turn {
+			continue
+		}
+		m.onNotify(p.id, notify.Event{Type: "your_move", Payload: YourMovePayload{
+			MatchID:    m.id,
+			Round:      m.round,
+			Turn:       string(m.turn),
+			DeadlineMs: toMs(m.deadline),
+		}})
+	}
+}
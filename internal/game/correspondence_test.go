@@ -0,0 +1,354 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/bc-mvp/internal/mail"
+	"example.com/bc-mvp/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlinePersist — memPersist с индексом дедлайнов, как у RedisMatchStore.
+type deadlinePersist struct {
+	memPersist
+}
+
+func (p *deadlinePersist) PendingDeadlines(ctx context.Context) ([]string, error) {
+	var ids []string
+	for id, snap := range p.m {
+		if snap.pendingDeadlineMs() > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func TestRules_Correspondence(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   Rules
+		wantErr bool
+		want    time.Duration
+	}{
+		{name: "default move time", rules: Rules{Correspondence: true}, want: 24 * time.Hour},
+		{name: "three days", rules: Rules{Correspondence: true, MoveHours: 72}, want: 72 * time.Hour},
+		{name: "real time keeps ROUND_DURATION", rules: Rules{}, want: time.Minute},
+		{name: "moveHours without correspondence", rules: Rules{MoveHours: 12}, wantErr: true},
+		{name: "more than two weeks", rules: Rules{Correspondence: true, MoveHours: MaxMoveHours + 1}, wantErr: true},
+		{name: "negative", rules: Rules{Correspondence: true, MoveHours: -1}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rules.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, tc.rules.roundDuration(time.Minute))
+		})
+	}
+}
+
+func TestMatch_Correspondence(t *testing.T) {
+	type sent struct {
+		to   string
+		move YourMovePayload
+	}
+	capture := func(m *Match) *[]sent {
+		var out []sent
+		m.onNotify = func(userID string, ev notify.Event) {
+			if ev.Type == "your_move" {
+				out = append(out, sent{to: userID, move: ev.Payload.(YourMovePayload)})
+			}
+		}
+		return &out
+	}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "players take turns without being online together",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{Correspondence: true, MoveHours: 48})
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+				require.NoError(t, m.SetSecret(P1, "1111"))
+				m.Detach(P1)
+
+				require.NoError(t, m.SetSecret(P2, "2222"))
+				m.Detach(P2)
+
+				m.mu.Lock()
+				assert.Equal(t, "playing", m.phase, "a disconnect does not pause the match")
+				assert.Equal(t, 1, m.round)
+				assert.WithinDuration(t, time.Now().Add(48*time.Hour), m.deadline, time.Minute)
+				m.roundTimer.Stop()
+				m.mu.Unlock()
+
+				require.NoError(t, m.SubmitGuess(P1, "0000"))
+				require.NoError(t, m.SubmitGuess(P2, "1111"))
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, "finished", m.phase)
+				assert.Equal(t, "p2", m.winner)
+			},
+		},
+		{
+			name: "real time match still waits for a disconnected player",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+				require.NoError(t, m.SetSecret(P1, "1111"))
+				require.NoError(t, m.SetSecret(P2, "2222"))
+				m.Detach(P2)
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, "waiting_players", m.phase)
+			},
+		},
+		{
+			name: "players away from the match hear that a round started",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{Correspondence: true})
+				got := capture(m)
+				m.Attach("u1", "Alice", newTestConn())
+				require.NoError(t, m.SetSecret(P1, "1111"))
+				m.Detach(P1)
+
+				m.Attach("u2", "Bob", newTestConn())
+				require.NoError(t, m.SetSecret(P2, "2222"))
+				m.mu.Lock()
+				m.roundTimer.Stop()
+				m.mu.Unlock()
+
+				require.Len(t, *got, 1)
+				assert.Equal(t, "u1", (*got)[0].to)
+				assert.Equal(t, YourMovePayload{MatchID: "m1", Round: 1, DeadlineMs: (*got)[0].move.DeadlineMs}, (*got)[0].move)
+				assert.Positive(t, (*got)[0].move.DeadlineMs)
+			},
+		},
+		{
+			name: "alternating: only the player whose turn it is",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{Correspondence: true, Mode: ModeAlternating})
+				got := capture(m)
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+				m.Detach(P2)
+				require.NoError(t, m.SetSecret(P1, "1111"))
+				require.NoError(t, m.SetSecret(P2, "2222"))
+				assert.Empty(t, *got, "p1 is connected and it is p1's turn")
+
+				require.NoError(t, m.SubmitGuess(P1, "0000"))
+				m.mu.Lock()
+				m.roundTimer.Stop()
+				m.mu.Unlock()
+
+				require.Len(t, *got, 1)
+				assert.Equal(t, "u2", (*got)[0].to)
+				assert.Equal(t, "p2", (*got)[0].move.Turn)
+			},
+		},
+		{
+			name: "real time match sends no your_move",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{Mode: ModeAlternating})
+				got := capture(m)
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+				require.NoError(t, m.SetSecret(P1, "1111"))
+				require.NoError(t, m.SetSecret(P2, "2222"))
+				require.NoError(t, m.SubmitGuess(P1, "0000"))
+				assert.Empty(t, *got)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
+
+func TestMatchService_ResumeDeadlines(t *testing.T) {
+	ctx := context.Background()
+	persist := &deadlinePersist{}
+
+	svc := NewMatchService(Config{}, persist)
+	m, err := svc.CreateWithRules(ctx, "m1", Rules{Correspondence: true, MoveHours: 6})
+	require.NoError(t, err)
+	m.Attach("u1", "Alice", newTestConn())
+	m.Attach("u2", "Bob", newTestConn())
+	require.NoError(t, m.SetSecret(P1, "1111"))
+	require.NoError(t, m.SetSecret(P2, "2222"))
+	m.mu.Lock()
+	m.roundTimer.Stop()
+	m.mu.Unlock()
+
+	// сервер лежал, пока дедлайн первого раунда не прошёл
+	snap := persist.m["m1"]
+	require.Equal(t, "playing", snap.Phase)
+	snap.DeadlineMs = time.Now().Add(-time.Hour).UnixMilli()
+	persist.m["m1"] = snap
+
+	restarted := NewMatchService(Config{}, persist)
+	n, err := restarted.ResumeDeadlines(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	loaded, ok, err := restarted.GetOrLoad(ctx, "m1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		loaded.mu.Lock()
+		defer loaded.mu.Unlock()
+		return loaded.round == 2
+	}, time.Second, 10*time.Millisecond, "overdue round times out right after the restart")
+
+	loaded.mu.Lock()
+	defer loaded.mu.Unlock()
+	loaded.roundTimer.Stop()
+	assert.Equal(t, "playing", loaded.phase)
+	require.Len(t, loaded.history, 1)
+	assert.True(t, loaded.history[0].P1.Missed)
+	assert.WithinDuration(t, time.Now().Add(6*time.Hour), loaded.deadline, time.Minute,
+		"the next round gets the match's move time, not ROUND_DURATION")
+}
+
+// memMailer — outbox в памяти: адрес есть только у пользователей из addr.
+type memMailer struct {
+	addr map[string]string
+	sent chan mail.Message
+}
+
+func (mm *memMailer) EnqueueForUser(ctx context.Context, userID string, build func(to string) mail.Message) (bool, error) {
+	to, ok := mm.addr[userID]
+	if !ok {
+		return false, nil
+	}
+	mm.sent <- build(to)
+	return true, nil
+}
+
+func TestDurableNotifier(t *testing.T) {
+	deadline := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	move := notify.Event{Type: "your_move", Payload: YourMovePayload{MatchID: "m1", Round: 3, DeadlineMs: deadline.UnixMilli()}}
+
+	cases := []struct {
+		name      string
+		subscribe string // у кого открыт /ws/user на этом инстансе
+		userID    string
+		ev        notify.Event
+		wantLive  int
+		wantMail  bool
+	}{
+		{name: "offline player gets an email", userID: "u1", ev: move, wantMail: true},
+		{name: "connected player gets no email", subscribe: "u1", userID: "u1", ev: move, wantLive: 1},
+		{name: "no verified address, nothing to send", userID: "guest", ev: move},
+		{name: "other events are not mailed", userID: "u1", ev: notify.Event{Type: "opponent_joined"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := notify.NewHub()
+			if tc.subscribe != "" {
+				sub := hub.Subscribe(tc.subscribe)
+				defer sub.Close()
+			}
+			mailer := &memMailer{addr: map[string]string{"u1": "alice@example.com"}, sent: make(chan mail.Message, 1)}
+			n := &DurableNotifier{Live: hub, Mail: mailer, BaseURL: "https://bc.example/"}
+
+			assert.Equal(t, tc.wantLive, n.Publish(tc.userID, tc.ev))
+			if !tc.wantMail {
+				select {
+				case m := <-mailer.sent:
+					t.Fatalf("unexpected email %+v", m)
+				case <-time.After(20 * time.Millisecond):
+				}
+				return
+			}
+			select {
+			case m := <-mailer.sent:
+				assert.Equal(t, "alice@example.com", m.To)
+				assert.Contains(t, m.Body, "Round 3")
+				assert.Contains(t, m.Body, "2026-10-20 12:00 UTC")
+				assert.Contains(t, m.Body, "https://bc.example/?match=m1")
+			case <-time.After(time.Second):
+				t.Fatal("no email for an offline player")
+			}
+		})
+	}
+}
+
+type failingMailer struct{}
+
+func (failingMailer) EnqueueForUser(ctx context.Context, userID string, build func(to string) mail.Message) (bool, error) {
+	return false, errors.New("outbox is down")
+}
+
+// syncBuffer — лог пишется из горутины DurableNotifier.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDurableNotifier_LogsEnqueueFailure(t *testing.T) {
+	var logs syncBuffer
+	n := &DurableNotifier{Live: notify.NewHub(), Mail: failingMailer{}, Log: slog.New(slog.NewTextHandler(&logs, nil))}
+
+	n.Publish("u1", notify.Event{Type: "your_move", Payload: YourMovePayload{MatchID: "m1", Round: 1}})
+	require.Eventually(t, func() bool { return logs.String() != "" }, time.Second, 5*time.Millisecond)
+	assert.Contains(t, logs.String(), "outbox is down")
+	assert.Contains(t, logs.String(), "match=m1")
+}
+
+func TestMatchService_YourMoveReachesOfflinePlayer(t *testing.T) {
+	ctx := context.Background()
+	hub := notify.NewHub()
+	mailer := &memMailer{addr: map[string]string{"u1": "alice@example.com"}, sent: make(chan mail.Message, 1)}
+	svc := NewMatchService(Config{}, &memPersist{})
+	svc.SetNotifier(&DurableNotifier{Live: hub, Mail: mailer})
+
+	m, err := svc.CreateWithRules(ctx, "m1", Rules{Correspondence: true})
+	require.NoError(t, err)
+	m.Attach("u1", "Alice", newTestConn())
+	require.NoError(t, m.SetSecret(P1, "1111"))
+	m.Detach(P1) // ни матча, ни /ws/user
+
+	m.Attach("u2", "Bob", newTestConn())
+	require.NoError(t, m.SetSecret(P2, "2222"))
+	m.mu.Lock()
+	m.roundTimer.Stop()
+	m.mu.Unlock()
+
+	select {
+	case msg := <-mailer.sent:
+		assert.Equal(t, "alice@example.com", msg.To)
+		assert.Contains(t, msg.Body, "/?match=m1")
+	case <-time.After(time.Second):
+		t.Fatal("your_move was lost for a player with no live connection")
+	}
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ежедневная головоломка (daily) — одиночный режим без матча и WebSocket.
+//
+// Секрет один на календарный день (UTC) и общий для всех: HMAC-SHA256(seed, день) => 4 цифры,
+// поэтому все инстансы получают одно и то же без общего состояния, а без seed секрет не угадать
+// заранее. У пользователя одна попытка на день — последовательность догадок до отгадки
+// или до DailyMaxRounds; хранит её store.DailyStore.
+
+// DailyMaxRounds — сколько догадок даётся на головоломку.
+const DailyMaxRounds = 10
+
+const dailyDayLayout = "2006-01-02"
+
+var (
+	ErrDailyFinished = errors.New("today's puzzle is already finished")
+	ErrDailyBadGuess = errors.New("guess must be exactly 4 digits (0-9)")
+)
+
+// DailyPlay — попытка одного пользователя за день. Догадки лежат в History
+// в формате раундов матча: попытка игрока — в P1.
+type DailyPlay struct {
+	Day     string             `json:"day"`
+	History []RoundHistoryItem `json:"history"`
+	Solved  bool               `json:"solved"`
+}
+
+func (p DailyPlay) Rounds() int { return len(p.History) }
+
+// Finished — отгадал или догадки кончились.
+func (p DailyPlay) Finished() bool {
+	return p.Solved || len(p.History) >= DailyMaxRounds
+}
+
+// Daily выдаёт секреты дня и проверяет догадки.
+type Daily struct {
+	seed []byte
+}
+
+func NewDaily(seed []byte) *Daily {
+	return &Daily{seed: seed}
+}
+
+// DailyDay — день головоломки для момента t: дата по UTC, "2006-01-02".
+func DailyDay(t time.Time) string {
+	return t.UTC().Format(dailyDayLayout)
+}
+
+// ParseDailyDay проверяет день из запроса и приводит его к каноническому виду.
+func ParseDailyDay(s string) (string, error) {
+	t, err := time.Parse(dailyDayLayout, s)
+	if err != nil {
+		return "", errors.New("day must be YYYY-MM-DD")
+	}
+	return DailyDay(t), nil
+}
+
+// Secret — секрет дня. Байты >= 250 отбрасываем (250 делится на 10), чтобы цифры были равновероятны.
+func (d *Daily) Secret(day string) string {
+	mac := hmac.New(sha256.New, d.seed)
+	mac.Write([]byte("daily:" + day))
+	sum := mac.Sum(nil)
+
+	b := make([]byte, 0, 4)
+	for {
+		for _, x := range sum {
+			if x >= 250 {
+				continue
+			}
+			b = append(b, '0'+x%10)
+			if len(b) == 4 {
+				return string(b)
+			}
+		}
+		// 32 байта почти всегда дают 4 цифры; иначе продолжаем детерминированно
+		next := sha256.Sum256(sum)
+		sum = next[:]
+	}
+}
+
+// Guess проверяет очередную догадку и дописывает её в попытку.
+func (d *Daily) Guess(p *DailyPlay, guess string) (Attempt, error) {
+	if p.Finished() {
+		return Attempt{}, ErrDailyFinished
+	}
+	if !valid4Digits(guess) {
+		return Attempt{}, ErrDailyBadGuess
+	}
+	b, c := BullsCows(d.Secret(p.Day), guess)
+	a := Attempt{Guess: &guess, Bulls: b, Cows: c}
+	p.History = append(p.History, RoundHistoryItem{Round: len(p.History) + 1, P1: a})
+	p.Solved = b == 4
+	return a, nil
+}
+
+// DailyShare — итог для публикации без цифр: строка на догадку, 🟢 бык, 🟡 корова, ⚪ мимо.
+//
+//	Bulls & Cows daily 2026-10-18 4/10
+//	🟡⚪⚪⚪
+//	...
+func DailyShare(p DailyPlay) string {
+	score := "X"
+	if p.Solved {
+		score = strconv.Itoa(p.Rounds())
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Bulls & Cows daily %s %s/%d", p.Day, score, DailyMaxRounds)
+	for _, it := range p.History {
+		sb.WriteByte('\n')
+		sb.WriteString(strings.Repeat("🟢", it.P1.Bulls))
+		sb.WriteString(strings.Repeat("🟡", it.P1.Cows))
+		sb.WriteString(strings.Repeat("⚪", 4-it.P1.Bulls-it.P1.Cows))
+	}
+	return sb.String()
+}
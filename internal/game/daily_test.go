@@ -0,0 +1,120 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailySecret(t *testing.T) {
+	d := NewDaily([]byte("seed"))
+
+	s := d.Secret("2026-10-18")
+	require.True(t, valid4Digits(s), s)
+	assert.Equal(t, s, d.Secret("2026-10-18"), "один и тот же день => один секрет")
+	assert.Equal(t, s, NewDaily([]byte("seed")).Secret("2026-10-18"), "одинаков на всех инстансах")
+
+	// за месяц совпадения возможны, но не все дни одинаковы
+	seen := map[string]bool{}
+	for day := 1; day <= 30; day++ {
+		seen[d.Secret(fmt.Sprintf("2026-11-%02d", day))] = true
+	}
+	assert.Greater(t, len(seen), 1)
+
+	other := NewDaily([]byte("other"))
+	differs := false
+	for day := 1; day <= 30 && !differs; day++ {
+		dd := fmt.Sprintf("2026-11-%02d", day)
+		differs = d.Secret(dd) != other.Secret(dd)
+	}
+	assert.True(t, differs, "секрет зависит от seed")
+}
+
+func TestDailyGuess(t *testing.T) {
+	d := NewDaily([]byte("seed"))
+	const day = "2026-10-18"
+	secret := d.Secret(day)
+	wrong := "0000"
+	if secret == wrong {
+		wrong = "1111"
+	}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "bad guess is rejected and not recorded",
+			run: func(t *testing.T) {
+				p := &DailyPlay{Day: day}
+				_, err := d.Guess(p, "12a4")
+				require.ErrorIs(t, err, ErrDailyBadGuess)
+				assert.Zero(t, p.Rounds())
+			},
+		},
+		{
+			name: "solving guess finishes the puzzle",
+			run: func(t *testing.T) {
+				p := &DailyPlay{Day: day}
+				_, err := d.Guess(p, wrong)
+				require.NoError(t, err)
+				a, err := d.Guess(p, secret)
+				require.NoError(t, err)
+
+				assert.Equal(t, 4, a.Bulls)
+				assert.True(t, p.Solved)
+				assert.True(t, p.Finished())
+				assert.Equal(t, 2, p.Rounds())
+				assert.Equal(t, 2, p.History[1].Round)
+
+				_, err = d.Guess(p, secret)
+				require.ErrorIs(t, err, ErrDailyFinished)
+			},
+		},
+		{
+			name: "puzzle ends after the last allowed guess",
+			run: func(t *testing.T) {
+				p := &DailyPlay{Day: day}
+				for i := 0; i < DailyMaxRounds; i++ {
+					_, err := d.Guess(p, wrong)
+					require.NoError(t, err)
+				}
+				assert.False(t, p.Solved)
+				assert.True(t, p.Finished())
+
+				_, err := d.Guess(p, secret)
+				require.ErrorIs(t, err, ErrDailyFinished)
+				assert.Equal(t, DailyMaxRounds, p.Rounds())
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
+
+func TestDailyShare(t *testing.T) {
+	p := DailyPlay{Day: "2026-10-18", Solved: true, History: []RoundHistoryItem{
+		{Round: 1, P1: Attempt{Bulls: 0, Cows: 1}},
+		{Round: 2, P1: Attempt{Bulls: 2, Cows: 2}},
+		{Round: 3, P1: Attempt{Bulls: 4}},
+	}}
+	assert.Equal(t, "Bulls & Cows daily 2026-10-18 3/10\n🟡⚪⚪⚪\n🟢🟢🟡🟡\n🟢🟢🟢🟢", DailyShare(p))
+
+	p.Solved = false
+	p.History = p.History[:1]
+	assert.Equal(t, "Bulls & Cows daily 2026-10-18 X/10\n🟡⚪⚪⚪", DailyShare(p))
+}
+
+func TestParseDailyDay(t *testing.T) {
+	day, err := ParseDailyDay("2026-10-18")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10-18", day)
+
+	for _, s := range []string{"2026-1-18", "18.10.2026", "2026-02-30", ""} {
+		_, err := ParseDailyDay(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,173 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch_FreeForAll(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "three players guess next player's secret and are ranked by solve round",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{Players: 3})
+				c1 := newTestConn()
+				m.Attach("u1", "Alice", c1)
+				m.Attach("u2", "Bob", newTestConn())
+				slot, code, _ := m.Attach("u3", "Carol", newTestConn())
+				require.Empty(t, code)
+				require.Equal(t, Slot("p3"), slot)
+
+				_, code, _ = m.Attach("u4", "Dave", newTestConn())
+				require.Equal(t, "match_full", code)
+
+				// p1 -> p2, p2 -> p3, p3 -> p1
+				require.NoError(t, m.SetSecret("p1", "1111"))
+				require.NoError(t, m.SetSecret("p2", "2222"))
+				require.NoError(t, m.SetSecret("p3", "3333"))
+
+				require.NoError(t, m.SubmitGuess("p1", "2222"))
+				require.NoError(t, m.SubmitGuess("p2", "0000"))
+				require.NoError(t, m.SubmitGuess("p3", "0000"))
+
+				m.mu.Lock()
+				require.Equal(t, "playing", m.phase, "game goes on while two players still guess")
+				require.Equal(t, 2, m.round)
+				m.mu.Unlock()
+
+				require.Error(t, m.SubmitGuess("p1", "2222"), "solved player is done")
+				require.NoError(t, m.SubmitGuess("p2", "3333"))
+				require.NoError(t, m.SubmitGuess("p3", "0000"))
+
+				st, ok := findLastState(readEnvelopesNonBlocking(c1))
+				require.True(t, ok)
+				assert.Equal(t, "finished", st.Phase)
+				assert.Equal(t, "p1", st.Winner)
+				assert.Equal(t, 3, st.MaxPlayers)
+				assert.Equal(t, []Ranking{
+					{Slot: "p1", Rank: 1, SolvedRound: 1},
+					{Slot: "p2", Rank: 2, SolvedRound: 2},
+					{Slot: "p3", Rank: 3},
+				}, st.Rankings)
+				assert.Equal(t, map[string]string{"p1": "1111", "p2": "2222", "p3": "3333"}, st.RevealedSecrets)
+
+				require.Len(t, st.History, 2)
+				assert.Len(t, st.History[0].Attempts, 3)
+				assert.Len(t, st.History[1].Attempts, 2)
+			},
+		},
+		{
+			name: "shared target starts without player secrets",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{Players: 3, Target: TargetShared})
+				c1 := newTestConn()
+				m.Attach("u1", "Alice", c1)
+				m.Attach("u2", "Bob", newTestConn())
+				m.Attach("u3", "Carol", newTestConn())
+
+				require.Error(t, m.SetSecret("p1", "1234"))
+
+				m.mu.Lock()
+				require.Equal(t, "playing", m.phase)
+				secret := m.sharedSecret
+				m.mu.Unlock()
+				require.True(t, valid4Digits(secret))
+
+				wrong := "0000"
+				if secret == wrong {
+					wrong = "1111"
+				}
+				require.NoError(t, m.SubmitGuess("p1", secret))
+				require.NoError(t, m.SubmitGuess("p2", secret))
+				require.NoError(t, m.SubmitGuess("p3", wrong))
+
+				envs := readEnvelopesNonBlocking(c1)
+				env, ok := hasEnvelope(envs, "game_finished")
+				require.True(t, ok)
+				var p GameFinishedPayload
+				require.NoError(t, json.Unmarshal(env.Payload, &p))
+				assert.Equal(t, "draw", p.Winner, "two players share first place")
+				require.Len(t, p.Rankings, 3)
+				assert.Equal(t, 1, p.Rankings[1].Rank)
+				assert.Equal(t, 3, p.Rankings[2].Rank)
+
+				st, ok := findLastState(envs)
+				require.True(t, ok)
+				assert.Equal(t, map[string]string{"shared": secret}, st.RevealedSecrets)
+			},
+		},
+		{
+			name: "snapshot round-trips arbitrary slots",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{Players: 4})
+				for _, id := range []string{"u1", "u2", "u3", "u4"} {
+					m.Attach(id, id, newTestConn())
+				}
+				require.NoError(t, m.SetSecret("p4", "4444"))
+
+				m.mu.Lock()
+				snap := m.snapshotLocked()
+				m.mu.Unlock()
+
+				b, err := json.Marshal(snap)
+				require.NoError(t, err)
+				var decoded MatchSnapshot
+				require.NoError(t, json.Unmarshal(b, &decoded))
+
+				m2 := NewMatch("m1", 0)
+				m2.mu.Lock()
+				defer m2.mu.Unlock()
+				m2.restoreLocked(decoded)
+
+				require.Len(t, m2.players, 4)
+				assert.Equal(t, "u4", m2.players[3].id)
+				assert.Equal(t, "4444", m2.players[3].secret)
+				assert.True(t, m2.players[3].secretSet)
+			},
+		},
+		{
+			name: "legacy two-player snapshot still loads",
+			run: func(t *testing.T) {
+				legacy := []byte(`{
+					"matchId":"m1","phase":"playing","round":2,
+					"p1Id":"u1","p1Name":"Alice","p2Id":"u2","p2Name":"Bob",
+					"p1Secret":"1111","p1SecretSet":true,"p2Secret":"2222","p2SecretSet":true,
+					"p1Guess":"2200","p1GuessSet":true,"p2Guess":"","p2GuessSet":false,
+					"p1Rematch":false,"p2Rematch":false,
+					"seriesP1Wins":1,"seriesP2Wins":0,"seriesDraws":0,
+					"deadlineMs":0,"winner":"","history":[]
+				}`)
+				var snap MatchSnapshot
+				require.NoError(t, json.Unmarshal(legacy, &snap))
+
+				m := NewMatch("m1", 0)
+				m.mu.Lock()
+				m.restoreLocked(snap)
+				m.mu.Unlock()
+
+				slot, code, _ := m.Attach("u2", "", newTestConn())
+				require.Empty(t, code)
+				require.Equal(t, P2, slot)
+
+				require.NoError(t, m.SubmitGuess(P2, "1111"))
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, "finished", m.phase)
+				assert.Equal(t, "p2", m.winner)
+				assert.Equal(t, 2, m.gamesPlayed)
+				assert.Equal(t, "Alice", m.players[0].name)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -0,0 +1,176 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Коды приглашений: короткая замена matchId, которую удобно продиктовать или закодировать в QR.
+//
+// Алфавит без похожих символов (0/O, 1/I/L) и только в верхнем регистре: ссылка
+// {APP_BASE_URL}/J/{code} целиком попадает в alphanumeric-режим QR (плотнее байтового).
+// В /ws/{matchId} код не принимается — клиент сначала получает matchId через GET /api/invite/{code}.
+const (
+	inviteAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	inviteCodeLen  = 6 // 31^6 ≈ 8.9e8 кодов
+
+	// столько раз пробуем новый код при коллизии с живым приглашением
+	inviteCreateAttempts = 5
+)
+
+var errInviteCollision = errors.New("could not allocate a unique invite code")
+
+// Invite — код приглашения в матч.
+type Invite struct {
+	Code      string
+	MatchID   string
+	ExpiresAt time.Time
+}
+
+// InviteStore хранит соответствие код -> matchId с истечением.
+type InviteStore interface {
+	// Create выдаёт новый код для матча.
+	Create(ctx context.Context, matchID string) (Invite, error)
+	// Resolve находит живое приглашение по нормализованному коду.
+	Resolve(ctx context.Context, code string) (Invite, bool, error)
+}
+
+// NewInviteCode — случайный код из inviteAlphabet без modulo bias.
+func NewInviteCode() string {
+	b := make([]byte, inviteCodeLen)
+	for i := range b {
+		b[i] = inviteAlphabet[randIntn(len(inviteAlphabet))]
+	}
+	return string(b)
+}
+
+// NormalizeInviteCode приводит введённый руками код к каноническому виду:
+// регистр не важен, пробелы и дефисы ("ABC-123") игнорируются.
+func NormalizeInviteCode(s string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		switch {
+		case r == ' ' || r == '-':
+			continue
+		case r > 0x7f || !strings.ContainsRune(inviteAlphabet, r):
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() != inviteCodeLen {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// joinURL — ссылка для приглашения: {base}/J/{code}. Схему и хост переводим в верхний регистр
+// (они регистронезависимы), чтобы ссылка оставалась QR-alphanumeric; путь base не трогаем.
+func joinURL(base, code string) string {
+	base = strings.TrimSuffix(base, "/")
+	if u, err := url.Parse(base); err == nil && u.Host != "" {
+		u.Scheme = strings.ToUpper(u.Scheme)
+		u.Host = strings.ToUpper(u.Host)
+		base = u.String()
+	}
+	return base + "/J/" + code
+}
+
+type RedisInviteStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewRedisInviteStore(rdb *redis.Client, ttl time.Duration) *RedisInviteStore {
+	return &RedisInviteStore{rdb: rdb, ttl: ttl}
+}
+
+func (s *RedisInviteStore) key(code string) string {
+	return "invite:" + code
+}
+
+func (s *RedisInviteStore) Create(ctx context.Context, matchID string) (Invite, error) {
+	for range inviteCreateAttempts {
+		code := NewInviteCode()
+		// SET NX: живое приглашение с тем же кодом не перезаписываем
+		ok, err := s.rdb.SetNX(ctx, s.key(code), matchID, s.ttl).Result()
+		if err != nil {
+			return Invite{}, err
+		}
+		if ok {
+			return Invite{Code: code, MatchID: matchID, ExpiresAt: time.Now().Add(s.ttl)}, nil
+		}
+	}
+	return Invite{}, errInviteCollision
+}
+
+func (s *RedisInviteStore) Resolve(ctx context.Context, code string) (Invite, bool, error) {
+	pipe := s.rdb.Pipeline()
+	get := pipe.Get(ctx, s.key(code))
+	ttl := pipe.PTTL(ctx, s.key(code))
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return Invite{}, false, nil
+		}
+		return Invite{}, false, err
+	}
+	inv := Invite{Code: code, MatchID: get.Val()}
+	if d := ttl.Val(); d > 0 {
+		inv.ExpiresAt = time.Now().Add(d)
+	}
+	return inv, true, nil
+}
+
+// handleInvite — GET /api/invite/{code}: matchId по коду приглашения.
+func (s *Server) handleInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	inv, ok := s.resolveInvite(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"code":      inv.Code,
+		"matchId":   inv.MatchID,
+		"joinUrl":   joinURL(s.joinBase, inv.Code),
+		"expiresAt": inv.ExpiresAt,
+	})
+}
+
+// handleJoinLink — /J/{code} (ссылка из QR): редирект на фронтенд с ?match={matchId}.
+func (s *Server) handleJoinLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	inv, ok := s.resolveInvite(w, r)
+	if !ok {
+		return
+	}
+	http.Redirect(w, r, "/?match="+url.QueryEscape(inv.MatchID), http.StatusFound)
+}
+
+func (s *Server) resolveInvite(w http.ResponseWriter, r *http.Request) (Invite, bool) {
+	code, ok := NormalizeInviteCode(r.PathValue("code"))
+	if !ok {
+		http.Error(w, "invite not found", http.StatusNotFound)
+		return Invite{}, false
+	}
+	inv, found, err := s.invites.Resolve(r.Context(), code)
+	if err != nil {
+		http.Error(w, "storage error", http.StatusInternalServerError)
+		return Invite{}, false
+	}
+	if !found {
+		http.Error(w, "invite not found", http.StatusNotFound)
+		return Invite{}, false
+	}
+	return inv, true
+}
@@ -0,0 +1,148 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memInvites struct {
+	m map[string]Invite
+}
+
+func (s *memInvites) Create(ctx context.Context, matchID string) (Invite, error) {
+	if s.m == nil {
+		s.m = make(map[string]Invite)
+	}
+	inv := Invite{Code: NewInviteCode(), MatchID: matchID, ExpiresAt: time.Now().Add(time.Hour)}
+	s.m[inv.Code] = inv
+	return inv, nil
+}
+
+func (s *memInvites) Resolve(ctx context.Context, code string) (Invite, bool, error) {
+	inv, ok := s.m[code]
+	return inv, ok, nil
+}
+
+func TestNormalizeInviteCode(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{in: "ABC234", want: "ABC234", ok: true},
+		{in: "abc234", want: "ABC234", ok: true},
+		{in: " abc-234 ", want: "ABC234", ok: true},
+		{in: "ABC23", ok: false},
+		{in: "ABC2345", ok: false},
+		{in: "ABC0O1", ok: false}, // неоднозначные символы в коды не попадают
+		{in: "ABC23é", ok: false},
+		{in: "", ok: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, ok := NormalizeInviteCode(tc.in)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNewInviteCode(t *testing.T) {
+	for range 100 {
+		code := NewInviteCode()
+		got, ok := NormalizeInviteCode(code)
+		require.True(t, ok, code)
+		require.Equal(t, code, got)
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	assert.Equal(t, "HTTPS://PLAY.EXAMPLE.COM/J/ABC234", joinURL("https://play.example.com/", "ABC234"))
+	assert.Equal(t, "HTTP://LOCALHOST:8080/app/J/ABC234", joinURL("http://localhost:8080/app", "ABC234"))
+}
+
+func TestRandID_Alphabet(t *testing.T) {
+	id := randID(1000)
+	require.Len(t, id, 1000)
+	_, ok := matchIDFromWSPath("/ws/" + id[:64])
+	assert.True(t, ok, "match ids must stay valid for /ws/{matchId}")
+}
+
+func TestServer_Invites(t *testing.T) {
+	cfg := Config{}
+	server := NewServer(cfg, NewMatchService(cfg, &memPersist{}), testVerifier{})
+	server.SetInvites(&memInvites{}, "http://localhost:8080")
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/api/match", "application/json", nil)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var created struct {
+		MatchID    string `json:"matchId"`
+		InviteCode string `json:"inviteCode"`
+		JoinURL    string `json:"joinUrl"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	require.NotEmpty(t, created.InviteCode)
+	assert.Equal(t, "HTTP://LOCALHOST:8080/J/"+created.InviteCode, created.JoinURL)
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "code resolves to the match, case-insensitive",
+			run: func(t *testing.T) {
+				res, err := http.Get(ts.URL + "/api/invite/" + strings.ToLower(created.InviteCode))
+				require.NoError(t, err)
+				defer res.Body.Close()
+				require.Equal(t, http.StatusOK, res.StatusCode)
+				var inv struct {
+					Code    string `json:"code"`
+					MatchID string `json:"matchId"`
+				}
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&inv))
+				assert.Equal(t, created.InviteCode, inv.Code)
+				assert.Equal(t, created.MatchID, inv.MatchID)
+			},
+		},
+		{
+			name: "unknown code",
+			run: func(t *testing.T) {
+				res, err := http.Get(ts.URL + "/api/invite/ZZZZZZ")
+				require.NoError(t, err)
+				res.Body.Close()
+				assert.Equal(t, http.StatusNotFound, res.StatusCode)
+			},
+		},
+		{
+			name: "join link redirects to the frontend",
+			run: func(t *testing.T) {
+				c := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+				res, err := c.Get(ts.URL + "/J/" + created.InviteCode)
+				require.NoError(t, err)
+				res.Body.Close()
+				assert.Equal(t, http.StatusFound, res.StatusCode)
+				assert.Equal(t, "/?match="+created.MatchID, res.Header.Get("Location"))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -0,0 +1,137 @@
+package game
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LobbyEntry — открытый публичный матч в лобби.
+type LobbyEntry struct {
+	MatchID     string    `json:"matchId"`
+	CreatedBy   string    `json:"createdBy"`
+	CreatorName string    `json:"creatorName"`
+	Rules       Rules     `json:"rules"`
+	Slots       int       `json:"slots"` // сколько игроков нужно (с учётом значений по умолчанию в Rules)
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// LobbyEvent — изменение лобби: Added != nil — матч появился, иначе Removed — matchId ушедшего.
+type LobbyEvent struct {
+	Added   *LobbyEntry
+	Removed string
+}
+
+// Lobby — список публичных матчей, ждущих игроков (GET /api/lobby).
+//
+// Живёт в памяти инстанса, как и сами матчи: матч попадает в лобби при создании
+// с public=true и уходит, когда updatePhaseLocked выводит его из waiting_players
+// (или по TTL, если соперник так и не нашёлся). После рестарта матч возвращается в лобби,
+// когда GetOrLoad поднимает его из snapshot-а.
+type Lobby struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]LobbyEntry
+	subs    map[chan LobbyEvent]struct{}
+}
+
+const lobbySubBuffer = 32
+
+func NewLobby(ttl time.Duration) *Lobby {
+	return &Lobby{
+		ttl:     ttl,
+		entries: make(map[string]LobbyEntry),
+		subs:    make(map[chan LobbyEvent]struct{}),
+	}
+}
+
+// List — открытые матчи, новые первыми.
+func (l *Lobby) List() []LobbyEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pruneLocked(time.Now())
+
+	out := make([]LobbyEntry, 0, len(l.entries))
+	for _, e := range l.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].MatchID < out[j].MatchID
+	})
+	return out
+}
+
+// Subscribe — поток изменений лобби; cancel обязателен. Медленный подписчик теряет события
+// (клиент может перечитать GET /api/lobby). Канал закрывается по cancel или Shutdown.
+func (l *Lobby) Subscribe() (<-chan LobbyEvent, func()) {
+	ch := make(chan LobbyEvent, lobbySubBuffer)
+	l.mu.Lock()
+	l.subs[ch] = struct{}{}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.unsubscribeLocked(ch)
+	}
+}
+
+// Shutdown закрывает всех подписчиков, чтобы SSE-потоки не держали srv.Shutdown.
+func (l *Lobby) Shutdown() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.subs {
+		l.unsubscribeLocked(ch)
+	}
+}
+
+func (l *Lobby) unsubscribeLocked(ch chan LobbyEvent) {
+	if _, ok := l.subs[ch]; !ok {
+		return
+	}
+	delete(l.subs, ch)
+	close(ch)
+}
+
+func (l *Lobby) add(e LobbyEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pruneLocked(e.CreatedAt)
+	l.entries[e.MatchID] = e
+	l.publishLocked(LobbyEvent{Added: &e})
+}
+
+func (l *Lobby) remove(matchID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.entries[matchID]; !ok {
+		return
+	}
+	delete(l.entries, matchID)
+	l.publishLocked(LobbyEvent{Removed: matchID})
+}
+
+// pruneLocked убирает матчи, которые провисели в лобби дольше ttl.
+func (l *Lobby) pruneLocked(now time.Time) {
+	if l.ttl <= 0 {
+		return
+	}
+	for id, e := range l.entries {
+		if now.Sub(e.CreatedAt) >= l.ttl {
+			delete(l.entries, id)
+			l.publishLocked(LobbyEvent{Removed: id})
+		}
+	}
+}
+
+func (l *Lobby) publishLocked(ev LobbyEvent) {
+	for ch := range l.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLobby(t *testing.T) {
+	ctx := context.Background()
+	newService := func(ttl time.Duration) (*MatchService, *Lobby) {
+		svc := NewMatchService(Config{}, &memPersist{})
+		l := NewLobby(ttl)
+		svc.SetLobby(l)
+		return svc, l
+	}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "only public matches are listed",
+			run: func(t *testing.T) {
+				svc, l := newService(time.Hour)
+				_, err := svc.CreateWithOptions(ctx, "pub", Rules{BestOf: 3}, CreateOptions{CreatedBy: "u1", CreatorName: "Alice", Public: true})
+				require.NoError(t, err)
+				_, err = svc.CreateWithOptions(ctx, "priv", Rules{}, CreateOptions{CreatedBy: "u1"})
+				require.NoError(t, err)
+
+				list := l.List()
+				require.Len(t, list, 1)
+				assert.Equal(t, "pub", list[0].MatchID)
+				assert.Equal(t, "Alice", list[0].CreatorName)
+				assert.Equal(t, 3, list[0].Rules.BestOf)
+				assert.Equal(t, 2, list[0].Slots)
+			},
+		},
+		{
+			name: "match leaves the lobby once it stops waiting for players",
+			run: func(t *testing.T) {
+				svc, l := newService(time.Hour)
+				m, err := svc.CreateWithOptions(ctx, "m1", Rules{}, CreateOptions{CreatedBy: "u1", Public: true})
+				require.NoError(t, err)
+				events, cancel := l.Subscribe()
+				defer cancel()
+
+				_, code, _ := m.Attach("u1", "Alice", newTestConn())
+				require.Empty(t, code)
+				assert.Len(t, l.List(), 1, "creator alone is still waiting")
+
+				_, code, _ = m.Attach("u2", "Bob", newTestConn())
+				require.Empty(t, code)
+				assert.Empty(t, l.List())
+				assert.Equal(t, LobbyEvent{Removed: "m1"}, <-events)
+
+				// отвалился игрок — матч снова в waiting_players, но в лобби не возвращается
+				m.Detach(P2)
+				assert.Empty(t, l.List())
+			},
+		},
+		{
+			name: "subscribers see matches added",
+			run: func(t *testing.T) {
+				svc, l := newService(time.Hour)
+				events, cancel := l.Subscribe()
+				defer cancel()
+
+				_, err := svc.CreateWithOptions(ctx, "m1", Rules{}, CreateOptions{CreatedBy: "u1", Public: true})
+				require.NoError(t, err)
+				ev := <-events
+				require.NotNil(t, ev.Added)
+				assert.Equal(t, "m1", ev.Added.MatchID)
+			},
+		},
+		{
+			name: "stale matches expire",
+			run: func(t *testing.T) {
+				svc, l := newService(time.Millisecond)
+				_, err := svc.CreateWithOptions(ctx, "m1", Rules{}, CreateOptions{CreatedBy: "u1", Public: true})
+				require.NoError(t, err)
+				time.Sleep(5 * time.Millisecond)
+				assert.Empty(t, l.List())
+			},
+		},
+		{
+			name: "waiting match is listed again after a restart",
+			run: func(t *testing.T) {
+				persist := &memPersist{}
+				svc := NewMatchService(Config{}, persist)
+				svc.SetLobby(NewLobby(time.Hour))
+				_, err := svc.CreateWithOptions(ctx, "m1", Rules{BestOf: 3}, CreateOptions{CreatedBy: "u1", CreatorName: "Alice", Public: true})
+				require.NoError(t, err)
+				created := svc.lobby.List()[0].CreatedAt
+
+				restarted := NewMatchService(Config{}, persist)
+				l := NewLobby(time.Hour)
+				restarted.SetLobby(l)
+				assert.Empty(t, l.List())
+
+				m, ok, err := restarted.GetOrLoad(ctx, "m1")
+				require.NoError(t, err)
+				require.True(t, ok)
+				list := l.List()
+				require.Len(t, list, 1)
+				assert.Equal(t, "Alice", list[0].CreatorName)
+				assert.Equal(t, 3, list[0].Rules.BestOf)
+				assert.Equal(t, created.UnixMilli(), list[0].CreatedAt.UnixMilli(), "TTL runs from the original listing")
+
+				_, code, _ := m.Attach("u1", "Alice", newTestConn())
+				require.Empty(t, code)
+				_, code, _ = m.Attach("u2", "Bob", newTestConn())
+				require.Empty(t, code)
+				assert.Empty(t, l.List())
+			},
+		},
+		{
+			name: "started match is not listed after a restart",
+			run: func(t *testing.T) {
+				persist := &memPersist{}
+				svc := NewMatchService(Config{}, persist)
+				svc.SetLobby(NewLobby(time.Hour))
+				m, err := svc.CreateWithOptions(ctx, "m1", Rules{}, CreateOptions{CreatedBy: "u1", Public: true})
+				require.NoError(t, err)
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+
+				restarted := NewMatchService(Config{}, persist)
+				l := NewLobby(time.Hour)
+				restarted.SetLobby(l)
+				_, ok, err := restarted.GetOrLoad(ctx, "m1")
+				require.NoError(t, err)
+				require.True(t, ok)
+				assert.Empty(t, l.List(), "players left after the restart, but the slots are taken")
+			},
+		},
+		{
+			name: "reserved match cannot be public",
+			run: func(t *testing.T) {
+				svc, l := newService(time.Hour)
+				_, err := svc.CreateWithOptions(ctx, "m1", Rules{}, CreateOptions{CreatedBy: "u1", Reserved: []string{"u1", "u2"}, Public: true})
+				assert.Error(t, err)
+				assert.Empty(t, l.List())
+			},
+		},
+		{
+			name: "shutdown closes subscriptions",
+			run: func(t *testing.T) {
+				_, l := newService(time.Hour)
+				events, cancel := l.Subscribe()
+				l.Shutdown()
+				_, ok := <-events
+				assert.False(t, ok)
+				cancel() // still safe after shutdown
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -3,9 +3,13 @@ package game
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+
+	"example.com/bc-mvp/internal/notify"
 )
 
 type Slot string
@@ -15,6 +19,11 @@ const (
 	P2 Slot = "p2"
 )
 
+// slotAt — имя слота по индексу: 0 => p1, 1 => p2, ...
+func slotAt(i int) Slot {
+	return Slot(fmt.Sprintf("p%d", i+1))
+}
+
 type Match struct {
 	id string
 	mu sync.Mutex
@@ -27,19 +36,53 @@ type Match struct {
 	roundTimer  *time.Timer
 	roundToken  int64
 	roundDur    time.Duration
-	winner      string // p1|p2|draw|""
-
-	p1 *Player
-	p2 *Player
-
-	history      []RoundHistoryItem
-	seriesP1Wins int
-	seriesP2Wins int
-	seriesDraws  int
-	onPersist    func(MatchSnapshot)
+	turn        Slot   // alternating: чей сейчас ход ("" в simultaneous)
+	winner      string // p1..pN|draw|""
+
+	finishReason string // solved|max_rounds|no_show|"" (если не закончено)
+	rankings     []Ranking
+
+	rules Rules
+
+	// players[i] сидит в слоте slotAt(i); для классической партии это p1 и p2
+	players []*Player
+
+	// приватный матч (вызов через /api/challenges): занять слот могут только эти userId; nil — открытый
+	reserved []string
+	// дедлайн неявки (noshow.go): к этому времени приглашённые должны занять слоты; zero — не ждём
+	joinBy    time.Time
+	joinTimer *time.Timer
+	// userId создателя матча ("" — создан анонимно)
+	createdBy string
+	// матч виден в публичном лобби; снимается, как только фаза уходит из waiting_players
+	listed bool
+	// для записи в лобби: имя создателя и когда матч туда попал (TTL считается от него)
+	creatorName string
+	listedAt    time.Time
+
+	// target=shared: общий секрет, который загадывает сервер
+	sharedSecret string
+	sharedNonce  string
+
+	// Rules.Teams: команды t1 (p1, p2) и t2 (p3, p4); иначе nil
+	teams []*Team
+
+	history        []RoundHistoryItem
+	gamesPlayed    int // сколько партий сыграно в рамках matchId (включая рематчи)
+	series         SeriesScore
+	seriesFinished bool // best-of-N серия сыграна, рематчи запрещены
+
+	chat []ChatMessage // последние chatLogLen сообщений чата (chat.go)
+
+	onPersist        func(MatchSnapshot)
+	onGameFinished   func(GameResult)
+	onSeriesFinished func(SeriesResult)
+	onNotify         func(userID string, ev notify.Event)
+	onUnlist         func()
 }
 
 type Player struct {
+	slot Slot
 	id   string
 	name string
 	conn *ClientConn
@@ -49,19 +92,42 @@ type Player struct {
 
 	secret    string
 	secretSet bool
+	nonce     string // commit-reveal: серверный nonce
+	salt      string // commit-reveal: соль клиента
+
+	guess     string
+	guessSet  bool
+	missed    bool
+	guessLeft time.Duration // сколько оставалось до дедлайна, когда пришёл guess (0 — без таймера)
 
-	guess    string
-	guessSet bool
-	missed   bool
+	solvedRound int           // раунд, в котором игрок отгадал свою цель (0 — ещё нет)
+	solvedLeft  time.Duration // guessLeft отгадавшей попытки (достижения)
+
+	muted    map[Slot]bool // чьи сообщения в чате игрок не получает
+	chatSent []time.Time   // время последних сообщений (лимит частоты), не сохраняется
 }
 
 func NewMatch(id string, roundDur time.Duration) *Match {
-	return &Match{
+	return NewMatchWithRules(id, roundDur, Rules{})
+}
+
+func NewMatchWithRules(id string, roundDur time.Duration, rules Rules) *Match {
+	m := &Match{
 		id:       id,
 		phase:    "waiting_players",
-		roundDur: roundDur,
-		p1:       &Player{},
-		p2:       &Player{},
+		roundDur: rules.roundDuration(roundDur),
+		rules:    rules,
+	}
+	m.resetSlotsLocked(rules.players())
+	m.resetTeamsLocked()
+	return m
+}
+
+// resetSlotsLocked создаёт n пустых слотов p1..pN.
+func (m *Match) resetSlotsLocked(n int) {
+	m.players = make([]*Player, n)
+	for i := range m.players {
+		m.players[i] = &Player{slot: slotAt(i)}
 	}
 }
 
@@ -69,43 +135,45 @@ func (m *Match) Attach(playerID, displayName string, cc *ClientConn) (Slot, stri
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	name := strings.TrimSpace(displayName)
+
 	// reconnect?
-	if m.p1.id == playerID && m.p1.id != "" {
-		m.p1.conn = cc
-		m.p1.connected = true
-		if strings.TrimSpace(displayName) != "" {
-			m.p1.name = strings.TrimSpace(displayName)
-		}
-		return P1, "", ""
-	}
-	if m.p2.id == playerID && m.p2.id != "" {
-		m.p2.conn = cc
-		m.p2.connected = true
-		if strings.TrimSpace(displayName) != "" {
-			m.p2.name = strings.TrimSpace(displayName)
+	for _, p := range m.players {
+		if p.id == playerID && p.id != "" {
+			p.conn = cc
+			p.connected = true
+			if name != "" {
+				p.name = name
+			}
+			return p.slot, "", ""
 		}
-		return P2, "", ""
 	}
 
 	// new join
-	if m.p1.id == "" {
-		m.p1.id = playerID
-		m.p1.name = strings.TrimSpace(displayName)
-		m.p1.conn = cc
-		m.p1.connected = true
-		m.updatePhaseLocked()
-		return P1, "", ""
-	}
-	if m.p2.id == "" && m.p1.id != playerID {
-		m.p2.id = playerID
-		m.p2.name = strings.TrimSpace(displayName)
-		m.p2.conn = cc
-		m.p2.connected = true
-		m.updatePhaseLocked()
-		return P2, "", ""
+	if len(m.reserved) > 0 && !slices.Contains(m.reserved, playerID) {
+		return "", "not_invited", "match is reserved for invited players"
+	}
+	for _, p := range m.players {
+		if p.id == "" {
+			p.id = playerID
+			p.name = name
+			p.conn = cc
+			p.connected = true
+			if m.createdBy != playerID {
+				m.notifyAwayLocked(m.createdBy, "opponent_joined", p)
+			}
+			if m.allJoinedLocked() {
+				m.stopJoinTimerLocked()
+			}
+			m.updatePhaseLocked()
+			m.maybeStartLocked()
+			// занятый слот и снятие с лобби должны пережить рестарт
+			m.persistLocked()
+			return p.slot, "", ""
+		}
 	}
 
-	return "", "match_full", "match already has two players"
+	return "", "match_full", "match already has all players"
 }
 
 func (m *Match) Detach(slot Slot) {
@@ -113,15 +181,26 @@ func (m *Match) Detach(slot Slot) {
 	defer m.mu.Unlock()
 
 	p := m.playerLocked(slot)
+	if p == nil {
+		return
+	}
 	p.connected = false
 	p.conn = nil
 	m.updatePhaseLocked()
 }
 
 func (m *Match) SetSecret(slot Slot, secret string) error {
+	return m.SetSecretWithSalt(slot, secret, "")
+}
+
+// SetSecretWithSalt загадывает секрет и публикует commitment с солью клиента (см. commit.go).
+func (m *Match) SetSecretWithSalt(slot Slot, secret, salt string) error {
 	if !valid4Digits(secret) {
 		return errors.New("secret must be exactly 4 digits (0-9)")
 	}
+	if len(salt) > MaxSaltLen {
+		return fmt.Errorf("salt must be at most %d characters", MaxSaltLen)
+	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -129,29 +208,61 @@ func (m *Match) SetSecret(slot Slot, secret string) error {
 	if m.phase == "finished" {
 		return errors.New("game already finished")
 	}
+	if m.rules.sharedTarget() {
+		return errors.New("secret is chosen by the server in this match")
+	}
+	if m.round > 0 {
+		return errors.New("secret is locked once the game has started")
+	}
 
 	p := m.playerLocked(slot)
-	p.secret = secret
-	p.secretSet = true
+	if p == nil {
+		return errors.New("unknown slot")
+	}
+	if t := m.teamOfLocked(slot); t != nil {
+		// секрет общий на команду
+		t.secret = secret
+		t.secretSet = true
+		t.nonce = randNonce()
+		t.salt = salt
+	} else {
+		p.secret = secret
+		p.secretSet = true
+		p.nonce = randNonce()
+		p.salt = salt
+	}
 
 	m.updatePhaseLocked()
-
-	// ✅ ВАЖНО: стартуем раунд по факту готовности секретов,
-	// а не по значению phase, потому что phase уже могла стать "playing".
-	if m.p1.secretSet && m.p2.secretSet && !m.roundActive && m.round == 0 && m.phase != "finished" {
-		m.phase = "playing"
-		m.startRoundLocked()
-	}
+	m.maybeStartLocked()
 
 	m.broadcastStateLocked()
 	m.persistLocked()
 	return nil
 }
 
+// maybeStartLocked стартует первый раунд, как только все слоты заняты и все секреты готовы.
+//
+// ✅ ВАЖНО: стартуем раунд по факту готовности секретов,
+// а не по значению phase, потому что phase уже могла стать "playing".
+func (m *Match) maybeStartLocked() {
+	if m.roundActive || m.round != 0 || m.phase == "finished" {
+		return
+	}
+	if !m.allJoinedLocked() || !m.secretsReadyLocked() {
+		return
+	}
+	m.phase = "playing"
+	m.startRoundLocked()
+}
+
 func (m *Match) SubmitGuess(slot Slot, guess string) error {
 	if !valid4Digits(guess) {
 		return errors.New("guess must be exactly 4 digits (0-9)")
 	}
+	if m.rules.Teams {
+		// в командах submit_guess — это предложение варианта с голосом за него
+		return m.ProposeGuess(slot, guess)
+	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -164,20 +275,40 @@ func (m *Match) SubmitGuess(slot Slot, guess string) error {
 	}
 
 	p := m.playerLocked(slot)
+	if p == nil {
+		return errors.New("unknown slot")
+	}
+	if p.solvedRound > 0 {
+		return errors.New("you already solved your target")
+	}
 	if p.guessSet || p.missed {
 		return errors.New("guess already submitted (or missed)")
 	}
+	if m.rules.alternating() && slot != m.turn {
+		return errors.New("not your turn")
+	}
 
 	p.guess = guess
 	p.guessSet = true
+	p.guessLeft = 0
+	if !m.deadline.IsZero() {
+		// не меньше миллисекунды: 0 значит «таймера не было»
+		p.guessLeft = max(time.Until(m.deadline), time.Millisecond)
+	}
+
+	if m.rules.alternating() {
+		m.finishTurnLocked()
+		m.persistLocked()
+		return nil
+	}
 
 	m.broadcastStateLocked()
 
-	// если оба ввели — закрываем раунд
-	if (m.p1.guessSet || m.p1.missed) && (m.p2.guessSet || m.p2.missed) {
+	// если все (кто ещё угадывает) ввели — закрываем раунд
+	if m.allGuessedLocked() {
 		m.finalizeRoundLocked()
 	}
-	// если раунд ещё не закрыт — сохраняем частичное состояние (что один игрок уже ввёл)
+	// если раунд ещё не закрыт — сохраняем частичное состояние (что кто-то уже ввёл)
 	m.persistLocked()
 	return nil
 }
@@ -189,21 +320,35 @@ func (m *Match) RequestRematch(slot Slot) error {
 	if m.phase != "finished" {
 		return errors.New("rematch available only after game finished")
 	}
+	if m.seriesFinished {
+		return errors.New("series already finished")
+	}
 
 	p := m.playerLocked(slot)
+	if p == nil {
+		return errors.New("unknown slot")
+	}
 	p.rematchRequested = true
+	for _, pl := range m.players {
+		if pl != p && !pl.rematchRequested {
+			m.notifyAwayLocked(pl.id, "rematch_requested", p)
+		}
+	}
 
 	// сообщаем состояние рематча
+	status := make(map[string]any, len(m.players))
+	all := true
+	for _, pl := range m.players {
+		status[string(pl.slot)] = pl.rematchRequested
+		all = all && pl.rematchRequested
+	}
 	m.broadcastLocked(Envelope{
-		Type: "rematch_status",
-		Payload: mustJSON(map[string]any{
-			"p1": m.p1.rematchRequested,
-			"p2": m.p2.rematchRequested,
-		}),
+		Type:    "rematch_status",
+		Payload: mustJSON(status),
 	})
 
-	// если оба согласились — стартуем новую игру
-	if m.p1.rematchRequested && m.p2.rematchRequested {
+	// если все согласились — стартуем новую игру
+	if all {
 		m.startRematchLocked()
 	}
 
@@ -212,10 +357,6 @@ func (m *Match) RequestRematch(slot Slot) error {
 }
 
 func (m *Match) startRematchLocked() {
-	// сбрасываем флаги рематча
-	m.p1.rematchRequested = false
-	m.p2.rematchRequested = false
-
 	// останавливаем таймер на всякий случай
 	if m.roundTimer != nil {
 		m.roundTimer.Stop()
@@ -224,45 +365,60 @@ func (m *Match) startRematchLocked() {
 	// сбрасываем состояние матча, но оставляем игроков и соединения
 	m.phase = "waiting_secrets"
 	m.winner = ""
+	m.finishReason = ""
+	m.rankings = nil
+	m.turn = ""
 	m.round = 0
 	m.roundActive = false
 	m.deadline = time.Time{}
 	m.history = nil
-
-	m.p1.secret = ""
-	m.p2.secret = ""
-	m.p1.secretSet = false
-	m.p2.secretSet = false
-
-	m.p1.guess = ""
-	m.p2.guess = ""
-	m.p1.guessSet = false
-	m.p2.guessSet = false
-	m.p1.missed = false
-	m.p2.missed = false
+	m.sharedSecret = ""
+	m.sharedNonce = ""
+	m.resetTeamsLocked()
+
+	for _, p := range m.players {
+		p.rematchRequested = false
+		p.secret = ""
+		p.secretSet = false
+		p.nonce = ""
+		p.salt = ""
+		p.guess = ""
+		p.guessSet = false
+		p.missed = false
+		p.guessLeft = 0
+		p.solvedRound = 0
+		p.solvedLeft = 0
+	}
 
 	// уведомляем фронт
 	m.broadcastLocked(Envelope{
 		Type: "rematch_started",
 		Payload: mustJSON(map[string]any{
-			"series": map[string]int{
-				"p1Wins": m.seriesP1Wins,
-				"p2Wins": m.seriesP2Wins,
-				"draws":  m.seriesDraws,
-			},
+			"series": m.series,
 		}),
 	})
 
+	// в shared-режиме секрет загадывает сервер — можно стартовать сразу
+	m.updatePhaseLocked()
+	m.maybeStartLocked()
+
 	m.broadcastStateLocked()
 	m.persistLocked()
 }
 
+// Ranked — итоги матча идут в рейтинговый зачёт (гостям такие матчи закрыты).
+func (m *Match) Ranked() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.rules.Unranked
+}
+
 func (m *Match) SendErrorTo(slot Slot, code, message string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	p := m.playerLocked(slot)
-	if p.conn == nil {
+	if p == nil || p.conn == nil {
 		return
 	}
 	m.sendLocked(p.conn, Envelope{
@@ -276,7 +432,7 @@ func (m *Match) SendStateTo(slot Slot) {
 	defer m.mu.Unlock()
 
 	p := m.playerLocked(slot)
-	if p.conn == nil {
+	if p == nil || p.conn == nil {
 		return
 	}
 	state := m.buildStateLocked(slot)
@@ -290,26 +446,35 @@ func (m *Match) BroadcastState() {
 }
 
 func (m *Match) broadcastStateLocked() {
-	// персонализируем "you" (p1/p2)
-	if m.p1.conn != nil {
-		state := m.buildStateLocked(P1)
-		m.sendLocked(m.p1.conn, Envelope{Type: "state", Payload: mustJSON(state)})
-	}
-	if m.p2.conn != nil {
-		state := m.buildStateLocked(P2)
-		m.sendLocked(m.p2.conn, Envelope{Type: "state", Payload: mustJSON(state)})
+	// персонализируем "you" (p1/p2/...)
+	for _, p := range m.players {
+		if p.conn == nil {
+			continue
+		}
+		state := m.buildStateLocked(p.slot)
+		m.sendLocked(p.conn, Envelope{Type: "state", Payload: mustJSON(state)})
 	}
 }
 
 func (m *Match) updatePhaseLocked() {
+	defer m.unlistLocked()
 	if m.phase == "finished" {
 		return
 	}
-	if m.p1.id == "" || m.p2.id == "" || !(m.p1.connected && m.p2.connected) {
+	if !m.allJoinedLocked() {
 		m.phase = "waiting_players"
 		return
 	}
-	if !m.p1.secretSet || !m.p2.secretSet {
+	// по переписке игроки не обязаны быть онлайн одновременно
+	if !m.rules.Correspondence {
+		for _, p := range m.players {
+			if !p.connected {
+				m.phase = "waiting_players"
+				return
+			}
+		}
+	}
+	if !m.secretsReadyLocked() {
 		m.phase = "waiting_secrets"
 		return
 	}
@@ -318,40 +483,148 @@ func (m *Match) updatePhaseLocked() {
 	}
 }
 
+// unlistLocked убирает матч из лобби, когда он перестал ждать игроков. Обратно не возвращаем:
+// если кто-то отвалился посреди игры, матч снова в waiting_players, но слоты уже заняты.
+func (m *Match) unlistLocked() {
+	if !m.listed || m.phase == "waiting_players" {
+		return
+	}
+	m.listed = false
+	if m.onUnlist != nil {
+		m.onUnlist()
+	}
+}
+
+// lobbyEntryLocked — запись матча для лобби.
+func (m *Match) lobbyEntryLocked() LobbyEntry {
+	return LobbyEntry{
+		MatchID:     m.id,
+		CreatedBy:   m.createdBy,
+		CreatorName: m.creatorName,
+		Rules:       m.rules,
+		Slots:       m.rules.players(),
+		CreatedAt:   m.listedAt,
+	}
+}
+
+func (m *Match) allJoinedLocked() bool {
+	for _, p := range m.players {
+		if p.id == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// secretsReadyLocked — все цели для угадывания загаданы.
+// В shared-режиме общий секрет загадывается при старте партии, от игроков ничего не ждём.
+func (m *Match) secretsReadyLocked() bool {
+	if m.rules.sharedTarget() {
+		return true
+	}
+	if m.rules.Teams {
+		return m.teamsSecretsReadyLocked()
+	}
+	for _, p := range m.players {
+		if !p.secretSet {
+			return false
+		}
+	}
+	return true
+}
+
+// allGuessedLocked — все, кто ещё угадывает, сделали ход (или пропустили).
+func (m *Match) allGuessedLocked() bool {
+	for _, p := range m.players {
+		if p.solvedRound > 0 {
+			continue
+		}
+		if !p.guessSet && !p.missed {
+			return false
+		}
+	}
+	return true
+}
+
 func (m *Match) startRoundLocked() {
+	if m.round == 0 && m.rules.sharedTarget() && m.sharedSecret == "" {
+		m.sharedSecret = randSecret()
+		m.sharedNonce = randNonce()
+	}
+
 	m.round++
 	m.roundActive = true
 
 	// reset per-round
-	m.p1.guessSet, m.p2.guessSet = false, false
-	m.p1.missed, m.p2.missed = false, false
-	m.p1.guess, m.p2.guess = "", ""
-
-	// deadline/timer (итерация 2)
-	if m.roundDur > 0 {
-		m.deadline = time.Now().Add(m.roundDur)
-		m.roundToken++
-		token := m.roundToken
-
-		if m.roundTimer != nil {
-			m.roundTimer.Stop()
-		}
-		m.roundTimer = time.AfterFunc(m.roundDur, func() {
-			m.onRoundTimeout(token)
-		})
-	} else {
-		m.deadline = time.Time{}
+	for _, p := range m.players {
+		p.guessSet = false
+		p.missed = false
+		p.guess = ""
+		p.guessLeft = 0
+	}
+	m.resetTeamRoundLocked()
+
+	// в alternating раунд начинает первый (по слотам) из тех, кто ещё угадывает
+	if m.rules.alternating() {
+		m.turn = m.nextTurnLocked("")
 	}
 
+	// deadline/timer (итерация 2); в alternating — отдельный таймер на каждый ход
+	m.armTimerLocked()
+	m.notifyMoveLocked()
+
 	// событие round_started
 	payload := RoundStartedPayload{
 		Round:      m.round,
 		DeadlineMs: toMs(m.deadline),
+		Turn:       string(m.turn),
 	}
 	m.broadcastLocked(Envelope{Type: "round_started", Payload: mustJSON(payload)})
 	m.broadcastStateLocked()
 }
 
+// armTimerLocked выставляет дедлайн текущего раунда (или хода) и заводит таймер.
+func (m *Match) armTimerLocked() {
+	if m.roundDur <= 0 {
+		m.deadline = time.Time{}
+		return
+	}
+
+	m.deadline = time.Now().Add(m.roundDur)
+	m.roundToken++
+	token := m.roundToken
+
+	if m.roundTimer != nil {
+		m.roundTimer.Stop()
+	}
+	m.roundTimer = time.AfterFunc(m.roundDur, func() {
+		m.onRoundTimeout(token)
+	})
+}
+
+// rearmTimerLocked заводит таймер заново по дедлайну из snapshot-а (после рестарта).
+// Если дедлайн прошёл, пока сервер лежал, матч по переписке получает таймаут сразу,
+// а в матче реального времени таймер не поднимаем, как и раньше.
+func (m *Match) rearmTimerLocked() {
+	if m.roundDur <= 0 || m.phase != "playing" || !m.roundActive || m.deadline.IsZero() {
+		return
+	}
+	d := time.Until(m.deadline)
+	if d <= 0 && !m.rules.Correspondence {
+		return
+	}
+	// новый token, чтобы старые таймеры (до рестарта) не влияли
+	m.roundToken++
+	token := m.roundToken
+
+	if m.roundTimer != nil {
+		m.roundTimer.Stop()
+	}
+	m.roundTimer = time.AfterFunc(max(d, 0), func() {
+		m.onRoundTimeout(token)
+	})
+}
+
 func (m *Match) onRoundTimeout(token int64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -363,12 +636,27 @@ func (m *Match) onRoundTimeout(token int64) {
 		return // старый таймер
 	}
 
+	if m.rules.alternating() {
+		// истёк таймер хода: пропуск только у того, чей ход
+		if p := m.playerLocked(m.turn); p != nil {
+			p.missed = true
+		}
+		m.finishTurnLocked()
+		m.persistLocked()
+		return
+	}
+
 	// вариант A: у кого нет guess — пропуск
-	if !m.p1.guessSet {
-		m.p1.missed = true
+	for _, p := range m.players {
+		if p.solvedRound == 0 && !p.guessSet {
+			p.missed = true
+		}
 	}
-	if !m.p2.guessSet {
-		m.p2.missed = true
+	// команды, не договорившиеся о догадке, тоже пропускают
+	for _, t := range m.teams {
+		if !t.guessSet {
+			t.missed = true
+		}
 	}
 
 	m.broadcastStateLocked()
@@ -387,61 +675,101 @@ func (m *Match) finalizeRoundLocked() {
 		m.roundTimer.Stop()
 	}
 
-	// считаем результат для каждого: guess против секрета соперника
-	a1 := m.attemptLocked(P1)
-	a2 := m.attemptLocked(P2)
+	if m.rules.Teams {
+		m.finalizeTeamRoundLocked()
+		return
+	}
 
-	item := RoundHistoryItem{
-		Round: m.round,
-		P1:    a1,
-		P2:    a2,
+	// считаем результат для каждого, кто ещё угадывает: guess против его цели
+	att := make(map[Slot]Attempt, len(m.players))
+	for _, p := range m.players {
+		if p.solvedRound > 0 {
+			continue
+		}
+		att[p.slot] = m.attemptLocked(p.slot)
 	}
+
+	item := m.newHistoryItem("", att)
 	m.history = append(m.history, item)
 
-	// победа/ничья
-	p1win := a1.Guess != nil && a1.Bulls == 4
-	p2win := a2.Guess != nil && a2.Bulls == 4
+	m.decideRoundLocked(att)
+	m.afterRoundLocked(item)
+}
 
+// decideRoundLocked отмечает отгадавших и решает, закончена ли партия.
+//
+// Дуэль (2 игрока) заканчивается в первом же раунде, где кто-то отгадал
+// (оба в одном раунде — ничья). Free-for-all продолжается, пока угадывать
+// не останется максимум один игрок; места — по раунду отгадки.
+func (m *Match) decideRoundLocked(att map[Slot]Attempt) {
+	for _, p := range m.players {
+		a, ok := att[p.slot]
+		if ok && p.solvedRound == 0 && a.Guess != nil && a.Bulls == 4 {
+			p.solvedRound = m.round
+			p.solvedLeft = p.guessLeft
+		}
+	}
+
+	unsolved := 0
+	for _, p := range m.players {
+		if p.solvedRound == 0 {
+			unsolved++
+		}
+	}
+
+	n := len(m.players)
 	switch {
-	case p1win && p2win:
-		m.winner = "draw"
-		m.phase = "finished"
-	case p1win:
-		m.winner = "p1"
-		m.phase = "finished"
-	case p2win:
-		m.winner = "p2"
-		m.phase = "finished"
+	case n == 2 && unsolved < n, n > 2 && unsolved <= 1:
+		m.finishReason = ReasonSolved
+	case m.rules.MaxRounds > 0 && m.round >= m.rules.MaxRounds:
+		// лимит раундов исчерпан — оставшихся решаем по tiebreak
+		m.finishReason = ReasonMaxRounds
+	default:
+		return
 	}
 
-	if m.phase == "finished" {
+	m.phase = "finished"
+	m.gamesPlayed++
+	m.rankings = m.rankLocked()
+	m.winner = winnerFromRankings(m.rankings)
+
+	// счёт серии ведём только для дуэли
+	if n == 2 {
 		switch m.winner {
 		case "p1":
-			m.seriesP1Wins++
+			m.series.P1Wins++
 		case "p2":
-			m.seriesP2Wins++
+			m.series.P2Wins++
 		case "draw":
-			m.seriesDraws++
+			m.series.Draws++
 		}
+		m.seriesFinished = m.seriesClinchedLocked()
 	}
+}
 
+// afterRoundLocked рассылает итог раунда и либо завершает партию, либо стартует следующий раунд.
+func (m *Match) afterRoundLocked(item RoundHistoryItem) {
 	// событие round_result
 	m.broadcastLocked(Envelope{Type: "round_result", Payload: mustJSON(item)})
 	m.broadcastStateLocked()
 
 	if m.phase == "finished" {
+		m.turn = ""
 		m.broadcastLocked(Envelope{
 			Type: "series_score",
 			Payload: mustJSON(map[string]any{
-				"series": map[string]int{
-					"p1Wins": m.seriesP1Wins,
-					"p2Wins": m.seriesP2Wins,
-					"draws":  m.seriesDraws,
-				},
+				"series": m.series,
 			}),
 		})
 
-		m.broadcastLocked(Envelope{Type: "game_finished", Payload: mustJSON(map[string]string{"winner": m.winner})})
+		m.broadcastLocked(Envelope{Type: "game_finished", Payload: mustJSON(m.gameFinishedPayloadLocked())})
+		m.recordGameLocked()
+
+		if m.seriesFinished {
+			m.broadcastLocked(Envelope{Type: "series_finished", Payload: mustJSON(m.seriesFinishedPayloadLocked())})
+			m.recordSeriesLocked()
+		}
+
 		m.broadcastStateLocked()
 		m.persistLocked()
 		return
@@ -452,19 +780,42 @@ func (m *Match) finalizeRoundLocked() {
 	m.startRoundLocked()
 }
 
-func (m *Match) attemptLocked(slot Slot) Attempt {
-	var me *Player
-	var opp *Player
-	if slot == P1 {
-		me = m.p1
-		opp = m.p2
-	} else {
-		me = m.p2
-		opp = m.p1
+// newHistoryItem собирает элемент history. p1/p2 заполняем всегда (формат дуэли),
+// attempts — только если игроков больше двух.
+func (m *Match) newHistoryItem(turn Slot, att map[Slot]Attempt) RoundHistoryItem {
+	item := RoundHistoryItem{
+		Round: m.round,
+		Turn:  string(turn),
+		P1:    att[P1],
+		P2:    att[P2],
+	}
+	if len(m.players) > 2 {
+		item.Attempts = make(map[string]Attempt, len(att))
+		for s, a := range att {
+			item.Attempts[string(s)] = a
+		}
+	}
+	return item
+}
+
+// targetSecretLocked — секрет, который угадывает игрок:
+// общий (target=shared) или секрет следующего по кругу игрока (для дуэли — соперника).
+func (m *Match) targetSecretLocked(slot Slot) string {
+	if m.rules.sharedTarget() {
+		return m.sharedSecret
+	}
+	i := m.slotIndex(slot)
+	if i < 0 {
+		return ""
 	}
+	return m.players[(i+1)%len(m.players)].secret
+}
+
+func (m *Match) attemptLocked(slot Slot) Attempt {
+	me := m.playerLocked(slot)
 
 	// пропуск
-	if me.missed || !me.guessSet {
+	if me == nil || me.missed || !me.guessSet {
 		return Attempt{
 			Guess:  nil,
 			Bulls:  0,
@@ -474,7 +825,7 @@ func (m *Match) attemptLocked(slot Slot) Attempt {
 	}
 
 	g := me.guess
-	b, c := BullsCows(opp.secret, g)
+	b, c := BullsCows(m.targetSecretLocked(slot), g)
 	return Attempt{
 		Guess:  &g,
 		Bulls:  b,
@@ -487,53 +838,100 @@ func (m *Match) buildStateLocked(slot Slot) StatePayload {
 	you := string(slot)
 
 	connected := 0
-	if m.p1.connected {
-		connected++
-	}
-	if m.p2.connected {
-		connected++
+	names := make(map[string]string, len(m.players))
+	ids := make(map[string]string, len(m.players))
+	secretsReady := make(map[string]bool, len(m.players))
+	guessesReady := make(map[string]bool, len(m.players))
+	for _, p := range m.players {
+		if p.connected {
+			connected++
+		}
+		s := string(p.slot)
+		names[s] = p.name
+		if p.id != "" {
+			ids[s] = p.id
+		}
+		secretsReady[s] = p.secretSet
+		guessesReady[s] = p.guessSet || p.missed
+		if t := m.teamOfLocked(p.slot); t != nil {
+			secretsReady[s] = t.secretSet
+			guessesReady[s] = t.guessSet || t.missed
+		}
 	}
 
 	st := StatePayload{
-		MatchID: m.id,
-		You:     you,
-		PlayerNames: map[string]string{
-			"p1": m.p1.name,
-			"p2": m.p2.name,
-		},
+		MatchID:          m.id,
+		You:              you,
+		PlayerNames:      names,
+		PlayerIDs:        ids,
 		PlayersConnected: connected,
 		Phase:            m.phase,
 		Round:            m.round,
 		DeadlineMs:       toMs(m.deadline),
-		SecretsReady: map[string]bool{
-			"p1": m.p1.secretSet,
-			"p2": m.p2.secretSet,
-		},
-		GuessesReady: map[string]bool{
-			"p1": m.p1.guessSet || m.p1.missed,
-			"p2": m.p2.guessSet || m.p2.missed,
-		},
-		History: m.history,
-		Winner:  m.winner,
-	}
-
-	// Reveal secrets only after the game is finished.
-	// Clients can show them in UI without exposing secrets in URLs/logs.
-	if m.phase == "finished" {
-		st.RevealedSecrets = map[string]string{
-			"p1": m.p1.secret,
-			"p2": m.p2.secret,
+		SecretsReady:     secretsReady,
+		GuessesReady:     guessesReady,
+		History:          m.history,
+		Winner:           m.winner,
+
+		MaxPlayers: len(m.players),
+		Target:     m.rules.target(),
+		Rankings:   m.rankings,
+
+		Mode: m.rules.mode(),
+		Turn: string(m.turn),
+
+		FinishReason: m.finishReason,
+		MaxRounds:    m.rules.MaxRounds,
+		Tiebreak:     m.rules.tiebreak(),
+
+		Ranked:         !m.rules.Unranked,
+		Correspondence: m.rules.Correspondence,
+
+		BestOf:         m.rules.BestOf,
+		Series:         m.series,
+		SeriesFinished: m.seriesFinished,
+	}
+	if p := m.playerLocked(slot); p != nil {
+		st.Muted = m.mutedSlotsLocked(p)
+	}
+
+	if m.rules.Teams {
+		st.Team = m.teamOfLocked(slot).id
+		st.Teams = m.teamStatesLocked(slot)
+	} else if len(m.players) > 2 {
+		st.SolvedRounds = make(map[string]int)
+		for _, p := range m.players {
+			if p.solvedRound > 0 {
+				st.SolvedRounds[string(p.slot)] = p.solvedRound
+			}
 		}
 	}
 
+	// Commitments are public from the moment a secret is set;
+	// secrets (with nonces and salts to verify them) are revealed only after the game is finished.
+	st.Commitments = m.commitmentsLocked()
+	if m.phase == "finished" {
+		m.revealLocked(&st)
+	}
+
 	return st
 }
 
+func (m *Match) slotIndex(slot Slot) int {
+	for i, p := range m.players {
+		if p.slot == slot {
+			return i
+		}
+	}
+	return -1
+}
+
 func (m *Match) playerLocked(slot Slot) *Player {
-	if slot == P1 {
-		return m.p1
+	i := m.slotIndex(slot)
+	if i < 0 {
+		return nil
 	}
-	return m.p2
+	return m.players[i]
 }
 
 func (m *Match) sendLocked(conn *ClientConn, env Envelope) {
@@ -549,11 +947,10 @@ func (m *Match) sendLocked(conn *ClientConn, env Envelope) {
 }
 
 func (m *Match) broadcastLocked(env Envelope) {
-	if m.p1.conn != nil {
-		m.sendLocked(m.p1.conn, env)
-	}
-	if m.p2.conn != nil {
-		m.sendLocked(m.p2.conn, env)
+	for _, p := range m.players {
+		if p.conn != nil {
+			m.sendLocked(p.conn, env)
+		}
 	}
 }
 
@@ -2,8 +2,12 @@ package game
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
+
+	"example.com/bc-mvp/internal/notify"
 )
 
 // MatchService отвечает за:
@@ -15,6 +19,9 @@ type MatchService struct {
 
 	cfg     Config
 	persist MatchPersistence
+	results ResultRecorder // optional: nil => итоги никуда не пишем
+	notify  Notifier       // optional: nil => уведомления вне матча не шлём
+	lobby   *Lobby         // optional: nil => публичных матчей нет
 }
 
 func NewMatchService(cfg Config, persist MatchPersistence) *MatchService {
@@ -25,17 +32,72 @@ func NewMatchService(cfg Config, persist MatchPersistence) *MatchService {
 	}
 }
 
+// SetResultRecorder подключает запись итогов партий/серий (Postgres).
+func (s *MatchService) SetResultRecorder(r ResultRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = r
+}
+
 func (s *MatchService) Create(ctx context.Context, matchID string) (*Match, error) {
-	m := NewMatch(matchID, s.cfg.RoundDuration)
+	return s.CreateWithRules(ctx, matchID, s.cfg.Rules)
+}
 
-	// hook: любое изменение матча будет сохранять snapshot
-	m.onPersist = func(snap MatchSnapshot) {
-		_ = s.persist.Save(ctx, matchID, snap) // MVP: без логирования
+func (s *MatchService) CreateWithRules(ctx context.Context, matchID string, rules Rules) (*Match, error) {
+	return s.CreateWithOptions(ctx, matchID, rules, CreateOptions{})
+}
+
+// CreateOptions — необязательные параметры нового матча.
+type CreateOptions struct {
+	// CreatedBy — userId создателя: ему приходит opponent_joined в /ws/user ("" — аноним).
+	CreatedBy string
+	// Reserved — приватный матч: Attach пускает только эти userId (пусто — открытый матч).
+	Reserved []string
+	// Public — показать матч в лобби (GET /api/lobby), пока он ждёт игроков.
+	Public bool
+	// CreatorName — имя создателя для лобби.
+	CreatorName string
+	// JoinTimeout — дуэль с Reserved: кто не занял слот за это время, проигрывает серию (noshow.go).
+	// По переписке ждём не меньше времени на ход. 0 — ждём сколько угодно.
+	JoinTimeout time.Duration
+}
+
+func (s *MatchService) CreateWithOptions(ctx context.Context, matchID string, rules Rules, opts CreateOptions) (*Match, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	if len(opts.Reserved) > rules.players() {
+		return nil, errors.New("more reserved players than slots")
+	}
+	if opts.Public && len(opts.Reserved) > 0 {
+		return nil, errors.New("reserved match cannot be public")
+	}
+	if opts.JoinTimeout > 0 && (len(opts.Reserved) != 2 || rules.players() != 2) {
+		return nil, errors.New("join timeout needs a duel with both players reserved")
+	}
+
+	m := NewMatchWithRules(matchID, s.cfg.RoundDuration, rules)
+	m.createdBy = opts.CreatedBy
+	if len(opts.Reserved) > 0 {
+		m.reserved = append([]string(nil), opts.Reserved...)
+	}
+	s.mu.Lock()
+	lobby := s.lobby
+	s.mu.Unlock()
+	m.listed = opts.Public && lobby != nil
+	if m.listed {
+		m.creatorName = opts.CreatorName
+		m.listedAt = time.Now()
 	}
+	if opts.JoinTimeout > 0 {
+		m.joinBy = time.Now().Add(max(opts.JoinTimeout, rules.roundDuration(0)))
+	}
+	s.wire(ctx, m)
 
 	// первичное сохранение
 	m.mu.Lock()
 	snap := m.snapshotLocked()
+	m.armJoinTimerLocked()
 	m.mu.Unlock()
 	_ = s.persist.Save(ctx, matchID, snap)
 
@@ -43,9 +105,35 @@ func (s *MatchService) Create(ctx context.Context, matchID string) (*Match, erro
 	s.in[matchID] = m
 	s.mu.Unlock()
 
+	if m.listed {
+		m.mu.Lock()
+		entry := m.lobbyEntryLocked()
+		m.mu.Unlock()
+		lobby.add(entry)
+	}
+
 	return m, nil
 }
 
+// Rules — правила по умолчанию для новых матчей.
+func (s *MatchService) Rules() Rules {
+	return s.cfg.Rules
+}
+
+// SetLobby включает публичные матчи (CreateOptions.Public).
+func (s *MatchService) SetLobby(l *Lobby) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lobby = l
+}
+
+// SetNotifier подключает уведомления вне матча (/ws/user).
+func (s *MatchService) SetNotifier(n Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notify = n
+}
+
 func (s *MatchService) GetOrLoad(ctx context.Context, matchID string) (*Match, bool, error) {
 	s.mu.Lock()
 	m, ok := s.in[matchID]
@@ -64,32 +152,96 @@ func (s *MatchService) GetOrLoad(ctx context.Context, matchID string) (*Match, b
 	m.restoreLocked(snap)
 	m.mu.Unlock()
 
-	// hook снова навешиваем
-	m.onPersist = func(snap MatchSnapshot) {
-		_ = s.persist.Save(ctx, matchID, snap)
-	}
+	// hooks снова навешиваем
+	s.wire(ctx, m)
 
-	// если матч в playing и дедлайн ещё не прошёл — поднимаем таймер заново
+	// если матч в playing и у раунда есть дедлайн — поднимаем таймер заново
 	m.mu.Lock()
-	if s.cfg.RoundDuration > 0 && m.phase == "playing" && m.roundActive && !m.deadline.IsZero() && time.Now().Before(m.deadline) {
-		// новый token, чтобы старые таймеры (до рестарта) не влияли
-		m.roundToken++
-		token := m.roundToken
-
-		if m.roundTimer != nil {
-			m.roundTimer.Stop()
-		}
-
-		d := time.Until(m.deadline)
-		m.roundTimer = time.AfterFunc(d, func() {
-			m.onRoundTimeout(token)
-		})
+	m.rearmTimerLocked()
+	m.armJoinTimerLocked()
+	var entry *LobbyEntry
+	if m.listed && m.phase == "waiting_players" {
+		e := m.lobbyEntryLocked()
+		entry = &e
 	}
 	m.mu.Unlock()
 
 	s.mu.Lock()
 	s.in[matchID] = m
+	lobby := s.lobby
 	s.mu.Unlock()
 
+	// публичный матч всё ещё ждёт игроков — возвращаем в лобби (TTL считается от исходного listedAt)
+	if entry != nil && lobby != nil {
+		lobby.add(*entry)
+	}
+
 	return m, true, nil
 }
+
+// ResumeDeadlines поднимает из persistence матчи по переписке с идущим таймером
+// и матчи, ждущие неявившихся игроков: GetOrLoad заводит таймер заново, просроченный срабатывает сразу.
+// Вызывать при старте, после Set*: иначе у поднятых матчей не будет hooks.
+// Возвращает число поднятых матчей.
+func (s *MatchService) ResumeDeadlines(ctx context.Context) (int, error) {
+	idx, ok := s.persist.(DeadlineIndex)
+	if !ok {
+		return 0, nil
+	}
+	ids, err := idx.PendingDeadlines(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	var errs []error
+	for _, id := range ids {
+		_, found, err := s.GetOrLoad(ctx, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resume %s: %w", id, err))
+			continue
+		}
+		if found {
+			n++
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// wire навешивает hooks матча: snapshot в persistence, итоги в ResultRecorder, уведомления в Notifier
+// и снятие с лобби.
+//
+// Матч живёт дольше HTTP-запроса, который его создал/загрузил,
+// поэтому отвязываемся от отмены ctx запроса.
+func (s *MatchService) wire(ctx context.Context, m *Match) {
+	ctx = context.WithoutCancel(ctx)
+	matchID := m.id
+
+	// hook: любое изменение матча будет сохранять snapshot
+	m.onPersist = func(snap MatchSnapshot) {
+		_ = s.persist.Save(ctx, matchID, snap) // MVP: без логирования
+	}
+
+	s.mu.Lock()
+	results, notifier, lobby := s.results, s.notify, s.lobby
+	s.mu.Unlock()
+	if lobby != nil {
+		m.onUnlist = func() {
+			lobby.remove(matchID)
+		}
+	}
+	if notifier != nil {
+		m.onNotify = func(userID string, ev notify.Event) {
+			notifier.Publish(userID, ev)
+		}
+	}
+	if results == nil {
+		return
+	}
+	m.onGameFinished = func(r GameResult) {
+		_ = results.RecordGame(ctx, r)
+	}
+	m.onSeriesFinished = func(r SeriesResult) {
+		_ = results.RecordSeries(ctx, r)
+	}
+}
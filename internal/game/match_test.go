@@ -2,6 +2,8 @@ package game
 
 import (
 	"encoding/json"
+	"example.com/bc-mvp/internal/notify"
+	"example.com/bc-mvp/internal/presence"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -313,6 +315,148 @@ func TestMatch_Scenarios2(t *testing.T) {
 	}
 }
 
+func TestMatch_Reserved(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "only reserved players can take a slot",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				m.reserved = []string{"u1", "u2"}
+
+				_, code, _ := m.Attach("u3", "Mallory", newTestConn())
+				assert.Equal(t, "not_invited", code)
+
+				slot, code, _ := m.Attach("u2", "Bob", newTestConn())
+				require.Empty(t, code)
+				assert.Equal(t, P1, slot)
+				slot, code, _ = m.Attach("u1", "Alice", newTestConn())
+				require.Empty(t, code)
+				assert.Equal(t, P2, slot)
+
+				// reconnect резервацию не проверяет повторно
+				slot, code, _ = m.Attach("u2", "Bob", newTestConn())
+				require.Empty(t, code)
+				assert.Equal(t, P1, slot)
+			},
+		},
+		{
+			name: "reservation survives snapshot restore",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				m.reserved = []string{"u1", "u2"}
+				b, err := json.Marshal(m.snapshotLocked())
+				require.NoError(t, err)
+
+				var snap MatchSnapshot
+				require.NoError(t, json.Unmarshal(b, &snap))
+				m2 := NewMatch("m1", 0)
+				m2.restoreLocked(snap)
+
+				_, code, _ := m2.Attach("u3", "Mallory", newTestConn())
+				assert.Equal(t, "not_invited", code)
+				_, code, _ = m2.Attach("u1", "Alice", newTestConn())
+				assert.Empty(t, code)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
+
+func TestMatch_Notifications(t *testing.T) {
+	type sent struct {
+		to  string
+		typ string
+		by  string
+	}
+	capture := func(m *Match) *[]sent {
+		var out []sent
+		m.onNotify = func(userID string, ev notify.Event) {
+			out = append(out, sent{to: userID, typ: ev.Type, by: ev.Payload.(NotifyPlayer).UserID})
+		}
+		return &out
+	}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "creator away from the match hears about the opponent",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				m.createdBy = "u1"
+				got := capture(m)
+
+				m.Attach("u2", "Bob", newTestConn())
+				assert.Equal(t, []sent{{to: "u1", typ: "opponent_joined", by: "u2"}}, *got)
+			},
+		},
+		{
+			name: "creator connected to the match is not notified",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				m.createdBy = "u1"
+				got := capture(m)
+
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+				assert.Empty(t, *got)
+			},
+		},
+		{
+			name: "rematch request reaches only the player who left",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				got := capture(m)
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+				require.NoError(t, m.SetSecret(P1, "1111"))
+				require.NoError(t, m.SetSecret(P2, "2222"))
+				require.NoError(t, m.SubmitGuess(P1, "2222"))
+				require.NoError(t, m.SubmitGuess(P2, "0000"))
+
+				m.Detach(P2)
+				require.NoError(t, m.RequestRematch(P1))
+				assert.Equal(t, []sent{{to: "u2", typ: "rematch_requested", by: "u1"}}, *got)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
+
+func TestMatch_PresenceState(t *testing.T) {
+	m := NewMatch("m1", 0)
+	m.Attach("u1", "Alice", newTestConn())
+	assert.Equal(t, presence.StateInQueue, m.PresenceState())
+
+	m.Attach("u2", "Bob", newTestConn())
+	assert.Equal(t, presence.StateInMatch, m.PresenceState())
+
+	require.NoError(t, m.SetSecret(P1, "1111"))
+	require.NoError(t, m.SetSecret(P2, "2222"))
+	require.NoError(t, m.SubmitGuess(P1, "2222"))
+	require.NoError(t, m.SubmitGuess(P2, "0000"))
+	assert.Equal(t, presence.StateOnline, m.PresenceState())
+
+	// соперник отвалился посреди партии — это всё ещё матч, а не поиск соперника
+	m2 := NewMatch("m2", 0)
+	m2.Attach("u1", "Alice", newTestConn())
+	m2.Attach("u2", "Bob", newTestConn())
+	require.NoError(t, m2.SetSecret(P1, "1111"))
+	require.NoError(t, m2.SetSecret(P2, "2222"))
+	m2.Detach(P2)
+	assert.Equal(t, presence.StateInMatch, m2.PresenceState())
+}
+
 func TestMatch_State_PlayerNames_And_RevealedSecrets(t *testing.T) {
 	cases := []struct {
 		name string
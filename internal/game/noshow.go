@@ -0,0 +1,95 @@
+package game
+
+import (
+	"slices"
+	"time"
+)
+
+// Неявка (CreateOptions.JoinTimeout, турнирные матчи).
+//
+// Дуэль с заранее известными игроками (Reserved) ждёт их не вечно: если к дедлайну
+// кто-то так и не занял слот, он проигрывает серию (finishReason = no_show), а итог серии
+// уходит в ResultRecorder — турнир идёт дальше. Не пришёл никто — ничья.
+// Партий при этом не было, поэтому GameResult не пишем: статистика и рейтинг не меняются.
+//
+// Дедлайн живёт в snapshot-е (JoinByMs): GetOrLoad заводит таймер заново,
+// а ResumeDeadlines поднимает такие матчи после рестарта, даже если в них никто не зайдёт.
+// Когда все на месте, JoinByMs остаётся в snapshot-е: по нему RedisMatchStore понимает,
+// что матч был в индексе дедлайнов, и убирает его оттуда.
+
+// armJoinTimerLocked заводит таймер неявки по m.joinBy; прошедший дедлайн срабатывает сразу.
+func (m *Match) armJoinTimerLocked() {
+	if !m.awaitingJoinLocked() {
+		return
+	}
+	if m.joinTimer != nil {
+		m.joinTimer.Stop()
+	}
+	m.joinTimer = time.AfterFunc(max(time.Until(m.joinBy), 0), m.onJoinTimeout)
+}
+
+// awaitingJoinLocked — идёт дедлайн неявки: он есть, матч не закончен и не все заняли слоты.
+func (m *Match) awaitingJoinLocked() bool {
+	return !m.joinBy.IsZero() && m.phase != "finished" && !m.allJoinedLocked()
+}
+
+// stopJoinTimerLocked — все на месте, неявки не будет.
+func (m *Match) stopJoinTimerLocked() {
+	if m.joinTimer != nil {
+		m.joinTimer.Stop()
+		m.joinTimer = nil
+	}
+}
+
+func (m *Match) onJoinTimeout() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.awaitingJoinLocked() {
+		return
+	}
+	m.joinTimer = nil
+	m.forfeitNoShowLocked()
+}
+
+// forfeitNoShowLocked засчитывает неявившимся поражение в серии.
+// Их слоты занимаем их же userId, чтобы в итоге серии были оба игрока.
+func (m *Match) forfeitNoShowLocked() {
+	var absent []string
+	for _, id := range m.reserved {
+		if !slices.ContainsFunc(m.players, func(p *Player) bool { return p.id == id }) {
+			absent = append(absent, id)
+		}
+	}
+	var present []*Player
+	for _, p := range m.players {
+		if p.id != "" {
+			present = append(present, p)
+			continue
+		}
+		if len(absent) > 0 {
+			p.id, absent = absent[0], absent[1:]
+		}
+	}
+
+	m.phase = "finished"
+	m.finishReason = ReasonNoShow
+	m.winner = "draw"
+	if len(present) == 1 {
+		// техническая победа во всей серии
+		m.winner = string(present[0].slot)
+		switch present[0].slot {
+		case P1:
+			m.series.P1Wins = m.rules.winsToClinch()
+		case P2:
+			m.series.P2Wins = m.rules.winsToClinch()
+		}
+	}
+	m.seriesFinished = true
+
+	m.broadcastLocked(Envelope{Type: "game_finished", Payload: mustJSON(m.gameFinishedPayloadLocked())})
+	m.broadcastLocked(Envelope{Type: "series_finished", Payload: mustJSON(m.seriesFinishedPayloadLocked())})
+	m.recordSeriesLocked()
+	m.broadcastStateLocked()
+	m.persistLocked()
+}
@@ -0,0 +1,127 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch_NoShow(t *testing.T) {
+	ctx := context.Background()
+	newService := func(persist MatchPersistence) (*MatchService, *memRecorder) {
+		svc := NewMatchService(Config{}, persist)
+		rec := &memRecorder{}
+		svc.SetResultRecorder(rec)
+		return svc, rec
+	}
+	seriesOf := func(rec *memRecorder) []SeriesResult {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		return append([]SeriesResult(nil), rec.series...)
+	}
+	duel := CreateOptions{Reserved: []string{"u1", "u2"}, JoinTimeout: 20 * time.Millisecond}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "absent player forfeits the series",
+			run: func(t *testing.T) {
+				svc, rec := newService(&memPersist{})
+				m, err := svc.CreateWithOptions(ctx, "m1", Rules{BestOf: 3}, duel)
+				require.NoError(t, err)
+				_, code, _ := m.Attach("u2", "Bob", newTestConn())
+				require.Empty(t, code)
+
+				require.Eventually(t, func() bool { return len(seriesOf(rec)) == 1 }, time.Second, 5*time.Millisecond)
+				res := seriesOf(rec)[0]
+				assert.Equal(t, "p1", res.Winner, "the one who showed up took the first slot")
+				assert.Equal(t, "u2", res.P1ID)
+				assert.Equal(t, "u1", res.P2ID)
+				assert.Equal(t, SeriesScore{P1Wins: 2}, res.Score)
+
+				rec.mu.Lock()
+				assert.Empty(t, rec.games, "no game was played")
+				rec.mu.Unlock()
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, "finished", m.phase)
+				assert.Equal(t, ReasonNoShow, m.finishReason)
+			},
+		},
+		{
+			name: "nobody showed up: draw",
+			run: func(t *testing.T) {
+				svc, rec := newService(&memPersist{})
+				_, err := svc.CreateWithOptions(ctx, "m1", Rules{}, duel)
+				require.NoError(t, err)
+
+				require.Eventually(t, func() bool { return len(seriesOf(rec)) == 1 }, time.Second, 5*time.Millisecond)
+				res := seriesOf(rec)[0]
+				assert.Equal(t, "draw", res.Winner)
+				assert.ElementsMatch(t, []string{"u1", "u2"}, []string{res.P1ID, res.P2ID})
+			},
+		},
+		{
+			name: "both joined: no forfeit even if secrets are late",
+			run: func(t *testing.T) {
+				svc, rec := newService(&memPersist{})
+				m, err := svc.CreateWithOptions(ctx, "m1", Rules{}, duel)
+				require.NoError(t, err)
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+
+				time.Sleep(50 * time.Millisecond)
+				assert.Empty(t, seriesOf(rec))
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, "waiting_secrets", m.phase)
+				assert.Nil(t, m.joinTimer)
+				assert.Zero(t, m.snapshotLocked().pendingDeadlineMs(), "the match leaves the deadline index")
+			},
+		},
+		{
+			name: "deadline that passed during a restart fires on resume",
+			run: func(t *testing.T) {
+				persist := &deadlinePersist{}
+				svc, _ := newService(persist)
+				m, err := svc.CreateWithOptions(ctx, "m1", Rules{}, CreateOptions{Reserved: []string{"u1", "u2"}, JoinTimeout: time.Hour})
+				require.NoError(t, err)
+				m.Attach("u1", "Alice", newTestConn())
+				m.mu.Lock()
+				m.joinTimer.Stop()
+				snap := m.snapshotLocked()
+				m.mu.Unlock()
+
+				// сервер лежал, пока соперник так и не пришёл
+				snap.JoinByMs = time.Now().Add(-time.Minute).UnixMilli()
+				persist.m["m1"] = snap
+
+				restarted, rec := newService(persist)
+				n, err := restarted.ResumeDeadlines(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, 1, n)
+				require.Eventually(t, func() bool { return len(seriesOf(rec)) == 1 }, time.Second, 5*time.Millisecond)
+				assert.Equal(t, "p1", seriesOf(rec)[0].Winner)
+			},
+		},
+		{
+			name: "join timeout needs a reserved duel",
+			run: func(t *testing.T) {
+				svc, _ := newService(&memPersist{})
+				_, err := svc.CreateWithOptions(ctx, "m1", Rules{}, CreateOptions{JoinTimeout: time.Minute})
+				assert.Error(t, err)
+				_, err = svc.CreateWithOptions(ctx, "m2", Rules{Players: 3}, CreateOptions{Reserved: []string{"u1", "u2"}, JoinTimeout: time.Minute})
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -0,0 +1,95 @@
+package game
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"example.com/bc-mvp/internal/mail"
+	"example.com/bc-mvp/internal/notify"
+)
+
+// Notifier — доставка уведомлений вне матча (/ws/user). Реализует notify.Hub.
+type Notifier interface {
+	Publish(userID string, ev notify.Event) int
+}
+
+// MoveMailer кладёт письмо на подтверждённый адрес пользователя (store.OutboxStore).
+type MoveMailer interface {
+	EnqueueForUser(ctx context.Context, userID string, build func(to string) mail.Message) (bool, error)
+}
+
+// moveMailTimeout — сколько ждём outbox. Publish зовут под блокировкой матча, поэтому письмо кладём в фоне.
+const moveMailTimeout = 10 * time.Second
+
+// DurableNotifier — Notifier для игры по переписке. Hub живёт в памяти инстанса, поэтому
+// your_move, который никто не получил вживую (игрок офлайн или подключён к другому инстансу),
+// уходит письмом через outbox. Остальные события доставляет только Live.
+type DurableNotifier struct {
+	Live    Notifier
+	Mail    MoveMailer
+	BaseURL string       // origin фронтенда: ссылка на матч в письме ({BaseURL}/?match={matchId})
+	Log     *slog.Logger // nil => slog.Default()
+}
+
+func (n *DurableNotifier) Publish(userID string, ev notify.Event) int {
+	got := n.Live.Publish(userID, ev)
+	move, ok := ev.Payload.(YourMovePayload)
+	if got > 0 || ev.Type != "your_move" || !ok || n.Mail == nil {
+		return got
+	}
+
+	link := strings.TrimRight(n.BaseURL, "/") + "/?match=" + url.QueryEscape(move.MatchID)
+	var deadline time.Time
+	if move.DeadlineMs > 0 {
+		deadline = time.UnixMilli(move.DeadlineMs)
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), moveMailTimeout)
+		defer cancel()
+		_, err := n.Mail.EnqueueForUser(ctx, userID, func(to string) mail.Message {
+			return mail.YourMove(to, link, move.Round, deadline)
+		})
+		if err != nil {
+			// письмо потеряно: игрок узнает о ходе, только когда сам откроет матч
+			n.logger().Error("your_move email", "user", userID, "match", move.MatchID, "err", err)
+		}
+	}()
+	return got
+}
+
+func (n *DurableNotifier) logger() *slog.Logger {
+	if n.Log != nil {
+		return n.Log
+	}
+	return slog.Default()
+}
+
+// NotifyPlayer — игрок в уведомлении: кто вошёл в матч или попросил рематч.
+type NotifyPlayer struct {
+	MatchID     string `json:"matchId"`
+	Slot        string `json:"slot"`
+	UserID      string `json:"userId"`
+	DisplayName string `json:"displayName"`
+}
+
+// notifyAwayLocked шлёт уведомление о действии игрока from пользователю userID,
+// если тот сейчас не подключён к этому матчу (подключённые и так получают state).
+func (m *Match) notifyAwayLocked(userID, typ string, from *Player) {
+	if m.onNotify == nil || userID == "" {
+		return
+	}
+	for _, p := range m.players {
+		if p.id == userID && p.connected {
+			return
+		}
+	}
+	m.onNotify(userID, notify.Event{Type: typ, Payload: NotifyPlayer{
+		MatchID:     m.id,
+		Slot:        string(from.slot),
+		UserID:      from.id,
+		DisplayName: from.name,
+	}})
+}
@@ -119,3 +119,29 @@ func TestRedisPersistence_RestoreActiveRound_TimerOff(t *testing.T) {
 	require.Equal(t, 1, m2.round)
 	require.True(t, m2.roundActive)
 }
+
+func TestRedisPersistence_JoinDeadlineLeavesIndex(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRedisClient(t)
+	require.NoError(t, rdb.FlushDB(ctx).Err())
+
+	persist := NewRedisMatchStore(rdb, time.Hour)
+	svc := NewMatchService(Config{}, persist)
+
+	m, err := svc.CreateWithOptions(ctx, "m_duel", Rules{}, CreateOptions{Reserved: []string{"u1", "u2"}, JoinTimeout: time.Hour})
+	require.NoError(t, err)
+
+	ids, err := persist.PendingDeadlines(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"m_duel"}, ids, "a duel waiting for its players is indexed")
+
+	// пришли оба: дедлайна неявки больше нет, обычный матч в индексе не нужен
+	_, code, _ := m.Attach("u1", "Alice", newTestConn())
+	require.Empty(t, code)
+	_, code, _ = m.Attach("u2", "Bob", newTestConn())
+	require.Empty(t, code)
+
+	ids, err = persist.PendingDeadlines(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+}
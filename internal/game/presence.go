@@ -0,0 +1,78 @@
+package game
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"example.com/bc-mvp/internal/presence"
+)
+
+// PresenceTracker — учёт открытых соединений для статуса игрока. Реализует presence.Tracker (Redis).
+type PresenceTracker interface {
+	Set(ctx context.Context, userID, connID, state string, activeAt time.Time) error
+	Remove(ctx context.Context, userID, connID string) error
+}
+
+const presenceTimeout = 2 * time.Second
+
+// presenceConn — сессия одного WS-соединения. Методы безопасны на nil (presence выключен).
+type presenceConn struct {
+	t      PresenceTracker
+	userID string
+	connID string
+	active atomic.Int64 // unix millis последнего сообщения от клиента
+	closed atomic.Bool  // после remove heartbeat writer-а не должен воскресить сессию
+}
+
+func (s *Server) newPresenceConn(userID string) *presenceConn {
+	if s.presence == nil || userID == "" {
+		return nil
+	}
+	c := &presenceConn{t: s.presence, userID: userID, connID: randID(16)}
+	c.touch()
+	return c
+}
+
+// touch отмечает активность клиента (любое входящее сообщение).
+func (c *presenceConn) touch() {
+	if c == nil {
+		return
+	}
+	c.active.Store(time.Now().UnixMilli())
+}
+
+// update создаёт или продлевает сессию; вызывается при подключении и на каждый heartbeat.
+func (c *presenceConn) update(state string) {
+	if c == nil || c.closed.Load() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+	defer cancel()
+	_ = c.t.Set(ctx, c.userID, c.connID, state, time.UnixMilli(c.active.Load())) // best effort
+}
+
+func (c *presenceConn) remove() {
+	if c == nil || c.closed.Swap(true) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+	defer cancel()
+	_ = c.t.Remove(ctx, c.userID, c.connID)
+}
+
+// PresenceState — состояние игрока этого матча для presence:
+// ждёт соперников до первого раунда, играет после, просто онлайн — когда матч закончен.
+func (m *Match) PresenceState() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case m.phase == "finished":
+		return presence.StateOnline
+	case m.phase == "waiting_players" && m.round == 0 && m.gamesPlayed == 0:
+		return presence.StateInQueue
+	default:
+		return presence.StateInMatch
+	}
+}
@@ -0,0 +1,82 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SeriesScore — счёт серии в рамках matchId.
+type SeriesScore struct {
+	P1Wins int `json:"p1Wins"`
+	P2Wins int `json:"p2Wins"`
+	Draws  int `json:"draws"`
+}
+
+func (s SeriesScore) played() int {
+	return s.P1Wins + s.P2Wins + s.Draws
+}
+
+// PlayerResult — место одного игрока в партии.
+type PlayerResult struct {
+	Slot        string
+	Team        string // командный режим: t1|t2
+	UserID      string
+	Rank        int // 1 — лучший; ничья => несколько первых мест
+	SolvedRound int // 0 — не отгадал
+
+	MissedRounds int           // раунды (в alternating — ходы), пропущенные по таймеру
+	TimeLeft     time.Duration // сколько оставалось на таймере при отгадке; 0 — без таймера или не отгадал
+}
+
+// GameResult — итог одной партии (для статистики в Postgres).
+type GameResult struct {
+	MatchID    string
+	GameNo     int // номер партии внутри матча, с 1
+	P1ID       string
+	P2ID       string
+	Players    []PlayerResult // все слоты по порядку (для дуэли — p1, p2)
+	Winner     string         // p1..p8|t1|t2|draw
+	Reason     string         // solved|max_rounds
+	Ranked     bool
+	Rounds     int
+	FinishedAt time.Time
+}
+
+// SeriesResult — итог серии best-of-N.
+type SeriesResult struct {
+	MatchID    string
+	BestOf     int
+	P1ID       string
+	P2ID       string
+	Score      SeriesScore
+	Winner     string // p1|p2|draw
+	FinishedAt time.Time
+}
+
+// ResultRecorder — контракт записи итогов партий и серий.
+// Реализуем Postgres-ом (store.ResultStore).
+type ResultRecorder interface {
+	RecordGame(ctx context.Context, r GameResult) error
+	RecordSeries(ctx context.Context, r SeriesResult) error
+}
+
+// ResultRecorders раздаёт итоги нескольким получателям (статистика, турниры).
+// Ошибка одного не мешает остальным; ошибки возвращаются вместе.
+type ResultRecorders []ResultRecorder
+
+func (rs ResultRecorders) RecordGame(ctx context.Context, r GameResult) error {
+	var errs []error
+	for _, rec := range rs {
+		errs = append(errs, rec.RecordGame(ctx, r))
+	}
+	return errors.Join(errs...)
+}
+
+func (rs ResultRecorders) RecordSeries(ctx context.Context, r SeriesResult) error {
+	var errs []error
+	for _, rec := range rs {
+		errs = append(errs, rec.RecordSeries(ctx, r))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,202 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxBestOf — верхняя граница длины серии, чтобы матч не жил вечно.
+const MaxBestOf = 15
+
+// MaxRoundsLimit — верхняя граница для Rules.MaxRounds.
+const MaxRoundsLimit = 100
+
+// Tiebreak — как решаем партию, если за MaxRounds никто не отгадал.
+const (
+	TiebreakLastRoundBulls = "last_round_bulls" // больше быков в последнем раунде
+	TiebreakTotalScore     = "total_score"      // больше быков+коров за всю партию
+	TiebreakDraw           = "draw"             // просто ничья
+)
+
+// Режим хода.
+const (
+	ModeSimultaneous = "simultaneous" // оба угадывают одновременно (по умолчанию)
+	ModeAlternating  = "alternating"  // ходят по очереди: p1, затем p2
+)
+
+// Число игроков в матче: классическая дуэль или free-for-all.
+const (
+	MinPlayers = 2
+	MaxPlayers = 8
+)
+
+// Командный режим: две команды по два игрока (p1+p2 против p3+p4).
+const (
+	TeamCount = 2
+	TeamSize  = 2
+)
+
+// Что угадывают игроки.
+const (
+	TargetNext   = "next"   // секрет следующего по кругу игрока (в дуэли — соперника); по умолчанию
+	TargetShared = "shared" // общий секрет, загаданный сервером
+)
+
+// Игра по переписке: время на раунд (ход) в часах — от часа до двух недель.
+const (
+	DefaultMoveHours = 24
+	MaxMoveHours     = 14 * 24
+)
+
+// Причина завершения партии (game_finished.reason, статистика).
+const (
+	ReasonSolved    = "solved"
+	ReasonMaxRounds = "max_rounds"
+	ReasonNoShow    = "no_show" // соперник не пришёл к дедлайну неявки (noshow.go)
+)
+
+// Rules — настраиваемые правила матча.
+// Задаются при создании матча и сохраняются в snapshot вместе с состоянием.
+type Rules struct {
+	// BestOf — длина серии (best-of-N). 0 => серия бесконечная (рематчи без ограничений).
+	// Только нечётная: при чётной серия может закончиться вничью (1-1).
+	BestOf int `json:"bestOf,omitempty"`
+
+	// MaxRounds — лимит раундов в партии. 0 => без лимита.
+	MaxRounds int `json:"maxRounds,omitempty"`
+	// Tiebreak — правило при исчерпании MaxRounds ("" => draw).
+	Tiebreak string `json:"tiebreak,omitempty"`
+
+	// Mode — simultaneous|alternating ("" => simultaneous).
+	Mode string `json:"mode,omitempty"`
+
+	// Players — число игроков 2..8 (0 => 2, классическая дуэль).
+	Players int `json:"players,omitempty"`
+	// Target — next|shared ("" => next).
+	Target string `json:"target,omitempty"`
+
+	// Teams — командный режим 2v2: секрет на команду, одна догадка команды за раунд.
+	Teams bool `json:"teams,omitempty"`
+
+	// Unranked — товарищеский матч: итоги пишутся в историю и статистику игрока,
+	// но с ranked=false. Гостевые аккаунты могут играть только такие матчи.
+	Unranked bool `json:"unranked,omitempty"`
+
+	// Correspondence — игра по переписке (correspondence.go): на раунд (в alternating — на ход)
+	// даются часы или дни, онлайн одновременно быть не нужно.
+	Correspondence bool `json:"correspondence,omitempty"`
+	// MoveHours — время на раунд (ход) по переписке, в часах (0 => DefaultMoveHours).
+	MoveHours int `json:"moveHours,omitempty"`
+}
+
+func (r Rules) Validate() error {
+	if r.BestOf < 0 || r.BestOf > MaxBestOf {
+		return fmt.Errorf("bestOf must be between 0 and %d", MaxBestOf)
+	}
+	if r.BestOf%2 == 0 && r.BestOf != 0 {
+		return fmt.Errorf("bestOf must be odd, got %d", r.BestOf)
+	}
+	if r.MaxRounds < 0 || r.MaxRounds > MaxRoundsLimit {
+		return fmt.Errorf("maxRounds must be between 0 and %d", MaxRoundsLimit)
+	}
+	switch r.Tiebreak {
+	case "", TiebreakLastRoundBulls, TiebreakTotalScore, TiebreakDraw:
+	default:
+		return fmt.Errorf("unknown tiebreak %q (want %s|%s|%s)",
+			r.Tiebreak, TiebreakLastRoundBulls, TiebreakTotalScore, TiebreakDraw)
+	}
+	switch r.Mode {
+	case "", ModeSimultaneous, ModeAlternating:
+	default:
+		return fmt.Errorf("unknown mode %q (want %s|%s)", r.Mode, ModeSimultaneous, ModeAlternating)
+	}
+	if r.Players != 0 && (r.Players < MinPlayers || r.Players > MaxPlayers) {
+		return fmt.Errorf("players must be between %d and %d", MinPlayers, MaxPlayers)
+	}
+	switch r.Target {
+	case "", TargetNext, TargetShared:
+	default:
+		return fmt.Errorf("unknown target %q (want %s|%s)", r.Target, TargetNext, TargetShared)
+	}
+	if r.Teams {
+		if r.players() != TeamCount*TeamSize {
+			return fmt.Errorf("teams mode needs exactly %d players", TeamCount*TeamSize)
+		}
+		if r.alternating() || r.sharedTarget() {
+			return fmt.Errorf("teams mode supports only %s mode with %s target", ModeSimultaneous, TargetNext)
+		}
+	}
+	if r.MoveHours != 0 {
+		if !r.Correspondence {
+			return fmt.Errorf("moveHours is supported only for correspondence matches")
+		}
+		if r.MoveHours < 1 || r.MoveHours > MaxMoveHours {
+			return fmt.Errorf("moveHours must be between 1 and %d", MaxMoveHours)
+		}
+	}
+	// счёт серии ведём только для дуэли
+	if r.BestOf > 0 && r.players() > 2 {
+		return fmt.Errorf("bestOf is supported only for two-player matches")
+	}
+	return nil
+}
+
+func (r Rules) players() int {
+	if r.Players == 0 {
+		if r.Teams {
+			return TeamCount * TeamSize
+		}
+		return MinPlayers
+	}
+	return r.Players
+}
+
+func (r Rules) target() string {
+	if r.Target == "" {
+		return TargetNext
+	}
+	return r.Target
+}
+
+func (r Rules) sharedTarget() bool {
+	return r.Target == TargetShared
+}
+
+func (r Rules) mode() string {
+	if r.Mode == "" {
+		return ModeSimultaneous
+	}
+	return r.Mode
+}
+
+func (r Rules) alternating() bool {
+	return r.Mode == ModeAlternating
+}
+
+// tiebreak — действующее правило tiebreak (только если задан MaxRounds).
+func (r Rules) tiebreak() string {
+	if r.MaxRounds <= 0 {
+		return ""
+	}
+	if r.Tiebreak == "" {
+		return TiebreakDraw
+	}
+	return r.Tiebreak
+}
+
+// roundDuration — время на раунд (ход): по переписке — из MoveHours, иначе def (ROUND_DURATION).
+func (r Rules) roundDuration(def time.Duration) time.Duration {
+	if !r.Correspondence {
+		return def
+	}
+	h := r.MoveHours
+	if h == 0 {
+		h = DefaultMoveHours
+	}
+	return time.Duration(h) * time.Hour
+}
+
+// winsToClinch — сколько побед нужно, чтобы досрочно выиграть серию.
+func (r Rules) winsToClinch() int {
+	return r.BestOf/2 + 1
+}
@@ -0,0 +1,113 @@
+package game
+
+import "time"
+
+// SeriesFinishedPayload — событие series_finished (best-of-N серия сыграна).
+type SeriesFinishedPayload struct {
+	BestOf int         `json:"bestOf"`
+	Series SeriesScore `json:"series"`
+	Winner string      `json:"winner"` // p1|p2|draw
+}
+
+// seriesClinchedLocked — серия закончена, если кто-то набрал нужное число побед
+// или сыграны все N партий. Для BestOf=0 серия бесконечная.
+func (m *Match) seriesClinchedLocked() bool {
+	if m.rules.BestOf <= 0 {
+		return false
+	}
+	need := m.rules.winsToClinch()
+	if m.series.P1Wins >= need || m.series.P2Wins >= need {
+		return true
+	}
+	return m.series.played() >= m.rules.BestOf
+}
+
+func (m *Match) seriesWinnerLocked() string {
+	switch {
+	case m.series.P1Wins > m.series.P2Wins:
+		return "p1"
+	case m.series.P2Wins > m.series.P1Wins:
+		return "p2"
+	default:
+		return "draw"
+	}
+}
+
+func (m *Match) seriesFinishedPayloadLocked() SeriesFinishedPayload {
+	return SeriesFinishedPayload{
+		BestOf: m.rules.BestOf,
+		Series: m.series,
+		Winner: m.seriesWinnerLocked(),
+	}
+}
+
+func (m *Match) recordGameLocked() {
+	if m.onGameFinished == nil {
+		return
+	}
+
+	ranks := make(map[string]Ranking, len(m.rankings))
+	for _, r := range m.rankings {
+		ranks[r.Slot] = r
+	}
+	players := make([]PlayerResult, len(m.players))
+	for i, p := range m.players {
+		r := ranks[string(p.slot)]
+		players[i] = PlayerResult{
+			Slot:        string(p.slot),
+			Team:        r.Team,
+			UserID:      p.id,
+			Rank:        r.Rank,
+			SolvedRound: r.SolvedRound,
+
+			MissedRounds: m.missedRoundsLocked(p.slot, r.Team),
+			TimeLeft:     p.solvedLeft,
+		}
+	}
+
+	m.onGameFinished(GameResult{
+		MatchID:    m.id,
+		GameNo:     m.gamesPlayed,
+		P1ID:       m.players[0].id,
+		P2ID:       m.players[1].id,
+		Players:    players,
+		Winner:     m.winner,
+		Reason:     m.finishReason,
+		Ranked:     !m.rules.Unranked,
+		Rounds:     m.round,
+		FinishedAt: time.Now(),
+	})
+}
+
+// missedRoundsLocked — сколько раз игрок (в командах — его команда) пропустил ход по таймеру в этой партии.
+func (m *Match) missedRoundsLocked(slot Slot, team string) int {
+	n := 0
+	for _, it := range m.history {
+		var a Attempt
+		var ok bool
+		if it.Teams != nil {
+			a, ok = it.Teams[team]
+		} else {
+			a, ok = it.attempts()[slot]
+		}
+		if ok && a.Missed {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *Match) recordSeriesLocked() {
+	if m.onSeriesFinished == nil {
+		return
+	}
+	m.onSeriesFinished(SeriesResult{
+		MatchID:    m.id,
+		BestOf:     m.rules.BestOf,
+		P1ID:       m.players[0].id,
+		P2ID:       m.players[1].id,
+		Score:      m.series,
+		Winner:     m.seriesWinnerLocked(),
+		FinishedAt: time.Now(),
+	})
+}
@@ -0,0 +1,195 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memRecorder struct {
+	mu     sync.Mutex
+	games  []GameResult
+	series []SeriesResult
+}
+
+func (r *memRecorder) RecordGame(ctx context.Context, res GameResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.games = append(r.games, res)
+	return nil
+}
+
+func (r *memRecorder) RecordSeries(ctx context.Context, res SeriesResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.series = append(r.series, res)
+	return nil
+}
+
+// playP1Win — одна партия, которую p1 выигрывает в первом раунде.
+func playP1Win(t *testing.T, m *Match) {
+	t.Helper()
+	require.NoError(t, m.SetSecret(P1, "1111"))
+	require.NoError(t, m.SetSecret(P2, "2222"))
+	require.NoError(t, m.SubmitGuess(P1, "2222"))
+	require.NoError(t, m.SubmitGuess(P2, "0000"))
+}
+
+func hasEnvelope(envs []Envelope, typ string) (Envelope, bool) {
+	for _, env := range envs {
+		if env.Type == typ {
+			return env, true
+		}
+	}
+	return Envelope{}, false
+}
+
+func TestMatch_BestOfSeries(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "best-of-3 finishes after two wins and blocks rematch",
+			run: func(t *testing.T) {
+				rec := &memRecorder{}
+				svc := NewMatchService(Config{}, &memPersist{})
+				svc.SetResultRecorder(rec)
+
+				m, err := svc.CreateWithRules(context.Background(), "m1", Rules{BestOf: 3})
+				require.NoError(t, err)
+
+				c1 := newTestConn()
+				m.Attach("u1", "Alice", c1)
+				m.Attach("u2", "Bob", newTestConn())
+
+				playP1Win(t, m)
+				_, ok := hasEnvelope(readEnvelopesNonBlocking(c1), "series_finished")
+				require.False(t, ok, "series must not finish after the first game")
+
+				require.NoError(t, m.RequestRematch(P1))
+				require.NoError(t, m.RequestRematch(P2))
+				playP1Win(t, m)
+
+				env, ok := hasEnvelope(readEnvelopesNonBlocking(c1), "series_finished")
+				require.True(t, ok)
+				var p SeriesFinishedPayload
+				require.NoError(t, json.Unmarshal(env.Payload, &p))
+				require.Equal(t, SeriesFinishedPayload{
+					BestOf: 3,
+					Series: SeriesScore{P1Wins: 2},
+					Winner: "p1",
+				}, p)
+
+				require.Error(t, m.RequestRematch(P1))
+
+				rec.mu.Lock()
+				defer rec.mu.Unlock()
+				require.Len(t, rec.games, 2)
+				require.Equal(t, 2, rec.games[1].GameNo)
+				require.Equal(t, "u1", rec.games[1].P1ID)
+				require.Len(t, rec.series, 1)
+				require.Equal(t, "p1", rec.series[0].Winner)
+			},
+		},
+		{
+			name: "game result counts missed rounds and the time left on the solving guess",
+			run: func(t *testing.T) {
+				rec := &memRecorder{}
+				m := NewMatchWithRules("m1", time.Minute, Rules{})
+				m.onGameFinished = func(r GameResult) { _ = rec.RecordGame(context.Background(), r) }
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+				require.NoError(t, m.SetSecret(P1, "1111"))
+				require.NoError(t, m.SetSecret(P2, "2222"))
+
+				// раунд 1: p2 не успевает
+				require.NoError(t, m.SubmitGuess(P1, "0000"))
+				m.mu.Lock()
+				token := m.roundToken
+				m.mu.Unlock()
+				m.onRoundTimeout(token)
+
+				require.NoError(t, m.SubmitGuess(P1, "2222"))
+				require.NoError(t, m.SubmitGuess(P2, "0000"))
+
+				rec.mu.Lock()
+				defer rec.mu.Unlock()
+				require.Len(t, rec.games, 1)
+				p1, p2 := rec.games[0].Players[0], rec.games[0].Players[1]
+				require.Equal(t, 0, p1.MissedRounds)
+				require.Equal(t, 1, p2.MissedRounds)
+				require.Greater(t, p1.TimeLeft, time.Duration(0))
+				require.LessOrEqual(t, p1.TimeLeft, time.Minute)
+				require.Zero(t, p2.TimeLeft, "p2 did not solve")
+			},
+		},
+		{
+			name: "unlimited series never finishes",
+			run: func(t *testing.T) {
+				m := NewMatch("m1", 0)
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+
+				for i := 0; i < 3; i++ {
+					playP1Win(t, m)
+					require.NoError(t, m.RequestRematch(P1))
+					require.NoError(t, m.RequestRematch(P2))
+				}
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				require.False(t, m.seriesFinished)
+				require.Equal(t, SeriesScore{P1Wins: 3}, m.series)
+			},
+		},
+		{
+			name: "series finished flag survives snapshot restore",
+			run: func(t *testing.T) {
+				m := NewMatchWithRules("m1", 0, Rules{BestOf: 1})
+				m.Attach("u1", "Alice", newTestConn())
+				m.Attach("u2", "Bob", newTestConn())
+				playP1Win(t, m)
+
+				m.mu.Lock()
+				snap := m.snapshotLocked()
+				m.mu.Unlock()
+
+				m2 := NewMatch("m1", 0)
+				m2.mu.Lock()
+				m2.restoreLocked(snap)
+				m2.mu.Unlock()
+
+				require.Error(t, m2.RequestRematch(P1))
+				require.Equal(t, 1, m2.rules.BestOf)
+			},
+		},
+		{
+			name: "invalid bestOf is rejected",
+			run: func(t *testing.T) {
+				svc := NewMatchService(Config{}, &memPersist{})
+				_, err := svc.CreateWithRules(context.Background(), "m1", Rules{BestOf: MaxBestOf + 1})
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "even bestOf is rejected: the series could end level",
+			run: func(t *testing.T) {
+				for _, n := range []int{2, 4, MaxBestOf - 1} {
+					require.Error(t, Rules{BestOf: n}.Validate(), "bestOf %d", n)
+				}
+				for _, n := range []int{0, 1, 3, MaxBestOf} {
+					require.NoError(t, Rules{BestOf: n}.Validate(), "bestOf %d", n)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -1,26 +1,53 @@
 package game
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
 	"net/http"
 	"time"
 
 	"example.com/bc-mvp/internal/auth"
+	"example.com/bc-mvp/internal/notify"
 )
 
 type Config struct {
 	RoundDuration time.Duration // 0 => таймер выключен
+	Rules         Rules         // правила по умолчанию для новых матчей
 }
 
 type Server struct {
-	cfg     Config
-	matches *MatchService
-	auth    TokenVerifier
+	cfg      Config
+	matches  *MatchService
+	auth     TokenVerifier
+	hub      *notify.Hub     // optional: nil => /ws/user недоступен
+	presence PresenceTracker // optional: nil => статус игроков не ведём
+	invites  InviteStore     // optional: nil => коды приглашений не выдаём
+	joinBase string          // origin для ссылок /J/{code}
+}
+
+// CreateMatchRequest — необязательное тело POST /api/match.
+type CreateMatchRequest struct {
+	BestOf    *int    `json:"bestOf,omitempty"`
+	MaxRounds *int    `json:"maxRounds,omitempty"`
+	Tiebreak  *string `json:"tiebreak,omitempty"`
+	Mode      *string `json:"mode,omitempty"`
+	Players   *int    `json:"players,omitempty"`
+	Target    *string `json:"target,omitempty"`
+	Teams     *bool   `json:"teams,omitempty"`
+	Ranked    *bool   `json:"ranked,omitempty"`
+	// Correspondence — игра по переписке: MoveHours часов на раунд (ход), онлайн одновременно не нужно
+	Correspondence *bool `json:"correspondence,omitempty"`
+	MoveHours      *int  `json:"moveHours,omitempty"`
+	// Public — показать матч в лобби; требует Bearer-токен (в лобби виден создатель)
+	Public *bool `json:"public,omitempty"`
 }
 
 type TokenVerifier interface {
-	Verify(token string) (*auth.Claims, error)
+	Verify(ctx context.Context, token string) (*auth.Claims, error)
 }
 
 func NewServer(cfg Config, matches *MatchService, verifier TokenVerifier) *Server {
@@ -31,6 +58,23 @@ func NewServer(cfg Config, matches *MatchService, verifier TokenVerifier) *Serve
 	}
 }
 
+// SetNotifications включает /ws/user — уведомления вне матча из hub.
+func (s *Server) SetNotifications(hub *notify.Hub) {
+	s.hub = hub
+}
+
+// SetPresence включает учёт соединений /ws/{matchId} и /ws/user для GET /api/users/{id}/presence.
+func (s *Server) SetPresence(t PresenceTracker) {
+	s.presence = t
+}
+
+// SetInvites включает короткие коды приглашений: POST /api/match возвращает inviteCode и joinUrl,
+// GET /api/invite/{code} и /J/{code} находят по ним матч. baseURL — origin для joinUrl.
+func (s *Server) SetInvites(store InviteStore, baseURL string) {
+	s.invites = store
+	s.joinBase = baseURL
+}
+
 // (опционально) если хочешь подменять storage в тестах/будущем:
 //func NewServerWithStore(cfg Config, matches *MatchService) *Server {
 //	return &Server{
@@ -44,6 +88,14 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 
 	// WebSocket: /ws/{matchId}
 	mux.HandleFunc("/ws/", s.handleWS)
+	// WebSocket: уведомления пользователя вне матча (matchId из randID не совпадёт с "user": длина 10)
+	mux.HandleFunc("/ws/user", s.handleUserWS)
+	if s.invites != nil {
+		mux.HandleFunc("/api/invite/{code}", s.handleInvite)
+		// ссылка из QR в верхнем регистре; строчную принимаем для набранных руками
+		mux.HandleFunc("/J/{code}", s.handleJoinLink)
+		mux.HandleFunc("/j/{code}", s.handleJoinLink)
+	}
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing matchId: use /ws/{matchId}", http.StatusBadRequest)
 	})
@@ -55,25 +107,113 @@ func (s *Server) handleCreateMatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matchID := randID(10)
+	// тело необязательное: {"bestOf":3,"maxRounds":10,"tiebreak":"total_score"};
+	// пустое тело => правила по умолчанию
+	rules := s.cfg.Rules
+	public := false
+	var ranked *bool
+	if r.ContentLength != 0 {
+		var req CreateMatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if req.BestOf != nil {
+			rules.BestOf = *req.BestOf
+		}
+		if req.MaxRounds != nil {
+			rules.MaxRounds = *req.MaxRounds
+		}
+		if req.Tiebreak != nil {
+			rules.Tiebreak = *req.Tiebreak
+		}
+		if req.Mode != nil {
+			rules.Mode = *req.Mode
+		}
+		if req.Players != nil {
+			rules.Players = *req.Players
+		}
+		if req.Target != nil {
+			rules.Target = *req.Target
+		}
+		if req.Ranked != nil {
+			rules.Unranked = !*req.Ranked
+			ranked = req.Ranked
+		}
+		if req.Correspondence != nil {
+			rules.Correspondence = *req.Correspondence
+		}
+		if req.MoveHours != nil {
+			rules.MoveHours = *req.MoveHours
+		}
+		if req.Teams != nil {
+			rules.Teams = *req.Teams
+			if rules.Teams && req.Players == nil {
+				rules.Players = 0 // состав задаёт командный режим (2v2)
+			}
+		}
+		if req.Public != nil {
+			public = *req.Public
+		}
+	}
+	if err := rules.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// создание матча доступно и без токена; с валидным токеном запоминаем создателя,
+	// чтобы прислать ему opponent_joined в /ws/user
+	opts := CreateOptions{Public: public}
+	if claims, err := s.authFromRequest(r); err == nil && claims != nil {
+		opts.CreatedBy = claims.UserID
+		opts.CreatorName = claims.DisplayName
+		// гость не войдёт в рейтинговый матч (ws.go), поэтому его матч по умолчанию товарищеский
+		if claims.Guest {
+			if ranked != nil && *ranked {
+				http.Error(w, "guest accounts can only create unranked matches", http.StatusForbidden)
+				return
+			}
+			rules.Unranked = true
+		}
+	}
+	if public && opts.CreatedBy == "" {
+		http.Error(w, "public match requires a bearer token", http.StatusUnauthorized)
+		return
+	}
 
-	_, err := s.matches.Create(r.Context(), matchID)
+	matchID := NewMatchID()
+
+	_, err := s.matches.CreateWithOptions(r.Context(), matchID, rules, opts)
 	if err != nil {
 		http.Error(w, "failed to create match", http.StatusInternalServerError)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{
+	resp := map[string]any{
 		"matchId": matchID,
-	})
+	}
+	if s.invites != nil {
+		// без кода матч всё равно доступен по matchId, поэтому сбой здесь не фатален
+		if inv, err := s.invites.Create(r.Context(), matchID); err == nil {
+			resp["inviteCode"] = inv.Code
+			resp["joinUrl"] = joinURL(s.joinBase, inv.Code)
+			resp["inviteExpiresAt"] = inv.ExpiresAt
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
+// NewMatchID — случайный matchId для /ws/{matchId}.
+func NewMatchID() string {
+	return randID(10)
+}
+
+// randID — случайная строка [a-z0-9]; символы равновероятны (randIntn, без modulo bias).
 func randID(n int) string {
 	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
 	b := make([]byte, n)
-	_, _ = rand.Read(b)
 	for i := range b {
-		b[i] = alphabet[int(b[i])%len(alphabet)]
+		b[i] = alphabet[randIntn(len(alphabet))]
 	}
 	return string(b)
 }
@@ -83,3 +223,22 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 	w.WriteHeader(code)
 	_ = json.NewEncoder(w).Encode(v)
 }
+
+// randSecret — случайный секрет из 4 цифр (target=shared).
+func randSecret() string {
+	const digits = "0123456789"
+	b := make([]byte, 4)
+	for i := range b {
+		b[i] = digits[randIntn(len(digits))]
+	}
+	return string(b)
+}
+
+// randIntn — равномерное случайное число [0, n) без modulo bias.
+func randIntn(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err) // crypto/rand не должен падать
+	}
+	return int(v.Int64())
+}
@@ -1,6 +1,9 @@
 package game
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // MatchSnapshot — сериализуемое состояние матча для Redis.
 type MatchSnapshot struct {
@@ -9,35 +12,123 @@ type MatchSnapshot struct {
 	Phase string `json:"phase"`
 	Round int    `json:"round"`
 
-	// важное: сохраняем ID игроков, иначе после рестарта невозможно корректно reconnect
-	P1ID   string `json:"p1Id"`
-	P1Name string `json:"p1Name,omitempty"`
-	P2ID   string `json:"p2Id"`
-	P2Name string `json:"p2Name,omitempty"`
+	// важное: сохраняем ID игроков, иначе после рестарта невозможно корректно reconnect.
+	// Players — по слоту на элемент (p1..pN).
+	Players []PlayerSnapshot `json:"players,omitempty"`
 
-	P1Secret    string `json:"p1Secret"`
-	P1SecretSet bool   `json:"p1SecretSet"`
-	P2Secret    string `json:"p2Secret"`
-	P2SecretSet bool   `json:"p2SecretSet"`
+	// приватный матч: userId приглашённых игроков
+	Reserved []string `json:"reserved,omitempty"`
+	// дедлайн неявки (noshow.go), unix millis; 0 — не ждём
+	JoinByMs int64 `json:"joinByMs,omitempty"`
+	// userId создателя (уведомление opponent_joined)
+	CreatedBy string `json:"createdBy,omitempty"`
 
-	P1Guess    string `json:"p1Guess"`
-	P1GuessSet bool   `json:"p1GuessSet"`
-	P2Guess    string `json:"p2Guess"`
-	P2GuessSet bool   `json:"p2GuessSet"`
+	// публичный матч ещё в лобби: после рестарта GetOrLoad возвращает его туда
+	Listed      bool   `json:"listed,omitempty"`
+	CreatorName string `json:"creatorName,omitempty"`
+	ListedAtMs  int64  `json:"listedAtMs,omitempty"`
 
-	// rematch flags
-	P1Rematch bool `json:"p1Rematch"`
-	P2Rematch bool `json:"p2Rematch"`
+	// target=shared: общий секрет сервера
+	SharedSecret string `json:"sharedSecret,omitempty"`
+	SharedNonce  string `json:"sharedNonce,omitempty"`
+
+	// Rules.Teams: секреты, догадки и предложения команд
+	Teams []TeamSnapshot `json:"teams,omitempty"`
+
+	// Sealed — зашифрованные секреты и текущие догадки (snapshot_crypto.go).
+	// Если есть, соответствующие поля выше пустые.
+	Sealed *SealedSecrets `json:"sealed,omitempty"`
+
+	// Legacy: формат до N игроков (только p1/p2). Больше не пишем,
+	// читаем только если Players пустой — чтобы поднимать старые snapshot-ы.
+	P1ID        string `json:"p1Id,omitempty"`
+	P1Name      string `json:"p1Name,omitempty"`
+	P2ID        string `json:"p2Id,omitempty"`
+	P2Name      string `json:"p2Name,omitempty"`
+	P1Secret    string `json:"p1Secret,omitempty"`
+	P1SecretSet bool   `json:"p1SecretSet,omitempty"`
+	P2Secret    string `json:"p2Secret,omitempty"`
+	P2SecretSet bool   `json:"p2SecretSet,omitempty"`
+	P1Guess     string `json:"p1Guess,omitempty"`
+	P1GuessSet  bool   `json:"p1GuessSet,omitempty"`
+	P2Guess     string `json:"p2Guess,omitempty"`
+	P2GuessSet  bool   `json:"p2GuessSet,omitempty"`
+	P1Rematch   bool   `json:"p1Rematch,omitempty"`
+	P2Rematch   bool   `json:"p2Rematch,omitempty"`
 
 	// счёт серии в рамках matchId
-	SeriesP1Wins int `json:"seriesP1Wins"`
-	SeriesP2Wins int `json:"seriesP2Wins"`
-	SeriesDraws  int `json:"seriesDraws"`
+	SeriesP1Wins   int  `json:"seriesP1Wins"`
+	SeriesP2Wins   int  `json:"seriesP2Wins"`
+	SeriesDraws    int  `json:"seriesDraws"`
+	SeriesFinished bool `json:"seriesFinished,omitempty"`
+	GamesPlayed    int  `json:"gamesPlayed,omitempty"`
+
+	// правила матча (старые snapshot-ы без поля => Rules{})
+	Rules Rules `json:"rules"`
+
+	DeadlineMs int64  `json:"deadlineMs"`     // unix millis, 0 если нет дедлайна
+	Turn       string `json:"turn,omitempty"` // alternating: чей ход
+
+	Winner       string             `json:"winner"`
+	FinishReason string             `json:"finishReason,omitempty"`
+	Rankings     []Ranking          `json:"rankings,omitempty"`
+	History      []RoundHistoryItem `json:"history"`
+
+	Chat []ChatMessage `json:"chat,omitempty"`
+}
+
+// pendingDeadlineMs — дедлайн, который должен сработать и без игроков онлайн (DeadlineIndex):
+// неявка (пока не все заняли слоты) или раунд по переписке; 0 — такого нет.
+func (s MatchSnapshot) pendingDeadlineMs() int64 {
+	switch {
+	case s.Phase == "finished":
+		return 0
+	case s.JoinByMs > 0 && slices.ContainsFunc(s.Players, func(p PlayerSnapshot) bool { return p.ID == "" }):
+		return s.JoinByMs
+	case s.Rules.Correspondence && s.Phase == "playing":
+		return s.DeadlineMs
+	}
+	return 0
+}
 
-	DeadlineMs int64 `json:"deadlineMs"` // unix millis, 0 если нет дедлайна
+// PlayerSnapshot — состояние одного слота.
+type PlayerSnapshot struct {
+	Slot string `json:"slot"`
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
 
-	Winner  string             `json:"winner"`
-	History []RoundHistoryItem `json:"history"`
+	Secret    string `json:"secret"`
+	SecretSet bool   `json:"secretSet"`
+	Nonce     string `json:"nonce,omitempty"`
+	Salt      string `json:"salt,omitempty"`
+
+	Guess       string `json:"guess"`
+	GuessSet    bool   `json:"guessSet"`
+	Missed      bool   `json:"missed,omitempty"`
+	GuessLeftMs int64  `json:"guessLeftMs,omitempty"`
+
+	Rematch      bool  `json:"rematch"`
+	SolvedRound  int   `json:"solvedRound,omitempty"`
+	SolvedLeftMs int64 `json:"solvedLeftMs,omitempty"`
+
+	Muted []string `json:"muted,omitempty"` // слоты, заглушённые в чате
+}
+
+// TeamSnapshot — состояние команды.
+type TeamSnapshot struct {
+	ID string `json:"id"`
+
+	Secret    string `json:"secret"`
+	SecretSet bool   `json:"secretSet"`
+	Nonce     string `json:"nonce,omitempty"`
+	Salt      string `json:"salt,omitempty"`
+
+	Guess     string     `json:"guess"`
+	GuessSet  bool       `json:"guessSet"`
+	Missed    bool       `json:"missed,omitempty"`
+	Proposals []Proposal `json:"proposals,omitempty"`
+
+	SolvedRound int `json:"solvedRound,omitempty"`
 }
 
 func (m *Match) snapshotLocked() MatchSnapshot {
@@ -46,75 +137,162 @@ func (m *Match) snapshotLocked() MatchSnapshot {
 		deadlineMs = m.deadline.UnixMilli()
 	}
 
+	players := make([]PlayerSnapshot, len(m.players))
+	for i, p := range m.players {
+		players[i] = PlayerSnapshot{
+			Slot:         string(p.slot),
+			ID:           p.id,
+			Name:         p.name,
+			Secret:       p.secret,
+			SecretSet:    p.secretSet,
+			Nonce:        p.nonce,
+			Salt:         p.salt,
+			Guess:        p.guess,
+			GuessSet:     p.guessSet,
+			Missed:       p.missed,
+			GuessLeftMs:  p.guessLeft.Milliseconds(),
+			Rematch:      p.rematchRequested,
+			SolvedRound:  p.solvedRound,
+			SolvedLeftMs: p.solvedLeft.Milliseconds(),
+			Muted:        m.mutedSlotsLocked(p),
+		}
+	}
+
+	var teams []TeamSnapshot
+	for _, t := range m.teams {
+		teams = append(teams, TeamSnapshot{
+			ID:          t.id,
+			Secret:      t.secret,
+			SecretSet:   t.secretSet,
+			Nonce:       t.nonce,
+			Salt:        t.salt,
+			Guess:       t.guess,
+			GuessSet:    t.guessSet,
+			Missed:      t.missed,
+			Proposals:   append([]Proposal(nil), t.proposals...),
+			SolvedRound: t.solvedRound,
+		})
+	}
+
 	return MatchSnapshot{
 		MatchID: m.id,
 		Phase:   m.phase,
 		Round:   m.round,
 
-		P1ID:   m.p1.id,
-		P1Name: m.p1.name,
-		P2ID:   m.p2.id,
-		P2Name: m.p2.name,
-
-		P1Secret:    m.p1.secret,
-		P1SecretSet: m.p1.secretSet,
-		P2Secret:    m.p2.secret,
-		P2SecretSet: m.p2.secretSet,
-
-		P1Guess:    m.p1.guess,
-		P1GuessSet: m.p1.guessSet,
-		P2Guess:    m.p2.guess,
-		P2GuessSet: m.p2.guessSet,
+		Players:      players,
+		Reserved:     append([]string(nil), m.reserved...),
+		JoinByMs:     toMs(m.joinBy),
+		CreatedBy:    m.createdBy,
+		Listed:       m.listed,
+		CreatorName:  m.creatorName,
+		ListedAtMs:   toMs(m.listedAt),
+		SharedSecret: m.sharedSecret,
+		SharedNonce:  m.sharedNonce,
+		Teams:        teams,
 
-		P1Rematch: m.p1.rematchRequested,
-		P2Rematch: m.p2.rematchRequested,
+		SeriesP1Wins:   m.series.P1Wins,
+		SeriesP2Wins:   m.series.P2Wins,
+		SeriesDraws:    m.series.Draws,
+		SeriesFinished: m.seriesFinished,
+		GamesPlayed:    m.gamesPlayed,
 
-		SeriesP1Wins: m.seriesP1Wins,
-		SeriesP2Wins: m.seriesP2Wins,
-		SeriesDraws:  m.seriesDraws,
+		Rules: m.rules,
 
 		DeadlineMs: deadlineMs,
+		Turn:       string(m.turn),
 
-		Winner:  m.winner,
-		History: append([]RoundHistoryItem(nil), m.history...),
+		Winner:       m.winner,
+		FinishReason: m.finishReason,
+		Rankings:     append([]Ranking(nil), m.rankings...),
+		History:      append([]RoundHistoryItem(nil), m.history...),
+
+		Chat: append([]ChatMessage(nil), m.chat...),
 	}
 }
 
 func (m *Match) restoreLocked(s MatchSnapshot) {
 	m.phase = s.Phase
 	m.round = s.Round
+	m.rules = s.Rules
+	m.roundDur = s.Rules.roundDuration(m.roundDur)
 
 	// players
-	m.p1.id = s.P1ID
-	m.p1.name = s.P1Name
-	m.p2.id = s.P2ID
-	m.p2.name = s.P2Name
-
-	// после рестарта нет соединений
-	m.p1.conn = nil
-	m.p2.conn = nil
-	m.p1.connected = false
-	m.p2.connected = false
-
-	// secrets / guesses
-	m.p1.secret = s.P1Secret
-	m.p1.secretSet = s.P1SecretSet
-	m.p2.secret = s.P2Secret
-	m.p2.secretSet = s.P2SecretSet
-
-	m.p1.guess = s.P1Guess
-	m.p1.guessSet = s.P1GuessSet
-	m.p2.guess = s.P2Guess
-	m.p2.guessSet = s.P2GuessSet
-
-	// rematch flags
-	m.p1.rematchRequested = s.P1Rematch
-	m.p2.rematchRequested = s.P2Rematch
+	players := s.Players
+	if len(players) == 0 {
+		players = legacyPlayers(s)
+	}
+	m.resetSlotsLocked(len(players))
+	for i, ps := range players {
+		p := m.players[i]
+		p.id = ps.ID
+		p.name = ps.Name
+
+		// после рестарта нет соединений
+		p.conn = nil
+		p.connected = false
+
+		// secrets / guesses
+		p.secret = ps.Secret
+		p.secretSet = ps.SecretSet
+		p.nonce = ps.Nonce
+		p.salt = ps.Salt
+		p.guess = ps.Guess
+		p.guessSet = ps.GuessSet
+		p.missed = ps.Missed
+		p.guessLeft = time.Duration(ps.GuessLeftMs) * time.Millisecond
+
+		p.rematchRequested = ps.Rematch
+		p.solvedRound = ps.SolvedRound
+		p.solvedLeft = time.Duration(ps.SolvedLeftMs) * time.Millisecond
+		for _, slot := range ps.Muted {
+			if p.muted == nil {
+				p.muted = make(map[Slot]bool)
+			}
+			p.muted[Slot(slot)] = true
+		}
+	}
+	m.reserved = append([]string(nil), s.Reserved...)
+	if s.JoinByMs > 0 {
+		m.joinBy = time.UnixMilli(s.JoinByMs)
+	}
+	m.createdBy = s.CreatedBy
+	m.listed = s.Listed
+	m.creatorName = s.CreatorName
+	if s.ListedAtMs > 0 {
+		m.listedAt = time.UnixMilli(s.ListedAtMs)
+	}
+	m.sharedSecret = s.SharedSecret
+	m.sharedNonce = s.SharedNonce
+
+	// teams
+	m.resetTeamsLocked()
+	for i, ts := range s.Teams {
+		if i >= len(m.teams) {
+			break
+		}
+		t := m.teams[i]
+		t.secret = ts.Secret
+		t.secretSet = ts.SecretSet
+		t.nonce = ts.Nonce
+		t.salt = ts.Salt
+		t.guess = ts.Guess
+		t.guessSet = ts.GuessSet
+		t.missed = ts.Missed
+		t.proposals = append([]Proposal(nil), ts.Proposals...)
+		t.solvedRound = ts.SolvedRound
+	}
 
 	// series score
-	m.seriesP1Wins = s.SeriesP1Wins
-	m.seriesP2Wins = s.SeriesP2Wins
-	m.seriesDraws = s.SeriesDraws
+	m.series = SeriesScore{
+		P1Wins: s.SeriesP1Wins,
+		P2Wins: s.SeriesP2Wins,
+		Draws:  s.SeriesDraws,
+	}
+	m.seriesFinished = s.SeriesFinished
+	m.gamesPlayed = s.GamesPlayed
+	if m.gamesPlayed == 0 {
+		m.gamesPlayed = m.series.played() // старые snapshot-ы: партии считались только в серии
+	}
 
 	if s.DeadlineMs > 0 {
 		m.deadline = time.UnixMilli(s.DeadlineMs)
@@ -122,9 +300,51 @@ func (m *Match) restoreLocked(s MatchSnapshot) {
 		m.deadline = time.Time{}
 	}
 
+	m.turn = Slot(s.Turn)
 	m.winner = s.Winner
+	m.finishReason = s.FinishReason
+	m.rankings = append([]Ranking(nil), s.Rankings...)
 	m.history = append([]RoundHistoryItem(nil), s.History...)
+	m.chat = append([]ChatMessage(nil), s.Chat...)
 
 	// активен раунд только если playing
 	m.roundActive = (m.phase == "playing")
 }
+
+// dropLegacy переводит старый двухслотовый формат в Players и очищает legacy-поля
+// (чтобы секреты не остались открытыми рядом с зашифрованным блоком).
+func (s *MatchSnapshot) dropLegacy() {
+	if len(s.Players) == 0 {
+		s.Players = legacyPlayers(*s)
+	}
+	s.P1ID, s.P1Name, s.P2ID, s.P2Name = "", "", "", ""
+	s.P1Secret, s.P1SecretSet, s.P2Secret, s.P2SecretSet = "", false, "", false
+	s.P1Guess, s.P1GuessSet, s.P2Guess, s.P2GuessSet = "", false, "", false
+	s.P1Rematch, s.P2Rematch = false, false
+}
+
+// legacyPlayers переводит старый двухслотовый формат snapshot-а в Players.
+func legacyPlayers(s MatchSnapshot) []PlayerSnapshot {
+	return []PlayerSnapshot{
+		{
+			Slot:      string(P1),
+			ID:        s.P1ID,
+			Name:      s.P1Name,
+			Secret:    s.P1Secret,
+			SecretSet: s.P1SecretSet,
+			Guess:     s.P1Guess,
+			GuessSet:  s.P1GuessSet,
+			Rematch:   s.P1Rematch,
+		},
+		{
+			Slot:      string(P2),
+			ID:        s.P2ID,
+			Name:      s.P2Name,
+			Secret:    s.P2Secret,
+			SecretSet: s.P2SecretSet,
+			Guess:     s.P2Guess,
+			GuessSet:  s.P2GuessSet,
+			Rematch:   s.P2Rematch,
+		},
+	}
+}
@@ -0,0 +1,154 @@
+package game
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Шифрование чувствительных полей snapshot-а (envelope: AES-GCM с ключом по key ID).
+//
+// Секреты, nonce/salt commit-reveal, текущие догадки и предложения команд
+// вынимаются из snapshot-а, шифруются одним блоком и кладутся в поле sealed
+// вместе с kid ключа. Остальной snapshot остаётся открытым JSON (фаза, история, счёт).
+// AAD = matchId + kid: зашифрованный блок нельзя подложить в чужой матч.
+//
+// Ротация: шифруем активным ключом, расшифровываем любым из известных.
+// Старые snapshot-ы без sealed читаются как есть и шифруются при следующем Save
+// (или сразу — RedisMatchStore.Reseal).
+
+// SealedSecrets — зашифрованный блок snapshot-а.
+type SealedSecrets struct {
+	KID   string `json:"kid"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// snapshotSecrets — открытое содержимое SealedSecrets. Players/Teams — по индексу.
+type snapshotSecrets struct {
+	Players      []sealedSlot `json:"players,omitempty"`
+	Teams        []sealedSlot `json:"teams,omitempty"`
+	SharedSecret string       `json:"sharedSecret,omitempty"`
+	SharedNonce  string       `json:"sharedNonce,omitempty"`
+}
+
+type sealedSlot struct {
+	Secret    string     `json:"secret,omitempty"`
+	Nonce     string     `json:"nonce,omitempty"`
+	Salt      string     `json:"salt,omitempty"`
+	Guess     string     `json:"guess,omitempty"`
+	Proposals []Proposal `json:"proposals,omitempty"`
+}
+
+// SnapshotCipher шифрует и расшифровывает секреты snapshot-ов.
+type SnapshotCipher struct {
+	activeKID string
+	aeads     map[string]cipher.AEAD
+}
+
+// NewSnapshotCipher: keys — все известные ключи (kid => 16/24/32 байта AES),
+// activeKID — каким ключом шифруем новые snapshot-ы.
+func NewSnapshotCipher(activeKID string, keys map[string][]byte) (*SnapshotCipher, error) {
+	if _, ok := keys[activeKID]; !ok {
+		return nil, fmt.Errorf("active snapshot key %q is not configured", activeKID)
+	}
+	c := &SnapshotCipher{activeKID: activeKID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for kid, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot key %q: %w", kid, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot key %q: %w", kid, err)
+		}
+		c.aeads[kid] = aead
+	}
+	return c, nil
+}
+
+// seal переносит чувствительные поля snap в Sealed (snap — копия, слайсы переписываем).
+func (c *SnapshotCipher) seal(snap *MatchSnapshot) error {
+	snap.dropLegacy()
+
+	var sec snapshotSecrets
+	players := make([]PlayerSnapshot, len(snap.Players))
+	for i, p := range snap.Players {
+		sec.Players = append(sec.Players, sealedSlot{Secret: p.Secret, Nonce: p.Nonce, Salt: p.Salt, Guess: p.Guess})
+		p.Secret, p.Nonce, p.Salt, p.Guess = "", "", "", ""
+		players[i] = p
+	}
+	teams := make([]TeamSnapshot, len(snap.Teams))
+	for i, t := range snap.Teams {
+		sec.Teams = append(sec.Teams, sealedSlot{Secret: t.Secret, Nonce: t.Nonce, Salt: t.Salt, Guess: t.Guess, Proposals: t.Proposals})
+		t.Secret, t.Nonce, t.Salt, t.Guess, t.Proposals = "", "", "", "", nil
+		teams[i] = t
+	}
+	sec.SharedSecret, sec.SharedNonce = snap.SharedSecret, snap.SharedNonce
+
+	pt, err := json.Marshal(sec)
+	if err != nil {
+		return err
+	}
+	aead := c.aeads[c.activeKID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	snap.Players = players
+	if snap.Teams != nil {
+		snap.Teams = teams
+	}
+	snap.SharedSecret, snap.SharedNonce = "", ""
+	snap.Sealed = &SealedSecrets{
+		KID:   c.activeKID,
+		Nonce: nonce,
+		Data:  aead.Seal(nil, nonce, pt, sealAAD(snap.MatchID, c.activeKID)),
+	}
+	return nil
+}
+
+// open возвращает чувствительные поля из Sealed обратно в snap.
+func (c *SnapshotCipher) open(snap *MatchSnapshot) error {
+	s := snap.Sealed
+	aead, ok := c.aeads[s.KID]
+	if !ok {
+		return fmt.Errorf("snapshot sealed with unknown key %q", s.KID)
+	}
+	pt, err := aead.Open(nil, s.Nonce, s.Data, sealAAD(snap.MatchID, s.KID))
+	if err != nil {
+		return fmt.Errorf("snapshot decrypt: %w", err)
+	}
+	var sec snapshotSecrets
+	if err := json.Unmarshal(pt, &sec); err != nil {
+		return err
+	}
+	if len(sec.Players) != len(snap.Players) || len(sec.Teams) != len(snap.Teams) {
+		return errors.New("snapshot sealed secrets do not match players")
+	}
+
+	for i, p := range sec.Players {
+		ps := &snap.Players[i]
+		ps.Secret, ps.Nonce, ps.Salt, ps.Guess = p.Secret, p.Nonce, p.Salt, p.Guess
+	}
+	for i, t := range sec.Teams {
+		ts := &snap.Teams[i]
+		ts.Secret, ts.Nonce, ts.Salt, ts.Guess, ts.Proposals = t.Secret, t.Nonce, t.Salt, t.Guess, t.Proposals
+	}
+	snap.SharedSecret, snap.SharedNonce = sec.SharedSecret, sec.SharedNonce
+	snap.Sealed = nil
+	return nil
+}
+
+// sealedWithActive — snapshot уже зашифрован активным ключом (Reseal его не трогает).
+func (c *SnapshotCipher) sealedWithActive(snap MatchSnapshot) bool {
+	return snap.Sealed != nil && snap.Sealed.KID == c.activeKID
+}
+
+func sealAAD(matchID, kid string) []byte {
+	return []byte(matchID + "|" + kid)
+}
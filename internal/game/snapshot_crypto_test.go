@@ -0,0 +1,153 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func newTestCipher(t *testing.T, active string, keys map[string][]byte) *SnapshotCipher {
+	t.Helper()
+	c, err := NewSnapshotCipher(active, keys)
+	require.NoError(t, err)
+	return c
+}
+
+// liveSnapshot — snapshot дуэли посреди раунда: секреты заданы, p1 уже ввёл догадку.
+func liveSnapshot(t *testing.T) MatchSnapshot {
+	t.Helper()
+	m := NewMatch("m1", 0)
+	m.Attach("u1", "Alice", newTestConn())
+	m.Attach("u2", "Bob", newTestConn())
+	require.NoError(t, m.SetSecretWithSalt(P1, "1357", "salt-1"))
+	require.NoError(t, m.SetSecret(P2, "2468"))
+	require.NoError(t, m.SubmitGuess(P1, "9024"))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshotLocked()
+}
+
+func TestRedisMatchStore_SnapshotEncryption(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "secrets and pending guesses are not stored in plaintext",
+			run: func(t *testing.T) {
+				s := &RedisMatchStore{cipher: newTestCipher(t, "k1", map[string][]byte{"k1": testKey(1)})}
+				snap := liveSnapshot(t)
+
+				b, err := s.encode(snap)
+				require.NoError(t, err)
+				for _, plain := range []string{"1357", "2468", "9024", "salt-1", snap.Players[0].Nonce} {
+					assert.NotContains(t, string(b), plain)
+				}
+				assert.Equal(t, "1357", snap.Players[0].Secret, "encode must not modify the caller's snapshot")
+
+				got, err := s.decode(b)
+				require.NoError(t, err)
+				assert.Equal(t, snap, got)
+			},
+		},
+		{
+			name: "old key still decrypts after rotation",
+			run: func(t *testing.T) {
+				old := &RedisMatchStore{cipher: newTestCipher(t, "k1", map[string][]byte{"k1": testKey(1)})}
+				b, err := old.encode(liveSnapshot(t))
+				require.NoError(t, err)
+
+				rotated := newTestCipher(t, "k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+				s := &RedisMatchStore{cipher: rotated}
+				got, err := s.decode(b)
+				require.NoError(t, err)
+				assert.Equal(t, "2468", got.Players[1].Secret)
+
+				var raw MatchSnapshot
+				require.NoError(t, json.Unmarshal(b, &raw))
+				assert.False(t, rotated.sealedWithActive(raw), "k1 snapshot must be resealed")
+
+				b2, err := s.encode(got)
+				require.NoError(t, err)
+				require.NoError(t, json.Unmarshal(b2, &raw))
+				assert.Equal(t, "k2", raw.Sealed.KID)
+
+				_, err = (&RedisMatchStore{cipher: newTestCipher(t, "k2", map[string][]byte{"k2": testKey(2)})}).decode(b)
+				require.Error(t, err, "dropped key cannot decrypt")
+			},
+		},
+		{
+			name: "sealed secrets are bound to the match id",
+			run: func(t *testing.T) {
+				s := &RedisMatchStore{cipher: newTestCipher(t, "k1", map[string][]byte{"k1": testKey(1)})}
+				b, err := s.encode(liveSnapshot(t))
+				require.NoError(t, err)
+
+				var raw MatchSnapshot
+				require.NoError(t, json.Unmarshal(b, &raw))
+				raw.MatchID = "m2"
+				moved, err := json.Marshal(raw)
+				require.NoError(t, err)
+
+				_, err = s.decode(moved)
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "plaintext snapshots still load and are sealed on next save",
+			run: func(t *testing.T) {
+				legacy := []byte(`{"matchId":"m1","phase":"playing","round":1,
+					"p1Id":"u1","p2Id":"u2","p1Secret":"1111","p1SecretSet":true,
+					"p2Secret":"2222","p2SecretSet":true,"history":[]}`)
+
+				s := &RedisMatchStore{cipher: newTestCipher(t, "k1", map[string][]byte{"k1": testKey(1)})}
+				snap, err := s.decode(legacy)
+				require.NoError(t, err)
+				assert.Equal(t, "1111", snap.P1Secret)
+
+				b, err := s.encode(snap)
+				require.NoError(t, err)
+				assert.NotContains(t, string(b), "1111")
+				assert.NotContains(t, string(b), "p1Secret")
+
+				got, err := s.decode(b)
+				require.NoError(t, err)
+				require.Len(t, got.Players, 2)
+				assert.Equal(t, "u1", got.Players[0].ID)
+				assert.Equal(t, "2222", got.Players[1].Secret)
+			},
+		},
+		{
+			name: "encrypted snapshot without configured key is an error",
+			run: func(t *testing.T) {
+				s := &RedisMatchStore{cipher: newTestCipher(t, "k1", map[string][]byte{"k1": testKey(1)})}
+				b, err := s.encode(liveSnapshot(t))
+				require.NoError(t, err)
+
+				_, err = (&RedisMatchStore{}).decode(b)
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "cipher rejects bad configuration",
+			run: func(t *testing.T) {
+				_, err := NewSnapshotCipher("k2", map[string][]byte{"k1": testKey(1)})
+				require.Error(t, err)
+				_, err = NewSnapshotCipher("k1", map[string][]byte{"k1": []byte("short")})
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
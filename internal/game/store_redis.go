@@ -3,6 +3,7 @@ package game
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -16,25 +17,73 @@ type MatchPersistence interface {
 	Load(ctx context.Context, matchID string) (MatchSnapshot, bool, error)
 }
 
+// DeadlineIndex — необязательное расширение MatchPersistence: матчи с таймером, которому не нужны
+// игроки онлайн (раунд по переписке, дедлайн неявки) — см. MatchSnapshot.pendingDeadlineMs.
+// Нужен, чтобы после рестарта таймеры сработали, даже если в матч никто не зайдёт.
+type DeadlineIndex interface {
+	PendingDeadlines(ctx context.Context) ([]string, error)
+}
+
+// deadlinesKey — sorted set таких матчей: member=matchId, score=дедлайн (unix millis).
+const deadlinesKey = "match:deadlines"
+
 type RedisMatchStore struct {
 	rdb *redis.Client
 	ttl time.Duration
+
+	// cipher шифрует секреты в snapshot-ах; nil => snapshot-ы пишем открытым JSON (dev)
+	cipher *SnapshotCipher
 }
 
 func NewRedisMatchStore(rdb *redis.Client, ttl time.Duration) *RedisMatchStore {
 	return &RedisMatchStore{rdb: rdb, ttl: ttl}
 }
 
+// SetCipher включает шифрование секретов в snapshot-ах (см. snapshot_crypto.go).
+func (s *RedisMatchStore) SetCipher(c *SnapshotCipher) {
+	s.cipher = c
+}
+
 func (s *RedisMatchStore) key(matchID string) string {
 	return fmt.Sprintf("match:%s:snapshot", matchID)
 }
 
 func (s *RedisMatchStore) Save(ctx context.Context, matchID string, snap MatchSnapshot) error {
-	b, err := json.Marshal(snap)
+	b, err := s.encode(snap)
 	if err != nil {
 		return err
 	}
-	return s.rdb.Set(ctx, s.key(matchID), b, s.ttl).Err()
+	// матч с дедлайном неявки мог попасть в индекс, даже если дедлайна уже нет: идём через ZRem
+	deadline := snap.pendingDeadlineMs()
+	if !snap.Rules.Correspondence && snap.JoinByMs == 0 {
+		return s.rdb.Set(ctx, s.key(matchID), b, s.ttl).Err()
+	}
+
+	// ход по переписке (или неявка) может ждать дольше MATCH_TTL: snapshot живёт до дедлайна и ещё ttl после
+	ttl := s.ttl
+	if ms := max(snap.DeadlineMs, snap.JoinByMs); ms > 0 {
+		ttl += max(time.Until(time.UnixMilli(ms)), 0)
+	}
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.key(matchID), b, ttl)
+		if deadline > 0 {
+			pipe.ZAdd(ctx, deadlinesKey, redis.Z{Score: float64(deadline), Member: matchID})
+		} else {
+			pipe.ZRem(ctx, deadlinesKey, matchID)
+		}
+		return nil
+	})
+	return err
+}
+
+// PendingDeadlines — матчи по переписке, у которых идёт таймер раунда (хода), и матчи, ждущие неявившихся.
+// Заодно чистит индекс от матчей, чей snapshot уже истёк (дедлайн + ttl в прошлом).
+func (s *RedisMatchStore) PendingDeadlines(ctx context.Context) ([]string, error) {
+	expired := time.Now().Add(-s.ttl).UnixMilli()
+	if err := s.rdb.ZRemRangeByScore(ctx, deadlinesKey, "-inf", fmt.Sprintf("(%d", expired)).Err(); err != nil {
+		return nil, err
+	}
+	return s.rdb.ZRange(ctx, deadlinesKey, 0, -1).Result()
 }
 
 func (s *RedisMatchStore) Load(ctx context.Context, matchID string) (MatchSnapshot, bool, error) {
@@ -46,9 +95,90 @@ func (s *RedisMatchStore) Load(ctx context.Context, matchID string) (MatchSnapsh
 		return MatchSnapshot{}, false, err
 	}
 
-	var snap MatchSnapshot
-	if err := json.Unmarshal(val, &snap); err != nil {
+	snap, err := s.decode(val)
+	if err != nil {
 		return MatchSnapshot{}, false, err
 	}
 	return snap, true, nil
 }
+
+// Reseal перешифровывает все snapshot-ы активным ключом: открытые (до включения
+// шифрования) и зашифрованные старым ключом после ротации. TTL ключей сохраняется.
+// Возвращает число переписанных snapshot-ов.
+func (s *RedisMatchStore) Reseal(ctx context.Context) (int, error) {
+	if s.cipher == nil {
+		return 0, errors.New("snapshot encryption is not configured")
+	}
+
+	n := 0
+	iter := s.rdb.Scan(ctx, 0, s.key("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		resealed := false
+		// WATCH: если матч успел сохраниться сам, транзакция не пройдёт — он уже зашифрован
+		err := s.rdb.Watch(ctx, func(tx *redis.Tx) error {
+			val, err := tx.Get(ctx, key).Bytes()
+			if err == redis.Nil {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			var raw MatchSnapshot
+			if err := json.Unmarshal(val, &raw); err != nil {
+				return err
+			}
+			if s.cipher.sealedWithActive(raw) {
+				return nil
+			}
+			snap, err := s.decode(val)
+			if err != nil {
+				return err
+			}
+			b, err := s.encode(snap)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.SetArgs(ctx, key, b, redis.SetArgs{KeepTTL: true})
+				return nil
+			})
+			resealed = err == nil
+			return err
+		}, key)
+		if err != nil && !errors.Is(err, redis.TxFailedErr) {
+			return n, fmt.Errorf("reseal %s: %w", key, err)
+		}
+		if resealed {
+			n++
+		}
+	}
+	return n, iter.Err()
+}
+
+func (s *RedisMatchStore) encode(snap MatchSnapshot) ([]byte, error) {
+	if s.cipher != nil {
+		if err := s.cipher.seal(&snap); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(snap)
+}
+
+// decode читает и открытые snapshot-ы (до включения шифрования), и зашифрованные.
+func (s *RedisMatchStore) decode(b []byte) (MatchSnapshot, error) {
+	var snap MatchSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return MatchSnapshot{}, err
+	}
+	if snap.Sealed == nil {
+		return snap, nil
+	}
+	if s.cipher == nil {
+		return MatchSnapshot{}, errors.New("snapshot is encrypted but no snapshot key is configured")
+	}
+	if err := s.cipher.open(&snap); err != nil {
+		return MatchSnapshot{}, err
+	}
+	return snap, nil
+}
@@ -0,0 +1,361 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Командный режим (Rules.Teams, 2v2).
+//
+// Слоты делятся на команды по порядку: p1+p2 — t1, p3+p4 — t2.
+// У команды один секрет (его задаёт любой из участников, последний set_secret побеждает)
+// и одна догадка за раунд. Догадку выбирают внутри команды: участник предлагает
+// вариант (team_propose, сразу голосуя за него), напарник голосует (team_vote).
+// Когда все участники команды проголосовали за одно предложение — оно становится
+// догадкой команды. Не договорились до дедлайна — команда пропускает раунд.
+//
+// Предложения и голоса видит только своя команда (team_proposals и state.teams).
+// В history попытки лежат в teams {t1, t2}; партия заканчивается в первом раунде,
+// где кто-то отгадал (обе команды — ничья), winner = t1|t2|draw.
+
+// Team — команда и её состояние в текущей партии.
+type Team struct {
+	id    string
+	slots []Slot
+
+	secret    string
+	secretSet bool
+	nonce     string
+	salt      string
+
+	guess    string
+	guessSet bool
+	missed   bool
+
+	proposals []Proposal // предложения текущего раунда
+
+	solvedRound int
+}
+
+// Proposal — вариант догадки, предложенный внутри команды.
+type Proposal struct {
+	By    string   `json:"by"` // слот автора
+	Guess string   `json:"guess"`
+	Votes []string `json:"votes"` // слоты, проголосовавшие за вариант
+}
+
+// TeamProposalsPayload — событие team_proposals (только участникам команды).
+type TeamProposalsPayload struct {
+	Team      string     `json:"team"`
+	Round     int        `json:"round"`
+	Proposals []Proposal `json:"proposals"`
+	Locked    string     `json:"locked,omitempty"` // догадка команды, если договорились
+}
+
+// TeamState — команда в state. Proposals заполнены только для своей команды.
+type TeamState struct {
+	ID          string     `json:"id"`
+	Members     []string   `json:"members"`
+	SecretSet   bool       `json:"secretSet"`
+	GuessReady  bool       `json:"guessReady"`
+	SolvedRound int        `json:"solvedRound,omitempty"`
+	Proposals   []Proposal `json:"proposals,omitempty"`
+}
+
+// TeamVotePayload — входящее team_vote: голос за предложенный вариант.
+type TeamVotePayload struct {
+	Guess string `json:"guess"`
+}
+
+// resetTeamsLocked создаёт пустые команды (для матчей без Teams — nil).
+func (m *Match) resetTeamsLocked() {
+	m.teams = nil
+	if !m.rules.Teams {
+		return
+	}
+	m.teams = make([]*Team, TeamCount)
+	for i := range m.teams {
+		t := &Team{id: teamID(i)}
+		for j := 0; j < TeamSize; j++ {
+			t.slots = append(t.slots, slotAt(i*TeamSize+j))
+		}
+		m.teams[i] = t
+	}
+}
+
+func teamID(i int) string {
+	return fmt.Sprintf("t%d", i+1)
+}
+
+// teamOfLocked — команда слота (nil вне командного режима).
+func (m *Match) teamOfLocked(slot Slot) *Team {
+	i := m.slotIndex(slot)
+	if i < 0 || len(m.teams) == 0 {
+		return nil
+	}
+	return m.teams[i/TeamSize]
+}
+
+// opponentTeamLocked — команда, чей секрет угадывает t.
+func (m *Match) opponentTeamLocked(t *Team) *Team {
+	for _, o := range m.teams {
+		if o != t {
+			return o
+		}
+	}
+	return nil
+}
+
+func (m *Match) ProposeGuess(slot Slot, guess string) error {
+	if !valid4Digits(guess) {
+		return errors.New("guess must be exactly 4 digits (0-9)")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.teamVoteCheckLocked(slot)
+	if err != nil {
+		return err
+	}
+	if findProposal(t.proposals, guess) < 0 {
+		t.proposals = append(t.proposals, Proposal{By: string(slot), Guess: guess})
+	}
+	m.castVoteLocked(t, slot, guess)
+	return nil
+}
+
+func (m *Match) VoteGuess(slot Slot, guess string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.teamVoteCheckLocked(slot)
+	if err != nil {
+		return err
+	}
+	if findProposal(t.proposals, guess) < 0 {
+		return errors.New("no such proposal")
+	}
+	m.castVoteLocked(t, slot, guess)
+	return nil
+}
+
+func (m *Match) teamVoteCheckLocked(slot Slot) (*Team, error) {
+	if !m.rules.Teams {
+		return nil, errors.New("match has no teams")
+	}
+	if m.phase != "playing" {
+		return nil, errors.New("game is not in playing phase")
+	}
+	if !m.roundActive {
+		return nil, errors.New("round is not active")
+	}
+	t := m.teamOfLocked(slot)
+	if t == nil {
+		return nil, errors.New("unknown slot")
+	}
+	if t.guessSet || t.missed {
+		return nil, errors.New("team guess already locked (or missed)")
+	}
+	return t, nil
+}
+
+// castVoteLocked переносит голос слота на guess и фиксирует догадку команды,
+// если за неё проголосовали все участники.
+func (m *Match) castVoteLocked(t *Team, slot Slot, guess string) {
+	for i := range t.proposals {
+		p := &t.proposals[i]
+		p.Votes = removeVote(p.Votes, string(slot))
+		if p.Guess == guess {
+			p.Votes = append(p.Votes, string(slot))
+		}
+	}
+
+	if p := t.proposals[findProposal(t.proposals, guess)]; len(p.Votes) == len(t.slots) {
+		t.guess = guess
+		t.guessSet = true
+	}
+
+	m.sendTeamProposalsLocked(t)
+	m.broadcastStateLocked()
+
+	if t.guessSet && m.teamsGuessedLocked() {
+		m.finalizeRoundLocked()
+	}
+	m.persistLocked()
+}
+
+func (m *Match) sendTeamProposalsLocked(t *Team) {
+	payload := TeamProposalsPayload{Team: t.id, Round: m.round, Proposals: t.proposals}
+	if t.guessSet {
+		payload.Locked = t.guess
+	}
+	env := Envelope{Type: "team_proposals", Payload: mustJSON(payload)}
+	for _, s := range t.slots {
+		if p := m.playerLocked(s); p != nil && p.conn != nil {
+			m.sendLocked(p.conn, env)
+		}
+	}
+}
+
+func (m *Match) teamsSecretsReadyLocked() bool {
+	for _, t := range m.teams {
+		if !t.secretSet {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Match) teamsGuessedLocked() bool {
+	for _, t := range m.teams {
+		if !t.guessSet && !t.missed {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Match) resetTeamRoundLocked() {
+	for _, t := range m.teams {
+		t.guess = ""
+		t.guessSet = false
+		t.missed = false
+		t.proposals = nil
+	}
+}
+
+func (m *Match) teamAttemptLocked(t *Team) Attempt {
+	if t.missed || !t.guessSet {
+		return Attempt{Guess: nil, Missed: true}
+	}
+	g := t.guess
+	b, c := BullsCows(m.opponentTeamLocked(t).secret, g)
+	return Attempt{Guess: &g, Bulls: b, Cows: c}
+}
+
+// finalizeTeamRoundLocked — итог раунда командной партии.
+func (m *Match) finalizeTeamRoundLocked() {
+	att := make(map[string]Attempt, len(m.teams))
+	for _, t := range m.teams {
+		att[t.id] = m.teamAttemptLocked(t)
+		if att[t.id].Bulls == 4 {
+			t.solvedRound = m.round
+		}
+	}
+
+	item := RoundHistoryItem{Round: m.round, Teams: att}
+	m.history = append(m.history, item)
+
+	solved := false
+	for _, t := range m.teams {
+		solved = solved || t.solvedRound > 0
+	}
+	switch {
+	case solved:
+		m.finishReason = ReasonSolved
+	case m.rules.MaxRounds > 0 && m.round >= m.rules.MaxRounds:
+		m.finishReason = ReasonMaxRounds
+	default:
+		m.afterRoundLocked(item)
+		return
+	}
+
+	m.phase = "finished"
+	m.gamesPlayed++
+	m.winner = m.teamWinnerLocked()
+	m.rankings = m.teamRankingsLocked()
+	m.afterRoundLocked(item)
+}
+
+// teamWinnerLocked: отгадала одна команда — она; обе или никто — по tiebreak.
+func (m *Match) teamWinnerLocked() string {
+	score := make(map[string]int, len(m.teams))
+	for _, t := range m.teams {
+		if t.solvedRound > 0 {
+			score[t.id] = 1
+		}
+	}
+	if m.finishReason == ReasonMaxRounds {
+		switch m.rules.tiebreak() {
+		case TiebreakLastRoundBulls:
+			last := m.history[len(m.history)-1]
+			for id, a := range last.Teams {
+				score[id] = a.Bulls
+			}
+		case TiebreakTotalScore:
+			for _, it := range m.history {
+				for id, a := range it.Teams {
+					score[id] += a.Bulls + a.Cows
+				}
+			}
+		}
+	}
+
+	t1, t2 := m.teams[0].id, m.teams[1].id
+	switch {
+	case score[t1] > score[t2]:
+		return t1
+	case score[t2] > score[t1]:
+		return t2
+	default:
+		return "draw"
+	}
+}
+
+// teamRankingsLocked — места по слотам: победившая команда 1, проигравшая 2; ничья — у всех 1.
+func (m *Match) teamRankingsLocked() []Ranking {
+	var out []Ranking
+	for _, t := range m.teams {
+		rank := 1
+		if m.winner != "draw" && m.winner != t.id {
+			rank = 2
+		}
+		for _, s := range t.slots {
+			out = append(out, Ranking{Slot: string(s), Team: t.id, Rank: rank, SolvedRound: t.solvedRound})
+		}
+	}
+	return out
+}
+
+// teamStatesLocked — команды для state игрока slot (чужие предложения скрыты).
+func (m *Match) teamStatesLocked(slot Slot) []TeamState {
+	mine := m.teamOfLocked(slot)
+	out := make([]TeamState, len(m.teams))
+	for i, t := range m.teams {
+		members := make([]string, len(t.slots))
+		for j, s := range t.slots {
+			members[j] = string(s)
+		}
+		out[i] = TeamState{
+			ID:          t.id,
+			Members:     members,
+			SecretSet:   t.secretSet,
+			GuessReady:  t.guessSet || t.missed,
+			SolvedRound: t.solvedRound,
+		}
+		if t == mine {
+			out[i].Proposals = t.proposals
+		}
+	}
+	return out
+}
+
+func findProposal(ps []Proposal, guess string) int {
+	for i, p := range ps {
+		if p.Guess == guess {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeVote(votes []string, slot string) []string {
+	out := votes[:0]
+	for _, v := range votes {
+		if v != slot {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTeamMatch — 2v2: t1 (p1, p2) загадала 1234, t2 (p3, p4) — 5678.
+func newTeamMatch(t *testing.T) (*Match, []*ClientConn) {
+	t.Helper()
+	m := NewMatchWithRules("m1", 0, Rules{Teams: true})
+	conns := make([]*ClientConn, 4)
+	for i, id := range []string{"u1", "u2", "u3", "u4"} {
+		conns[i] = newTestConn()
+		m.Attach(id, id, conns[i])
+	}
+	require.NoError(t, m.SetSecret("p2", "1234"))
+	require.NoError(t, m.SetSecret("p3", "5678"))
+	return m, conns
+}
+
+func TestMatch_Teams(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "teams agree on a guess and the other team never sees the discussion",
+			run: func(t *testing.T) {
+				rec := &memRecorder{}
+				m, conns := newTeamMatch(t)
+				m.onGameFinished = func(r GameResult) { _ = rec.RecordGame(context.Background(), r) }
+
+				m.mu.Lock()
+				require.Equal(t, "playing", m.phase, "one secret per team is enough")
+				m.mu.Unlock()
+
+				require.NoError(t, m.ProposeGuess("p1", "5678"))
+				require.NoError(t, m.SubmitGuess("p3", "0000"))
+				require.Error(t, m.VoteGuess("p4", "5678"), "cannot vote for the other team's proposal")
+
+				for _, env := range readEnvelopesNonBlocking(conns[2]) {
+					if env.Type != "team_proposals" {
+						continue
+					}
+					var p TeamProposalsPayload
+					require.NoError(t, json.Unmarshal(env.Payload, &p))
+					assert.Equal(t, "t2", p.Team)
+				}
+
+				m.mu.Lock()
+				st := m.buildStateLocked("p3")
+				m.mu.Unlock()
+				assert.Equal(t, "t2", st.Team)
+				assert.Empty(t, st.Teams[0].Proposals)
+				require.Len(t, st.Teams[1].Proposals, 1)
+
+				require.NoError(t, m.VoteGuess("p2", "5678"))
+				require.NoError(t, m.VoteGuess("p4", "0000"))
+
+				st, ok := findLastState(readEnvelopesNonBlocking(conns[0]))
+				require.True(t, ok)
+				assert.Equal(t, "finished", st.Phase)
+				assert.Equal(t, "t1", st.Winner)
+				require.Len(t, st.History, 1)
+				assert.Equal(t, 4, st.History[0].Teams["t1"].Bulls)
+				assert.Equal(t, map[string]string{"t1": "1234", "t2": "5678"}, st.RevealedSecrets)
+
+				require.Len(t, rec.games, 1)
+				g := rec.games[0]
+				assert.Equal(t, "t1", g.Winner)
+				require.Len(t, g.Players, 4)
+				assert.Equal(t, PlayerResult{Slot: "p2", Team: "t1", UserID: "u2", Rank: 1, SolvedRound: 1}, g.Players[1])
+				assert.Equal(t, PlayerResult{Slot: "p4", Team: "t2", UserID: "u4", Rank: 2}, g.Players[3])
+			},
+		},
+		{
+			name: "vote moves between proposals and a team without agreement misses the round",
+			run: func(t *testing.T) {
+				m, _ := newTeamMatch(t)
+
+				require.NoError(t, m.ProposeGuess("p1", "1111"))
+				require.NoError(t, m.ProposeGuess("p1", "2222"))
+				require.NoError(t, m.ProposeGuess("p2", "3333"))
+
+				m.mu.Lock()
+				team := m.teams[0]
+				assert.False(t, team.guessSet)
+				assert.Empty(t, team.proposals[0].Votes)
+				assert.Equal(t, []string{"p1"}, team.proposals[1].Votes)
+				assert.Equal(t, []string{"p2"}, team.proposals[2].Votes)
+				token := m.roundToken
+				m.mu.Unlock()
+
+				require.NoError(t, m.ProposeGuess("p3", "0000"))
+				require.NoError(t, m.VoteGuess("p4", "0000"))
+				m.onRoundTimeout(token)
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				require.Len(t, m.history, 1)
+				assert.True(t, m.history[0].Teams["t1"].Missed)
+				assert.False(t, m.history[0].Teams["t2"].Missed)
+				assert.Equal(t, 2, m.round)
+				assert.Empty(t, m.teams[0].proposals, "proposals are per round")
+			},
+		},
+		{
+			name: "snapshot keeps team secrets and proposals",
+			run: func(t *testing.T) {
+				m, _ := newTeamMatch(t)
+				require.NoError(t, m.ProposeGuess("p1", "5600"))
+
+				m.mu.Lock()
+				b, err := json.Marshal(m.snapshotLocked())
+				m.mu.Unlock()
+				require.NoError(t, err)
+				var snap MatchSnapshot
+				require.NoError(t, json.Unmarshal(b, &snap))
+
+				m2 := NewMatch("m1", 0)
+				m2.mu.Lock()
+				m2.restoreLocked(snap)
+				m2.mu.Unlock()
+
+				m2.Attach("u2", "", newTestConn())
+				require.NoError(t, m2.VoteGuess("p2", "5600"))
+
+				m2.mu.Lock()
+				defer m2.mu.Unlock()
+				require.Len(t, m2.teams, 2)
+				assert.Equal(t, "1234", m2.teams[0].secret)
+				assert.True(t, m2.teams[0].guessSet)
+				assert.Equal(t, "5600", m2.teams[0].guess)
+			},
+		},
+		{
+			name: "teams rules are validated",
+			run: func(t *testing.T) {
+				require.NoError(t, Rules{Teams: true}.Validate())
+				require.Error(t, Rules{Teams: true, Players: 3}.Validate())
+				require.Error(t, Rules{Teams: true, Mode: ModeAlternating}.Validate())
+				require.Error(t, Rules{Teams: true, Target: TargetShared}.Validate())
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -0,0 +1,95 @@
+package game
+
+import "sort"
+
+// Ranking — место игрока по итогам партии.
+type Ranking struct {
+	Slot        string `json:"slot"`
+	Team        string `json:"team,omitempty"`        // командный режим: t1|t2
+	Rank        int    `json:"rank"`                  // 1 — лучший; одинаковые места делятся (1,1,3)
+	SolvedRound int    `json:"solvedRound,omitempty"` // 0 — не отгадал
+}
+
+// tiebreakScoresLocked — очки по правилу tiebreak для тех, кто не отгадал.
+// Для tiebreak=draw у всех 0 (все неотгадавшие делят место).
+func (m *Match) tiebreakScoresLocked() map[Slot]int {
+	scores := make(map[Slot]int, len(m.players))
+
+	switch m.rules.tiebreak() {
+	case TiebreakLastRoundBulls:
+		for s, a := range m.roundAttemptsLocked(m.round) {
+			scores[s] = a.Bulls
+		}
+	case TiebreakTotalScore:
+		for _, it := range m.history {
+			for s, a := range it.attempts() {
+				scores[s] += a.Bulls + a.Cows
+			}
+		}
+	}
+	return scores
+}
+
+// rankLocked раскладывает игроков по местам: сначала отгадавшие (раньше — выше),
+// затем остальные по tiebreak.
+func (m *Match) rankLocked() []Ranking {
+	scores := m.tiebreakScoresLocked()
+
+	ps := append([]*Player(nil), m.players...)
+	// key: меньше — лучше
+	less := func(a, b *Player) bool {
+		switch {
+		case a.solvedRound > 0 && b.solvedRound > 0:
+			return a.solvedRound < b.solvedRound
+		case a.solvedRound > 0:
+			return true
+		case b.solvedRound > 0:
+			return false
+		default:
+			return scores[a.slot] > scores[b.slot]
+		}
+	}
+	sort.SliceStable(ps, func(i, j int) bool { return less(ps[i], ps[j]) })
+
+	out := make([]Ranking, len(ps))
+	for i, p := range ps {
+		rank := i + 1
+		if i > 0 && !less(ps[i-1], p) {
+			rank = out[i-1].Rank // делят место с предыдущим
+		}
+		out[i] = Ranking{Slot: string(p.slot), Rank: rank, SolvedRound: p.solvedRound}
+	}
+	return out
+}
+
+// winnerFromRankings — слот единственного первого места, иначе draw.
+func winnerFromRankings(rs []Ranking) string {
+	winner := ""
+	for _, r := range rs {
+		if r.Rank != 1 {
+			continue
+		}
+		if winner != "" {
+			return "draw"
+		}
+		winner = r.Slot
+	}
+	if winner == "" {
+		return "draw"
+	}
+	return winner
+}
+
+func (m *Match) gameFinishedPayloadLocked() GameFinishedPayload {
+	p := GameFinishedPayload{
+		Winner: m.winner,
+		Reason: m.finishReason,
+	}
+	if m.finishReason == ReasonMaxRounds {
+		p.Tiebreak = m.rules.tiebreak()
+	}
+	if len(m.players) > 2 {
+		p.Rankings = m.rankings
+	}
+	return p
+}
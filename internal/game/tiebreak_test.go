@@ -0,0 +1,78 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch_MaxRoundsTiebreak(t *testing.T) {
+	// секреты: p1=1234, p2=5678; никто не отгадает за 2 раунда
+	type round struct{ g1, g2 string }
+
+	cases := []struct {
+		name       string
+		rules      Rules
+		rounds     []round
+		wantWinner string
+		wantReason string
+	}{
+		{
+			name:       "last_round_bulls picks more bulls in final round",
+			rules:      Rules{MaxRounds: 2, Tiebreak: TiebreakLastRoundBulls},
+			rounds:     []round{{"5600", "1200"}, {"5000", "1230"}}, // last: p1 1 bull, p2 3 bulls
+			wantWinner: "p2",
+			wantReason: ReasonMaxRounds,
+		},
+		{
+			name:       "total_score sums bulls and cows",
+			rules:      Rules{MaxRounds: 2, Tiebreak: TiebreakTotalScore},
+			rounds:     []round{{"8765", "0000"}, {"0000", "1000"}}, // p1 4, p2 1
+			wantWinner: "p1",
+			wantReason: ReasonMaxRounds,
+		},
+		{
+			name:       "draw tiebreak is default",
+			rules:      Rules{MaxRounds: 1},
+			rounds:     []round{{"5600", "0000"}},
+			wantWinner: "draw",
+			wantReason: ReasonMaxRounds,
+		},
+		{
+			name:       "solve on the last allowed round is not a tiebreak",
+			rules:      Rules{MaxRounds: 2, Tiebreak: TiebreakTotalScore},
+			rounds:     []round{{"0000", "0000"}, {"5678", "0000"}},
+			wantWinner: "p1",
+			wantReason: ReasonSolved,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMatchWithRules("m1", 0, tc.rules)
+			c1 := newTestConn()
+			m.Attach("u1", "Alice", c1)
+			m.Attach("u2", "Bob", newTestConn())
+
+			require.NoError(t, m.SetSecret(P1, "1234"))
+			require.NoError(t, m.SetSecret(P2, "5678"))
+			for _, r := range tc.rounds {
+				require.NoError(t, m.SubmitGuess(P1, r.g1))
+				require.NoError(t, m.SubmitGuess(P2, r.g2))
+			}
+
+			env, ok := hasEnvelope(readEnvelopesNonBlocking(c1), "game_finished")
+			require.True(t, ok)
+			var p GameFinishedPayload
+			require.NoError(t, json.Unmarshal(env.Payload, &p))
+			require.Equal(t, tc.wantWinner, p.Winner)
+			require.Equal(t, tc.wantReason, p.Reason)
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			require.Equal(t, "finished", m.phase)
+			require.Len(t, m.history, len(tc.rounds))
+		})
+	}
+}
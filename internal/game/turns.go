@@ -0,0 +1,104 @@
+package game
+
+// Alternating (пошаговый) режим.
+//
+// Раунд состоит из ходов (полураундов) по порядку слотов: p1, потом p2 (и дальше
+// в free-for-all; отгадавшие ходы пропускают). У каждого хода свой таймер.
+// Результат раунда решается после последнего хода, поэтому если p1 отгадал первым,
+// у p2 всё равно есть уравнивающий ход: отгадал тоже — ничья, нет — победа p1.
+//
+// В history каждый ход — отдельный RoundHistoryItem с Turn=p1|p2|...;
+// заполнена только попытка того, кто ходил.
+
+// TurnStartedPayload — событие turn_started (очередной ход внутри раунда).
+// Начало раунда (первый ход) приходит как round_started с turn.
+type TurnStartedPayload struct {
+	Round      int    `json:"round"`
+	Turn       string `json:"turn"` // p1|p2|...
+	DeadlineMs int64  `json:"deadlineMs"`
+}
+
+// nextTurnLocked — следующий после after (по порядку слотов) игрок, который ещё угадывает.
+// after="" => первый такой игрок. "" если ходить больше некому.
+func (m *Match) nextTurnLocked(after Slot) Slot {
+	start := 0
+	if after != "" {
+		start = m.slotIndex(after) + 1
+	}
+	for i := start; i < len(m.players); i++ {
+		if m.players[i].solvedRound == 0 {
+			return m.players[i].slot
+		}
+	}
+	return ""
+}
+
+// finishTurnLocked закрывает текущий ход: пишет полураунд в history
+// и либо передаёт ход следующему, либо завершает раунд.
+func (m *Match) finishTurnLocked() {
+	if !m.roundActive || m.turn == "" {
+		return
+	}
+
+	slot := m.turn
+	item := m.newHistoryItem(slot, map[Slot]Attempt{slot: m.attemptLocked(slot)})
+	m.history = append(m.history, item)
+
+	if next := m.nextTurnLocked(slot); next != "" {
+		m.broadcastLocked(Envelope{Type: "round_result", Payload: mustJSON(item)})
+
+		m.turn = next
+		m.armTimerLocked()
+		m.notifyMoveLocked()
+		m.broadcastLocked(Envelope{Type: "turn_started", Payload: mustJSON(TurnStartedPayload{
+			Round:      m.round,
+			Turn:       string(m.turn),
+			DeadlineMs: toMs(m.deadline),
+		})})
+		m.broadcastStateLocked()
+		return
+	}
+
+	// последний ход — раунд закончен
+	m.roundActive = false
+	if m.roundTimer != nil {
+		m.roundTimer.Stop()
+	}
+	m.decideRoundLocked(m.roundAttemptsLocked(m.round))
+	m.afterRoundLocked(item)
+}
+
+// roundAttemptsLocked собирает попытки всех игроков за раунд
+// (в alternating они лежат в отдельных полураундах).
+func (m *Match) roundAttemptsLocked(round int) map[Slot]Attempt {
+	att := make(map[Slot]Attempt, len(m.players))
+	for _, it := range m.history {
+		if it.Round != round {
+			continue
+		}
+		for s, a := range it.attempts() {
+			att[s] = a
+		}
+	}
+	return att
+}
+
+// attempts — попытки элемента history по слотам.
+// Старый (дуэльный) формат: только p1/p2; полураунд — только попытка ходившего.
+func (it RoundHistoryItem) attempts() map[Slot]Attempt {
+	if it.Attempts != nil {
+		out := make(map[Slot]Attempt, len(it.Attempts))
+		for s, a := range it.Attempts {
+			out[Slot(s)] = a
+		}
+		return out
+	}
+	switch Slot(it.Turn) {
+	case P1:
+		return map[Slot]Attempt{P1: it.P1}
+	case P2:
+		return map[Slot]Attempt{P2: it.P2}
+	default:
+		return map[Slot]Attempt{P1: it.P1, P2: it.P2}
+	}
+}
@@ -0,0 +1,120 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAlternatingMatch(t *testing.T, roundDur time.Duration) (*Match, *ClientConn) {
+	t.Helper()
+	m := NewMatchWithRules("m1", roundDur, Rules{Mode: ModeAlternating})
+	c1 := newTestConn()
+	m.Attach("u1", "Alice", c1)
+	m.Attach("u2", "Bob", newTestConn())
+	require.NoError(t, m.SetSecret(P1, "1111"))
+	require.NoError(t, m.SetSecret(P2, "2222"))
+	return m, c1
+}
+
+func TestMatch_Alternating(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "p2 cannot guess on p1 turn",
+			run: func(t *testing.T) {
+				m, c1 := newAlternatingMatch(t, 0)
+
+				require.Error(t, m.SubmitGuess(P2, "1111"))
+
+				st, ok := findLastState(readEnvelopesNonBlocking(c1))
+				require.True(t, ok)
+				assert.Equal(t, "p1", st.Turn)
+				assert.Equal(t, ModeAlternating, st.Mode)
+			},
+		},
+		{
+			name: "p2 gets equalizing turn after p1 solves",
+			run: func(t *testing.T) {
+				m, _ := newAlternatingMatch(t, 0)
+
+				require.NoError(t, m.SubmitGuess(P1, "2222"))
+
+				m.mu.Lock()
+				require.Equal(t, "playing", m.phase)
+				require.Equal(t, P2, m.turn)
+				m.mu.Unlock()
+
+				require.NoError(t, m.SubmitGuess(P2, "1111"))
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, "finished", m.phase)
+				assert.Equal(t, "draw", m.winner)
+				require.Len(t, m.history, 2)
+				assert.Equal(t, "p1", m.history[0].Turn)
+				assert.Equal(t, 4, m.history[0].P1.Bulls)
+				assert.Equal(t, "p2", m.history[1].Turn)
+				assert.Equal(t, 4, m.history[1].P2.Bulls)
+			},
+		},
+		{
+			name: "p1 wins when p2 fails the equalizing turn",
+			run: func(t *testing.T) {
+				m, _ := newAlternatingMatch(t, 0)
+
+				require.NoError(t, m.SubmitGuess(P1, "2222"))
+				require.NoError(t, m.SubmitGuess(P2, "0000"))
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, "finished", m.phase)
+				assert.Equal(t, "p1", m.winner)
+			},
+		},
+		{
+			name: "next round starts with p1 turn",
+			run: func(t *testing.T) {
+				m, _ := newAlternatingMatch(t, 0)
+
+				require.NoError(t, m.SubmitGuess(P1, "0000"))
+				require.NoError(t, m.SubmitGuess(P2, "0000"))
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, 2, m.round)
+				assert.Equal(t, P1, m.turn)
+				assert.Len(t, m.history, 2)
+			},
+		},
+		{
+			name: "turn timeout marks only current player missed",
+			run: func(t *testing.T) {
+				m, _ := newAlternatingMatch(t, 40*time.Millisecond)
+
+				time.Sleep(60 * time.Millisecond) // p1 пропустил ход
+
+				m.mu.Lock()
+				require.Len(t, m.history, 1)
+				assert.True(t, m.history[0].P1.Missed)
+				assert.Equal(t, P2, m.turn)
+				m.mu.Unlock()
+
+				require.NoError(t, m.SubmitGuess(P2, "1111"))
+
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				assert.Equal(t, "finished", m.phase)
+				assert.Equal(t, "p2", m.winner)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
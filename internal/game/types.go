@@ -11,6 +11,7 @@ type Envelope struct {
 // SetSecretPayload входящие
 type SetSecretPayload struct {
 	Secret string `json:"secret"`
+	Salt   string `json:"salt,omitempty"` // commit-reveal: соль клиента (необязательно)
 }
 
 type SubmitGuessPayload struct {
@@ -19,8 +20,9 @@ type SubmitGuessPayload struct {
 
 // RoundStartedPayload исходящие
 type RoundStartedPayload struct {
-	Round      int   `json:"round"`
-	DeadlineMs int64 `json:"deadlineMs"`
+	Round      int    `json:"round"`
+	DeadlineMs int64  `json:"deadlineMs"`
+	Turn       string `json:"turn,omitempty"` // alternating: чей первый ход в раунде
 }
 
 type Attempt struct {
@@ -32,23 +34,69 @@ type Attempt struct {
 
 type RoundHistoryItem struct {
 	Round int     `json:"round"`
+	Turn  string  `json:"turn,omitempty"` // alternating: полураунд p1|p2|... (заполнена только его попытка)
 	P1    Attempt `json:"p1"`
 	P2    Attempt `json:"p2"`
+
+	// Attempts — попытки всех слотов; только для матчей больше чем на двоих.
+	Attempts map[string]Attempt `json:"attempts,omitempty"`
+
+	// Teams — попытки команд t1/t2 (командный режим; p1/p2 тогда пустые).
+	Teams map[string]Attempt `json:"teams,omitempty"`
 }
 
 type StatePayload struct {
 	MatchID          string             `json:"matchId"`
-	You              string             `json:"you"` // "p1" | "p2"
+	You              string             `json:"you"` // "p1" | "p2" | ... | "p8"
 	PlayerNames      map[string]string  `json:"playerNames"`
+	PlayerIDs        map[string]string  `json:"playerIds"` // слот => userId (для GET /api/users/{id})
 	PlayersConnected int                `json:"playersConnected"`
 	Phase            string             `json:"phase"` // waiting_players|waiting_secrets|playing|finished
 	Round            int                `json:"round"`
 	DeadlineMs       int64              `json:"deadlineMs"`
-	SecretsReady     map[string]bool    `json:"secretsReady"` // p1/p2
-	GuessesReady     map[string]bool    `json:"guessesReady"` // p1/p2 (текущий раунд)
+	SecretsReady     map[string]bool    `json:"secretsReady"` // по слотам
+	GuessesReady     map[string]bool    `json:"guessesReady"` // по слотам (текущий раунд)
 	History          []RoundHistoryItem `json:"history"`
-	Winner           string             `json:"winner"`                    // p1|p2|draw|"" (если не закончено)
-	RevealedSecrets  map[string]string  `json:"revealedSecrets,omitempty"` // показываем только после finished
+	Winner           string             `json:"winner"`                    // p1..p8|t1|t2|draw|"" (если не закончено)
+	RevealedSecrets  map[string]string  `json:"revealedSecrets,omitempty"` // показываем только после finished; target=shared => ключ "shared"
+
+	// commit-reveal (commit.go): commitments — сразу после set_secret,
+	// nonces и salts — вместе с revealedSecrets, ключи те же
+	Commitments    map[string]string `json:"commitments"`
+	RevealedNonces map[string]string `json:"revealedNonces,omitempty"`
+	RevealedSalts  map[string]string `json:"revealedSalts,omitempty"`
+
+	MaxPlayers   int            `json:"maxPlayers"`             // число слотов (2 — дуэль)
+	Target       string         `json:"target"`                 // next|shared
+	SolvedRounds map[string]int `json:"solvedRounds,omitempty"` // free-for-all: кто в каком раунде отгадал
+	Rankings     []Ranking      `json:"rankings,omitempty"`     // места после finished
+
+	Team  string      `json:"team,omitempty"`  // командный режим: твоя команда t1|t2
+	Teams []TeamState `json:"teams,omitempty"` // командный режим: предложения видны только своей команде
+
+	Mode string `json:"mode"`           // simultaneous|alternating
+	Turn string `json:"turn,omitempty"` // alternating: чей сейчас ход p1|p2|...
+
+	FinishReason string `json:"finishReason,omitempty"` // solved|max_rounds
+	MaxRounds    int    `json:"maxRounds,omitempty"`    // 0 => без лимита
+	Tiebreak     string `json:"tiebreak,omitempty"`     // правило при исчерпании maxRounds
+
+	Ranked         bool `json:"ranked"`
+	Correspondence bool `json:"correspondence,omitempty"` // игра по переписке: deadlineMs — часы и дни
+
+	BestOf         int         `json:"bestOf,omitempty"` // 0 => серия без ограничения
+	Series         SeriesScore `json:"series"`
+	SeriesFinished bool        `json:"seriesFinished"`
+
+	Muted []string `json:"muted,omitempty"` // чат: слоты, которые ты заглушил
+}
+
+// GameFinishedPayload — событие game_finished.
+type GameFinishedPayload struct {
+	Winner   string    `json:"winner"`             // p1..p8|t1|t2|draw
+	Reason   string    `json:"reason"`             // solved|max_rounds
+	Tiebreak string    `json:"tiebreak,omitempty"` // только для reason=max_rounds
+	Rankings []Ranking `json:"rankings,omitempty"` // только для матчей больше чем на двоих
 }
 
 type ErrorPayload struct {
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"example.com/bc-mvp/internal/auth"
 	"github.com/gorilla/websocket"
 )
 
@@ -54,7 +56,7 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Вариант 1: token из headers
-	playerID, displayName, err := s.authFromRequest(r)
+	claims, err := s.authFromRequest(r)
 	if err != nil {
 		http.Error(w, "invalid token", http.StatusUnauthorized)
 		return
@@ -66,15 +68,22 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Если токена не было в headers — ожидаем auth-сообщение как первое.
-	if playerID == "" {
-		pid, name, aerr := s.authOverWS(ws)
+	if claims == nil {
+		c, aerr := s.authOverWS(r.Context(), ws)
 		if aerr != nil {
 			_ = ws.WriteJSON(Envelope{Type: "error", Payload: mustJSON(ErrorPayload{Code: "unauthorized", Message: aerr.Error()})})
 			_ = ws.Close()
 			return
 		}
-		playerID = pid
-		displayName = name
+		claims = c
+	}
+	playerID, displayName := claims.UserID, claims.DisplayName
+
+	// гости играют только товарищеские матчи
+	if claims.Guest && m.Ranked() {
+		_ = ws.WriteJSON(Envelope{Type: "error", Payload: mustJSON(ErrorPayload{Code: "guest_unranked_only", Message: "guest accounts can only join unranked matches"})})
+		_ = ws.Close()
+		return
 	}
 
 	cc := &ClientConn{
@@ -92,6 +101,9 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	pc := s.newPresenceConn(playerID)
+	pc.update(m.PresenceState())
+
 	// writer loop (теперь уже после успешной авторизации)
 	go func() {
 		ticker := time.NewTicker(25 * time.Second)
@@ -105,12 +117,14 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 				_ = ws.WriteMessage(websocket.TextMessage, msg)
 			case <-ticker.C:
 				_ = ws.WriteMessage(websocket.PingMessage, []byte{})
+				pc.update(m.PresenceState())
 			}
 		}
 	}()
 
 	// initial state
 	m.SendStateTo(slot)
+	m.SendChatHistoryTo(slot)
 	m.BroadcastState()
 
 	// reader loop
@@ -119,6 +133,7 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			break
 		}
+		pc.touch()
 
 		var env Envelope
 		if err := json.Unmarshal(data, &env); err != nil {
@@ -135,7 +150,7 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 				m.SendErrorTo(slot, "bad_input", "invalid payload")
 				continue
 			}
-			if err := m.SetSecret(slot, p.Secret); err != nil {
+			if err := m.SetSecretWithSalt(slot, p.Secret, p.Salt); err != nil {
 				m.SendErrorTo(slot, "bad_input", err.Error())
 			}
 
@@ -149,6 +164,56 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 				m.SendErrorTo(slot, "bad_input", err.Error())
 			}
 
+		case "team_propose":
+			var p SubmitGuessPayload
+			if err := json.Unmarshal(env.Payload, &p); err != nil {
+				m.SendErrorTo(slot, "bad_input", "invalid payload")
+				continue
+			}
+			if err := m.ProposeGuess(slot, p.Guess); err != nil {
+				m.SendErrorTo(slot, "bad_input", err.Error())
+			}
+
+		case "team_vote":
+			var p TeamVotePayload
+			if err := json.Unmarshal(env.Payload, &p); err != nil {
+				m.SendErrorTo(slot, "bad_input", "invalid payload")
+				continue
+			}
+			if err := m.VoteGuess(slot, p.Guess); err != nil {
+				m.SendErrorTo(slot, "bad_input", err.Error())
+			}
+
+		case "chat":
+			var p ChatPayload
+			if err := json.Unmarshal(env.Payload, &p); err != nil {
+				m.SendErrorTo(slot, "bad_input", "invalid payload")
+				continue
+			}
+			if err := m.SendChat(slot, p.Text); err != nil {
+				m.SendErrorTo(slot, chatErrorCode(err), err.Error())
+			}
+
+		case "emote":
+			var p EmotePayload
+			if err := json.Unmarshal(env.Payload, &p); err != nil {
+				m.SendErrorTo(slot, "bad_input", "invalid payload")
+				continue
+			}
+			if err := m.SendEmote(slot, p.Emote); err != nil {
+				m.SendErrorTo(slot, chatErrorCode(err), err.Error())
+			}
+
+		case "mute", "unmute":
+			var p MutePayload
+			if err := json.Unmarshal(env.Payload, &p); err != nil {
+				m.SendErrorTo(slot, "bad_input", "invalid payload")
+				continue
+			}
+			if err := m.SetMuted(slot, Slot(p.Slot), env.Type == "mute"); err != nil {
+				m.SendErrorTo(slot, "bad_input", err.Error())
+			}
+
 		case "rematch_request":
 			if err := m.RequestRematch(slot); err != nil {
 				m.SendErrorTo(slot, "bad_input", err.Error())
@@ -160,21 +225,19 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// disconnect
+	pc.remove()
 	m.Detach(slot)
 	cc.Close()
 	m.BroadcastState()
 }
 
-func (s *Server) authFromRequest(r *http.Request) (userID string, displayName string, err error) {
+// authFromRequest — claims из headers; nil, nil если токена там нет.
+func (s *Server) authFromRequest(r *http.Request) (*auth.Claims, error) {
 	// Authorization: Bearer <token>
 	h := r.Header.Get("Authorization")
 	if strings.HasPrefix(h, "Bearer ") {
 		tok := strings.TrimPrefix(h, "Bearer ")
-		claims, err := s.auth.Verify(tok)
-		if err != nil {
-			return "", "", err
-		}
-		return claims.UserID, claims.DisplayName, nil
+		return s.auth.Verify(r.Context(), tok)
 	}
 
 	// Sec-WebSocket-Protocol: <token> (используют некоторые клиенты)
@@ -187,48 +250,44 @@ func (s *Server) authFromRequest(r *http.Request) (userID string, displayName st
 			if tok == "" {
 				continue
 			}
-			claims, err := s.auth.Verify(tok)
+			claims, err := s.auth.Verify(r.Context(), tok)
 			if err == nil {
-				return claims.UserID, claims.DisplayName, nil
+				return claims, nil
 			}
 		}
 	}
 
 	// Не ошибка: просто придётся авторизоваться через первое WS-сообщение.
-	return "", "", nil
+	return nil, nil
 }
 
 type authPayload struct {
 	Token string `json:"token"`
 }
 
-func (s *Server) authOverWS(ws *websocket.Conn) (userID string, displayName string, err error) {
+func (s *Server) authOverWS(ctx context.Context, ws *websocket.Conn) (*auth.Claims, error) {
 	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
 	defer ws.SetReadDeadline(time.Time{})
 
 	_, data, err := ws.ReadMessage()
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 	var env Envelope
 	if err := json.Unmarshal(data, &env); err != nil {
-		return "", "", err
+		return nil, err
 	}
 	if env.Type != "auth" {
-		return "", "", errors.New("missing auth message")
+		return nil, errors.New("missing auth message")
 	}
 	var p authPayload
 	if err := json.Unmarshal(env.Payload, &p); err != nil {
-		return "", "", err
+		return nil, err
 	}
 	if strings.TrimSpace(p.Token) == "" {
-		return "", "", errors.New("missing token")
-	}
-	claims, err := s.auth.Verify(strings.TrimSpace(p.Token))
-	if err != nil {
-		return "", "", err
+		return nil, errors.New("missing token")
 	}
-	return claims.UserID, claims.DisplayName, nil
+	return s.auth.Verify(ctx, strings.TrimSpace(p.Token))
 }
 
 func mustJSON(v any) json.RawMessage {
@@ -11,7 +11,10 @@ import (
 	"time"
 
 	"example.com/bc-mvp/internal/auth"
+	"example.com/bc-mvp/internal/notify"
 	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type memPersist struct {
@@ -33,11 +36,15 @@ func (p *memPersist) Load(ctx context.Context, matchID string) (MatchSnapshot, b
 
 type testVerifier struct{}
 
-func (v testVerifier) Verify(token string) (*auth.Claims, error) {
-	if token != "good" {
+func (v testVerifier) Verify(ctx context.Context, token string) (*auth.Claims, error) {
+	switch token {
+	case "good":
+		return &auth.Claims{UserID: "u1", DisplayName: "Alice"}, nil
+	case "guest":
+		return &auth.Claims{UserID: "g1", DisplayName: "Guest-0001", Guest: true}, nil
+	default:
 		return nil, errors.New("bad token")
 	}
-	return &auth.Claims{UserID: "u1", DisplayName: "Alice"}, nil
 }
 
 func TestWS_Endpoint_PathParam(t *testing.T) {
@@ -137,3 +144,201 @@ func TestWS_Endpoint_PathParam(t *testing.T) {
 		})
 	}
 }
+
+func TestWS_GuestUnrankedOnly(t *testing.T) {
+	cfg := Config{RoundDuration: 0}
+	matchSvc := NewMatchService(cfg, &memPersist{})
+	server := NewServer(cfg, matchSvc, testVerifier{})
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ctx := context.Background()
+	if _, err := matchSvc.Create(ctx, "ranked1"); err != nil {
+		t.Fatalf("create match: %v", err)
+	}
+	if _, err := matchSvc.CreateWithRules(ctx, "friendly1", Rules{Unranked: true}); err != nil {
+		t.Fatalf("create match: %v", err)
+	}
+
+	cases := []struct {
+		matchID  string
+		wantType string
+		wantCode string
+	}{
+		{matchID: "ranked1", wantType: "error", wantCode: "guest_unranked_only"},
+		{matchID: "friendly1", wantType: "state"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.matchID, func(t *testing.T) {
+			hdr := http.Header{}
+			hdr.Set("Authorization", "Bearer guest")
+			ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws/"+tc.matchID, hdr)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer ws.Close()
+
+			_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			var env Envelope
+			if err := json.Unmarshal(data, &env); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if env.Type != tc.wantType {
+				t.Fatalf("type=%q, want %q (%s)", env.Type, tc.wantType, env.Payload)
+			}
+			if tc.wantCode != "" {
+				var p ErrorPayload
+				_ = json.Unmarshal(env.Payload, &p)
+				if p.Code != tc.wantCode {
+					t.Fatalf("code=%q, want %q", p.Code, tc.wantCode)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateMatch_Guest(t *testing.T) {
+	cfg := Config{RoundDuration: 0}
+	matchSvc := NewMatchService(cfg, &memPersist{})
+	server := NewServer(cfg, matchSvc, testVerifier{})
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	cases := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+		wantRanked bool
+	}{
+		{name: "guest with default rules gets a friendly match", token: "guest", wantStatus: http.StatusOK},
+		{name: "guest public match is friendly too", token: "guest", body: `{"public":true}`, wantStatus: http.StatusOK},
+		{name: "guest asking for ranked", token: "guest", body: `{"ranked":true}`, wantStatus: http.StatusForbidden},
+		{name: "registered user keeps ranked default", token: "good", wantStatus: http.StatusOK, wantRanked: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/match", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tc.wantStatus, resp.StatusCode)
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			var out struct {
+				MatchID string `json:"matchId"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+			m, ok, err := matchSvc.GetOrLoad(context.Background(), out.MatchID)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, tc.wantRanked, m.Ranked(), "a guest must be able to join the match they created")
+		})
+	}
+}
+
+func TestWS_UserNotifications(t *testing.T) {
+	cfg := Config{RoundDuration: 0}
+	matchSvc := NewMatchService(cfg, &memPersist{})
+	hub := notify.NewHub()
+	matchSvc.SetNotifier(hub)
+	server := NewServer(cfg, matchSvc, testVerifier{})
+	server.SetNotifications(hub)
+
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	readType := func(t *testing.T, ws *websocket.Conn) Envelope {
+		t.Helper()
+		_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var env Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return env
+	}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "auth message, then opponent_joined for a created match",
+			run: func(t *testing.T) {
+				ws, _, err := websocket.DefaultDialer.Dial(wsURL+"/ws/user", nil)
+				if err != nil {
+					t.Fatalf("dial: %v", err)
+				}
+				defer ws.Close()
+				_ = ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"auth","payload":{"token":"good"}}`))
+				if env := readType(t, ws); env.Type != "subscribed" {
+					t.Fatalf("type=%q, want subscribed", env.Type)
+				}
+
+				// u1 создал матч и ушёл со страницы, гость занял слот
+				if _, err := matchSvc.CreateWithOptions(context.Background(), "created1", Rules{Unranked: true}, CreateOptions{CreatedBy: "u1"}); err != nil {
+					t.Fatalf("create match: %v", err)
+				}
+				hdr := http.Header{}
+				hdr.Set("Authorization", "Bearer guest")
+				opp, _, err := websocket.DefaultDialer.Dial(wsURL+"/ws/created1", hdr)
+				if err != nil {
+					t.Fatalf("dial match: %v", err)
+				}
+				defer opp.Close()
+
+				env := readType(t, ws)
+				if env.Type != "opponent_joined" {
+					t.Fatalf("type=%q, want opponent_joined", env.Type)
+				}
+				var p NotifyPlayer
+				_ = json.Unmarshal(env.Payload, &p)
+				if p.MatchID != "created1" || p.UserID != "g1" {
+					t.Fatalf("payload=%+v", p)
+				}
+			},
+		},
+		{
+			name: "bad header token is rejected before upgrade",
+			run: func(t *testing.T) {
+				hdr := http.Header{}
+				hdr.Set("Authorization", "Bearer bad")
+				ws, resp, err := websocket.DefaultDialer.Dial(wsURL+"/ws/user", hdr)
+				if err == nil {
+					_ = ws.Close()
+					t.Fatalf("expected dial error")
+				}
+				if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+					t.Fatalf("resp=%v err=%v, want 401", resp, err)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
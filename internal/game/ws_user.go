@@ -0,0 +1,92 @@
+package game
+
+import (
+	"net/http"
+	"time"
+
+	"example.com/bc-mvp/internal/presence"
+	"github.com/gorilla/websocket"
+)
+
+// handleUserWS — WebSocket /ws/user: уведомления пользователя вне матча
+// (opponent_joined, rematch_requested, friend_request, challenge, ...).
+//
+// Авторизация та же, что у /ws/{matchId}: header или первое сообщение {"type":"auth"}.
+// Сообщения от клиента, кроме auth, не нужны: читаем их только чтобы заметить закрытие.
+// События приходят в том же формате {"type","payload"}, что и в матче.
+func (s *Server) handleUserWS(w http.ResponseWriter, r *http.Request) {
+	if s.hub == nil {
+		http.Error(w, "notifications are disabled", http.StatusNotFound)
+		return
+	}
+
+	claims, err := s.authFromRequest(r)
+	if err != nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	if claims == nil {
+		c, aerr := s.authOverWS(r.Context(), ws)
+		if aerr != nil {
+			_ = ws.WriteJSON(Envelope{Type: "error", Payload: mustJSON(ErrorPayload{Code: "unauthorized", Message: aerr.Error()})})
+			return
+		}
+		claims = c
+	}
+
+	sub := s.hub.Subscribe(claims.UserID)
+	defer sub.Close()
+
+	pc := s.newPresenceConn(claims.UserID)
+	pc.update(presence.StateOnline)
+	defer pc.remove()
+
+	// reader: содержимое сообщений не нужно — любое из них ({"type":"active"} и т.п.)
+	// только отмечает активность для presence; ждём закрытия соединения
+	// (пишет только цикл ниже — gorilla/websocket не допускает конкурентной записи)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+			pc.touch()
+		}
+	}()
+
+	// первым сообщением подтверждаем подписку
+	if err := ws.WriteJSON(Envelope{Type: "subscribed", Payload: mustJSON(map[string]string{"userId": claims.UserID})}); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(25 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				// hub закрыт (shutdown)
+				_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := ws.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := ws.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+				return
+			}
+			pc.update(presence.StateOnline)
+		}
+	}
+}
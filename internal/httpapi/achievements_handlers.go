@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"example.com/bc-mvp/internal/achievements"
+	"example.com/bc-mvp/internal/store"
+)
+
+// AchievementCatalog lists every achievement that can be unlocked, so clients can show
+// the locked ones too.
+func AchievementCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	items := make([]map[string]any, 0, len(achievements.Rules))
+	for _, rule := range achievements.Rules {
+		items = append(items, map[string]any{
+			"id":          rule.ID,
+			"name":        rule.Name,
+			"description": rule.Description,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// achievementsJSON is the achievements list shared by /api/me, /api/users/{id} and the export.
+// IDs no longer in the catalog are skipped.
+func achievementsJSON(list []store.UnlockedAchievement) []map[string]any {
+	out := make([]map[string]any, 0, len(list))
+	for _, u := range list {
+		a, ok := achievements.Lookup(u.ID)
+		if !ok {
+			continue
+		}
+		item := map[string]any{
+			"id":          a.ID,
+			"name":        a.Name,
+			"description": a.Description,
+			"unlockedAt":  u.UnlockedAt,
+		}
+		if u.MatchID != "" {
+			item["matchId"] = u.MatchID
+		}
+		out = append(out, item)
+	}
+	return out
+}
@@ -1,22 +1,47 @@
 package httpapi
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
 	"strings"
 	"time"
 
 	"example.com/bc-mvp/internal/auth"
+	"example.com/bc-mvp/internal/profile"
+	"example.com/bc-mvp/internal/ratelimit"
 	"example.com/bc-mvp/internal/store"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	Users    *store.UserStore
-	Stats    *store.StatsStore
-	Auth     *auth.Service
-	TokenTTL time.Duration
+	Users        *store.UserStore
+	Stats        *store.StatsStore
+	Achievements *store.AchievementStore
+	Tokens       *store.TokenStore
+	Auth         *auth.Service
+	TokenTTL     time.Duration // access token lifetime (short)
+	RefreshTTL   time.Duration // refresh token lifetime
+
+	// External identity providers by name (/api/auth/oidc/{provider}/...).
+	OIDC         map[string]*auth.OIDCProvider
+	Identities   *store.IdentityStore
+	OIDCStateKey []byte // HMAC key for the pending-login cookie
+
+	// Password reset and email verification links (sent through the mail outbox).
+	UserTokens *store.UserTokenStore
+	BaseURL    string        // frontend origin the links point to
+	ResetTTL   time.Duration // password reset link lifetime
+	VerifyTTL  time.Duration // email verification link lifetime
+
+	// Login brute-force protection; nil => disabled.
+	Limiter    *ratelimit.LoginLimiter
+	TrustProxy bool // take the client IP from X-Forwarded-For (only behind a trusted proxy)
 }
 
 type RegisterRequest struct {
@@ -31,7 +56,18 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	AccessToken string `json:"accessToken"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"` // access token lifetime, seconds
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	All          bool   `json:"all"` // revoke refresh tokens on every device
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -46,14 +82,17 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
-	req.DisplayName = strings.TrimSpace(req.DisplayName)
+	req.DisplayName = profile.NormalizeDisplayName(req.DisplayName)
 
 	if req.Email == "" || req.Password == "" || req.DisplayName == "" {
 		writeError(w, http.StatusBadRequest, "bad_request", "email, password and displayName are required")
 		return
 	}
-	if len(req.Password) < 1 {
-		writeError(w, http.StatusBadRequest, "bad_request", "password must be at least 1 chars")
+	if err := auth.ValidatePassword(req.Password, req.Email, req.DisplayName); err != nil {
+		writeError(w, http.StatusBadRequest, "weak_password", err.Error())
+		return
+	}
+	if !h.checkDisplayName(w, r, req.DisplayName, "") {
 		return
 	}
 
@@ -82,6 +121,8 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	// создаём пустую статистику
 	_ = h.Stats.InitForUser(r.Context(), userID)
+	// best-effort: the user can ask for another link via /api/auth/email/resend
+	_ = h.sendUserToken(r.Context(), u, store.PurposeVerifyEmail)
 
 	// MVP: register возвращает 201 без токена (можно сделать сразу login, если хочешь)
 	w.WriteHeader(http.StatusCreated)
@@ -105,24 +146,262 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the attempt is counted before the password check, so parallel guesses share one budget
+	var attempt ratelimit.Reservation
+	if h.Limiter != nil {
+		var (
+			wait time.Duration
+			err  error
+		)
+		attempt, wait, err = h.Limiter.Reserve(r.Context(), clientIP(r, h.TrustProxy), req.Email)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to check login attempts")
+			return
+		}
+		if wait > 0 {
+			writeTooManyRequests(w, wait, "too many failed login attempts; try again later")
+			return
+		}
+	}
+
 	u, err := h.Users.GetByEmail(r.Context(), req.Email)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password))
+	}
 	if err != nil {
+		if h.Limiter != nil {
+			// the next attempt may already be blocked: tell the client when to retry
+			if wait := h.Limiter.Fail(attempt); wait > 0 {
+				setRetryAfter(w, wait)
+			}
+		}
 		writeError(w, http.StatusUnauthorized, "invalid_credentials", "invalid email or password")
 		return
 	}
+	if h.Limiter != nil {
+		_ = h.Limiter.Success(r.Context(), attempt)
+	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password)); err != nil {
-		writeError(w, http.StatusUnauthorized, "invalid_credentials", "invalid email or password")
+	h.issueTokens(w, r, u)
+}
+
+// Guest creates a guest account with a random display name and logs it in.
+// Guests can only join unranked matches; /api/auth/upgrade turns them into regular users.
+func (h *AuthHandler) Guest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+
+	name, err := uniqueName(r.Context(), h.Users, "Guest")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to generate guest name")
+		return
+	}
+	u := store.User{ID: uuid.NewString(), DisplayName: name, IsGuest: true}
+	if err := h.Users.CreateGuest(r.Context(), u.ID, u.DisplayName); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to create guest")
+		return
+	}
+	_ = h.Stats.InitForUser(r.Context(), u.ID)
+
+	h.issueTokens(w, r, u)
+}
+
+// UpgradeGuest attaches email and password to the current guest account.
+// The user ID stays the same, so stats and match history carry over.
+func (h *AuthHandler) UpgradeGuest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	req.DisplayName = profile.NormalizeDisplayName(req.DisplayName)
+
+	if req.Email == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "email and password are required")
+		return
+	}
+	if err := auth.ValidatePassword(req.Password, req.Email, req.DisplayName); err != nil {
+		writeError(w, http.StatusBadRequest, "weak_password", err.Error())
+		return
+	}
+	if req.DisplayName != "" && !h.checkDisplayName(w, r, req.DisplayName, userID) {
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to hash password")
+		return
+	}
+
+	err = h.Users.UpgradeGuest(r.Context(), userID, req.Email, string(hash), req.DisplayName)
+	switch {
+	case errors.Is(err, store.ErrEmailTaken):
+		writeError(w, http.StatusConflict, "email_taken", "email already exists")
+		return
+	case errors.Is(err, store.ErrNotGuest):
+		writeError(w, http.StatusConflict, "not_guest", "account is already registered")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal", "failed to upgrade account")
+		return
+	}
+
+	u, err := h.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load user")
+		return
+	}
+
+	_ = h.sendUserToken(r.Context(), u, store.PurposeVerifyEmail)
+
+	// new tokens without the guest claim
+	h.issueTokens(w, r, u)
+}
+
+// issueTokens starts a new refresh token family (one per login) and writes both tokens.
+func (h *AuthHandler) issueTokens(w http.ResponseWriter, r *http.Request, u store.User) {
+	refresh, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to issue refresh token")
+		return
+	}
+	err = h.Tokens.Create(r.Context(), store.RefreshToken{
+		ID:        uuid.NewString(),
+		UserID:    u.ID,
+		FamilyID:  uuid.NewString(),
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(h.RefreshTTL),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to store refresh token")
 		return
 	}
 
-	token, err := h.Auth.SignWithName(u.ID, u.DisplayName, h.TokenTTL)
+	h.writeTokens(w, u, refresh)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// The old refresh token becomes unusable; presenting it again revokes the whole family.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	if req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "refreshToken is required")
+		return
+	}
+
+	refresh, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to issue refresh token")
+		return
+	}
+	next, err := h.Tokens.Rotate(r.Context(), auth.HashRefreshToken(req.RefreshToken), store.RefreshToken{
+		ID:        uuid.NewString(),
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(h.RefreshTTL),
+	})
+	switch {
+	case errors.Is(err, store.ErrRefreshTokenReused):
+		writeError(w, http.StatusUnauthorized, "refresh_token_reused", "refresh token was already used; please log in again")
+		return
+	case errors.Is(err, store.ErrRefreshTokenInvalid):
+		writeError(w, http.StatusUnauthorized, "invalid_refresh_token", "refresh token is invalid or expired")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal", "failed to rotate refresh token")
+		return
+	}
+
+	u, err := h.Users.GetByID(r.Context(), next.UserID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "user not found")
+		return
+	}
+
+	h.writeTokens(w, u, refresh)
+}
+
+// Logout revokes the refresh token family. With "all": true it also revokes every other
+// refresh token and every access token issued so far; otherwise access tokens on other
+// devices stay valid (the one in hand expires within JWT_TTL).
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+
+	var req LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	if req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "refreshToken is required")
+		return
+	}
+
+	userID, err := h.Tokens.RevokeFamily(r.Context(), auth.HashRefreshToken(req.RefreshToken))
+	if errors.Is(err, store.ErrRefreshTokenInvalid) {
+		writeError(w, http.StatusUnauthorized, "invalid_refresh_token", "refresh token is invalid")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to revoke refresh token")
+		return
+	}
+	if req.All {
+		if err := h.Tokens.RevokeAllForUser(r.Context(), userID); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to revoke refresh tokens")
+			return
+		}
+		// access tokens are stateless: cut them off by the revocation timestamp
+		if err := h.Users.RevokeTokens(r.Context(), userID); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to revoke access tokens")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) writeTokens(w http.ResponseWriter, u store.User, refresh string) {
+	sign := h.Auth.SignWithName
+	if u.IsGuest {
+		sign = h.Auth.SignGuest
+	}
+	token, err := sign(u.ID, u.DisplayName, h.TokenTTL)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "internal", "failed to sign token")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, LoginResponse{AccessToken: token})
+	writeJSON(w, http.StatusOK, LoginResponse{
+		AccessToken:  token,
+		RefreshToken: refresh,
+		ExpiresIn:    int(h.TokenTTL / time.Second),
+	})
 }
 
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
@@ -143,16 +422,99 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "internal", "failed to load stats")
 		return
 	}
+	unlocked, err := h.Achievements.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load achievements")
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"id":          u.ID,
-		"email":       u.Email,
-		"displayName": u.DisplayName,
-		"createdAt":   u.CreatedAt,
-		"stats": map[string]any{
-			"wins":   st.Wins,
-			"losses": st.Losses,
-			"draws":  st.Draws,
-		},
+		"id":            u.ID,
+		"email":         u.Email,
+		"displayName":   u.DisplayName,
+		"guest":         u.IsGuest,
+		"emailVerified": u.EmailVerified,
+		"createdAt":     u.CreatedAt,
+		"stats":         statsJSON(st),
+		"achievements":  achievementsJSON(unlocked),
 	})
 }
+
+// statsJSON is the stats object shared by /api/me and /api/users/{id}.
+func statsJSON(st store.PlayerStats) map[string]any {
+	return map[string]any{
+		"wins":         st.Wins,
+		"losses":       st.Losses,
+		"draws":        st.Draws,
+		"seriesWins":   st.SeriesWins,
+		"seriesLosses": st.SeriesLosses,
+		"seriesDraws":  st.SeriesDraws,
+		// subset of wins/losses decided by tiebreak after maxRounds
+		"tiebreakWins":   st.TiebreakWins,
+		"tiebreakLosses": st.TiebreakLosses,
+		// subset of wins/losses/draws from 2v2 team games
+		"teamWins":   st.TeamWins,
+		"teamLosses": st.TeamLosses,
+		"teamDraws":  st.TeamDraws,
+	}
+}
+
+// JWKS serves the public keys used to verify access tokens (RS256/EdDSA; HMAC keys are never published).
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, h.Auth.JWKS())
+}
+
+// checkDisplayName validates a normalized display name and checks that no other user has it.
+// On failure it writes the response and returns false.
+func (h *AuthHandler) checkDisplayName(w http.ResponseWriter, r *http.Request, name, userID string) bool {
+	if err := profile.ValidateDisplayName(name); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_display_name", err.Error())
+		return false
+	}
+	taken, err := h.Users.DisplayNameTaken(r.Context(), name, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to check display name")
+		return false
+	}
+	if taken {
+		writeError(w, http.StatusConflict, "name_taken", "display name is already taken")
+		return false
+	}
+	return true
+}
+
+// uniqueNameAttempts bounds the retries of uniqueName; with 10^8 suffixes a retry is already rare.
+const uniqueNameAttempts = 5
+
+// uniqueName returns a random display name (see randomName) that nobody has taken yet,
+// matching the case-insensitive uniqueness enforced for chosen names.
+func uniqueName(ctx context.Context, users *store.UserStore, prefix string) (string, error) {
+	for i := 0; i < uniqueNameAttempts; i++ {
+		name, err := randomName(prefix)
+		if err != nil {
+			return "", err
+		}
+		taken, err := users.DisplayNameTaken(ctx, name, "")
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return name, nil
+		}
+	}
+	return "", errors.New("no free display name")
+}
+
+// randomName returns a random display name like "Guest-48213907".
+func randomName(prefix string) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(100_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%08d", prefix, n.Int64()), nil
+}
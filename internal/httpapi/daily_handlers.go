@@ -0,0 +1,182 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"example.com/bc-mvp/internal/store"
+)
+
+// DailyHandler serves the daily puzzle: one secret per UTC day, the same for everyone,
+// and one sequence of up to game.DailyMaxRounds guesses per user.
+type DailyHandler struct {
+	Daily   *game.Daily
+	Results *store.DailyStore
+}
+
+type DailyGuessRequest struct {
+	Guess string `json:"guess"`
+}
+
+// Get returns the caller's attempt for today, or for a past ?day=YYYY-MM-DD. The secret is
+// only revealed for past days, so a finished player cannot pass today's answer on.
+func (h *DailyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+	today := game.DailyDay(time.Now())
+	day, ok := dailyDay(w, r, today)
+	if !ok {
+		return
+	}
+
+	res, err := h.Results.Get(r.Context(), day, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load daily puzzle")
+		return
+	}
+	out := dailyJSON(res)
+	if day < today {
+		out["secret"] = h.Daily.Secret(day)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Guess submits the next guess for today's puzzle. The first guess starts the clock
+// used to break ties on the leaderboard. Guests cannot play: a fresh guest account is one
+// request away, so guests could learn the secret and solve it in one guess on a real account.
+func (h *DailyHandler) Guess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+	if GuestFromContext(r.Context()) {
+		writeError(w, http.StatusForbidden, "guest_not_allowed", "guest accounts cannot play the daily puzzle")
+		return
+	}
+	var req DailyGuessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+
+	var attempt game.Attempt
+	res, err := h.Results.Play(r.Context(), game.DailyDay(time.Now()), userID, func(p *game.DailyPlay) error {
+		a, err := h.Daily.Guess(p, req.Guess)
+		attempt = a
+		return err
+	})
+	switch {
+	case errors.Is(err, game.ErrDailyBadGuess):
+		writeError(w, http.StatusBadRequest, "bad_guess", err.Error())
+		return
+	case errors.Is(err, game.ErrDailyFinished):
+		writeError(w, http.StatusConflict, "daily_finished", err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal", "failed to save guess")
+		return
+	}
+	out := dailyJSON(res)
+	out["attempt"] = attempt
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Leaderboard ranks the players who solved the puzzle of ?day (default today) by the
+// number of guesses, then by time from the first guess to the solving one.
+func (h *DailyHandler) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	day, ok := dailyDay(w, r, game.DailyDay(time.Now()))
+	if !ok {
+		return
+	}
+	limit := defaultMatchLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxMatchLimit {
+			writeError(w, http.StatusBadRequest, "bad_request", "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	entries, finished, err := h.Results.Leaderboard(r.Context(), day, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load leaderboard")
+		return
+	}
+	items := make([]map[string]any, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, map[string]any{
+			"rank":        e.Rank,
+			"userId":      e.UserID,
+			"displayName": e.DisplayName,
+			"rounds":      e.Rounds,
+			"durationMs":  e.Duration.Milliseconds(),
+			"finishedAt":  e.FinishedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"day":      day,
+		"finished": finished, // players done with the day, solved or not
+		"items":    items,
+	})
+}
+
+// dailyDay reads ?day (default today); days after today are rejected.
+func dailyDay(w http.ResponseWriter, r *http.Request, today string) (string, bool) {
+	s := r.URL.Query().Get("day")
+	if s == "" {
+		return today, true
+	}
+	day, err := game.ParseDailyDay(s)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return "", false
+	}
+	if day > today {
+		writeError(w, http.StatusBadRequest, "bad_request", "day is in the future")
+		return "", false
+	}
+	return day, true
+}
+
+func dailyJSON(res store.DailyResult) map[string]any {
+	p := res.Play
+	out := map[string]any{
+		"day":       p.Day,
+		"maxRounds": game.DailyMaxRounds,
+		"rounds":    p.Rounds(),
+		"history":   p.History,
+		"solved":    p.Solved,
+		"finished":  p.Finished(),
+	}
+	if !res.StartedAt.IsZero() {
+		out["startedAt"] = res.StartedAt
+	}
+	if res.FinishedAt != nil {
+		out["finishedAt"] = res.FinishedAt
+	}
+	if p.Finished() {
+		out["share"] = game.DailyShare(p)
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/bc-mvp/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubVerifier map[string]*auth.Claims
+
+func (v stubVerifier) Verify(ctx context.Context, token string) (*auth.Claims, error) {
+	if c, ok := v[token]; ok {
+		return c, nil
+	}
+	return nil, errors.New("bad token")
+}
+
+func TestDailyHandler_Guess(t *testing.T) {
+	verifier := stubVerifier{
+		"guest": {UserID: "g1", DisplayName: "Guest-00000001", Guest: true},
+	}
+	// Results is nil: every case here is rejected before the store
+	h := AuthMiddleware(verifier)(http.HandlerFunc((&DailyHandler{}).Guess))
+
+	cases := []struct {
+		name     string
+		method   string
+		token    string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "guests cannot play", method: http.MethodPost, token: "guest", wantCode: http.StatusForbidden, wantErr: "guest_not_allowed"},
+		{name: "no token", method: http.MethodPost, wantCode: http.StatusUnauthorized, wantErr: "unauthorized"},
+		{name: "wrong method", method: http.MethodGet, token: "guest", wantCode: http.StatusMethodNotAllowed, wantErr: "method_not_allowed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/daily/guess", strings.NewReader(`{"guess":"0123"}`))
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"`+tc.wantErr+`"`)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"example.com/bc-mvp/internal/auth"
+	"example.com/bc-mvp/internal/mail"
+	"example.com/bc-mvp/internal/store"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ForgotPassword emails a single-use password reset link.
+// Always answers 202 so the endpoint does not reveal which emails are registered.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Email == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "email is required")
+		return
+	}
+
+	if u, err := h.Users.GetByEmail(r.Context(), req.Email); err == nil {
+		// throttled or failed: the answer is the same, the user can ask again later
+		_ = h.sendUserToken(r.Context(), u, store.PurposePasswordReset)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password by a reset token and logs the user out everywhere.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "token and password are required")
+		return
+	}
+	if err := auth.ValidatePassword(req.Password, "", ""); err != nil {
+		writeError(w, http.StatusBadRequest, "weak_password", err.Error())
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to hash password")
+		return
+	}
+	_, err = h.UserTokens.ResetPassword(r.Context(), auth.HashOpaqueToken(req.Token), string(hash))
+	if errors.Is(err, store.ErrUserTokenInvalid) {
+		writeError(w, http.StatusBadRequest, "invalid_token", "reset link is invalid, used or expired")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to reset password")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail confirms the email address by a verification token.
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	if req.Token == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "token is required")
+		return
+	}
+
+	_, err := h.UserTokens.VerifyEmail(r.Context(), auth.HashOpaqueToken(req.Token))
+	if errors.Is(err, store.ErrUserTokenInvalid) {
+		writeError(w, http.StatusBadRequest, "invalid_token", "verification link is invalid, used or expired")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to verify email")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification emails a new verification link to the current user.
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	u, err := h.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "user not found")
+		return
+	}
+	switch {
+	case u.Email == "":
+		writeError(w, http.StatusBadRequest, "no_email", "account has no email")
+		return
+	case u.EmailVerified:
+		writeError(w, http.StatusConflict, "already_verified", "email is already verified")
+		return
+	}
+
+	err = h.sendUserToken(r.Context(), u, store.PurposeVerifyEmail)
+	if errors.Is(err, store.ErrUserTokenThrottled) {
+		writeTooManyRequests(w, time.Minute, "verification email was sent recently")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to send verification email")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendUserToken issues a single-use token and enqueues the email with the link in one transaction;
+// the outbox sender delivers it in the background.
+func (h *AuthHandler) sendUserToken(ctx context.Context, u store.User, purpose string) error {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	var (
+		ttl time.Duration
+		msg mail.Message
+	)
+	switch purpose {
+	case store.PurposePasswordReset:
+		ttl = h.ResetTTL
+		msg = mail.PasswordReset(u.Email, h.link("/reset-password", token), ttl)
+	default:
+		ttl = h.VerifyTTL
+		msg = mail.VerifyEmail(u.Email, h.link("/verify-email", token), ttl)
+	}
+
+	return h.UserTokens.Issue(ctx, store.UserToken{
+		ID:        uuid.NewString(),
+		UserID:    u.ID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		Email:     u.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}, msg)
+}
+
+// link builds a frontend URL carrying the token: {BaseURL}{path}?token=...
+func (h *AuthHandler) link(path, token string) string {
+	return strings.TrimSuffix(h.BaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
@@ -2,7 +2,11 @@ package httpapi
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ErrorResponse struct {
@@ -19,3 +23,31 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 func writeError(w http.ResponseWriter, code int, errCode, msg string) {
 	writeJSON(w, code, ErrorResponse{Code: errCode, Message: msg})
 }
+
+// writeTooManyRequests answers 429 with Retry-After (whole seconds, rounded up).
+func writeTooManyRequests(w http.ResponseWriter, wait time.Duration, msg string) {
+	setRetryAfter(w, wait)
+	writeError(w, http.StatusTooManyRequests, "too_many_requests", msg)
+}
+
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	secs := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+}
+
+// clientIP returns the caller's IP. X-Forwarded-For is only honoured behind a trusted proxy:
+// otherwise any client could pick its own rate-limit key.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			// the proxy appends the address it saw: the last entry is the one we can trust
+			parts := strings.Split(xff, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,342 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"example.com/bc-mvp/internal/game"
+	"example.com/bc-mvp/internal/notify"
+	"example.com/bc-mvp/internal/store"
+	"github.com/google/uuid"
+)
+
+// FriendsHandler serves the friends list and direct challenges.
+// Friends and challenges need a registered account on both sides.
+type FriendsHandler struct {
+	Users   *store.UserStore
+	Friends *store.FriendStore
+	Matches *game.MatchService
+	Hub     *notify.Hub
+}
+
+type FriendRequestRequest struct {
+	UserID string `json:"userId"`
+}
+
+// ChallengeRequest invites a friend to a private 1v1 match; omitted rules use the server defaults.
+type ChallengeRequest struct {
+	UserID    string  `json:"userId"`
+	BestOf    *int    `json:"bestOf,omitempty"`
+	MaxRounds *int    `json:"maxRounds,omitempty"`
+	Mode      *string `json:"mode,omitempty"`
+	Ranked    *bool   `json:"ranked,omitempty"`
+	// Correspondence lets the two friends play without being online together (see game.Rules).
+	Correspondence *bool `json:"correspondence,omitempty"`
+	MoveHours      *int  `json:"moveHours,omitempty"`
+}
+
+// List returns friends, incoming and outgoing requests.
+func (h *FriendsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	list, err := h.Friends.List(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load friends")
+		return
+	}
+	out := map[string][]map[string]any{
+		store.FriendAccepted: {},
+		store.FriendIncoming: {},
+		store.FriendOutgoing: {},
+	}
+	for _, f := range list {
+		out[f.Status] = append(out[f.Status], map[string]any{
+			"userId":      f.UserID,
+			"displayName": f.DisplayName,
+			"since":       f.Since,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"friends":  out[store.FriendAccepted],
+		"incoming": out[store.FriendIncoming],
+		"outgoing": out[store.FriendOutgoing],
+	})
+}
+
+// SendRequest sends a friend request. If the other user has already asked,
+// the pair becomes friends right away (200 instead of 201).
+func (h *FriendsHandler) SendRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	me, ok := h.registeredUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req FriendRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	to, ok := h.recipient(w, r, req.UserID)
+	if !ok {
+		return
+	}
+
+	accepted, err := h.Friends.Request(r.Context(), me.ID, to.ID)
+	switch {
+	case errors.Is(err, store.ErrFriendRequestYourself):
+		writeError(w, http.StatusBadRequest, "bad_request", "cannot befriend yourself")
+		return
+	case errors.Is(err, store.ErrFriendRequestRecipient):
+		writeError(w, http.StatusNotFound, "not_found", "user not found")
+		return
+	case errors.Is(err, store.ErrAlreadyFriends):
+		writeError(w, http.StatusConflict, "already_friends", "already friends")
+		return
+	case errors.Is(err, store.ErrFriendRequestExists):
+		writeError(w, http.StatusConflict, "request_exists", "friend request already sent")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal", "failed to send friend request")
+		return
+	}
+
+	if accepted {
+		h.Hub.Publish(to.ID, notify.Event{Type: "friend_accepted", Payload: map[string]any{"by": userRef(me)}})
+		writeJSON(w, http.StatusOK, map[string]string{"status": store.FriendAccepted})
+		return
+	}
+	h.Hub.Publish(to.ID, notify.Event{Type: "friend_request", Payload: map[string]any{"from": userRef(me)}})
+	writeJSON(w, http.StatusCreated, map[string]string{"status": store.FriendOutgoing})
+}
+
+// Accept accepts the request from {id}.
+func (h *FriendsHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	me, ok := h.registeredUser(w, r)
+	if !ok {
+		return
+	}
+
+	from := r.PathValue("id")
+	if isBadUUID(from) {
+		writeError(w, http.StatusNotFound, "not_found", "friend request not found")
+		return
+	}
+	err := h.Friends.Accept(r.Context(), me.ID, from)
+	if errors.Is(err, store.ErrFriendRequestNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "friend request not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to accept friend request")
+		return
+	}
+	h.Hub.Publish(from, notify.Event{Type: "friend_accepted", Payload: map[string]any{"by": userRef(me)}})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Decline declines the request from {id}; the requester is not notified.
+func (h *FriendsHandler) Decline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	from := r.PathValue("id")
+	if isBadUUID(from) {
+		writeError(w, http.StatusNotFound, "not_found", "friend request not found")
+		return
+	}
+	err := h.Friends.Decline(r.Context(), userID, from)
+	if errors.Is(err, store.ErrFriendRequestNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "friend request not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to decline friend request")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Remove removes the friend {id} or cancels the outgoing request to {id}.
+func (h *FriendsHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use DELETE")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	other := r.PathValue("id")
+	if isBadUUID(other) {
+		writeError(w, http.StatusNotFound, "not_found", "friend not found")
+		return
+	}
+	err := h.Friends.Remove(r.Context(), userID, other)
+	if errors.Is(err, store.ErrFriendNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "friend not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to remove friend")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Challenge creates a private match that only the caller and the invited friend can join,
+// and notifies the friend in real time. Both then connect to /ws/{matchId} as usual.
+func (h *FriendsHandler) Challenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	me, ok := h.registeredUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	to, ok := h.recipient(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	friends, err := h.Friends.AreFriends(r.Context(), me.ID, to.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to check friends")
+		return
+	}
+	if !friends {
+		writeError(w, http.StatusForbidden, "not_friends", "you can only challenge friends")
+		return
+	}
+
+	// always a 1v1: exactly the two reserved players
+	rules := h.Matches.Rules()
+	rules.Players = 2
+	rules.Teams = false
+	if req.BestOf != nil {
+		rules.BestOf = *req.BestOf
+	}
+	if req.MaxRounds != nil {
+		rules.MaxRounds = *req.MaxRounds
+	}
+	if req.Mode != nil {
+		rules.Mode = *req.Mode
+	}
+	if req.Ranked != nil {
+		rules.Unranked = !*req.Ranked
+	}
+	if req.Correspondence != nil {
+		rules.Correspondence = *req.Correspondence
+	}
+	if req.MoveHours != nil {
+		rules.MoveHours = *req.MoveHours
+	}
+	if err := rules.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_rules", err.Error())
+		return
+	}
+
+	matchID := game.NewMatchID()
+	if _, err := h.Matches.CreateWithOptions(r.Context(), matchID, rules, game.CreateOptions{
+		CreatedBy: me.ID,
+		Reserved:  []string{me.ID, to.ID},
+	}); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to create match")
+		return
+	}
+
+	delivered := h.Hub.Publish(to.ID, notify.Event{Type: "challenge", Payload: map[string]any{
+		"matchId":        matchID,
+		"from":           userRef(me),
+		"bestOf":         rules.BestOf,
+		"mode":           rules.Mode,
+		"ranked":         !rules.Unranked,
+		"correspondence": rules.Correspondence,
+	}})
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"matchId":  matchID,
+		"notified": delivered > 0, // false: the friend has no open /ws/user or notification stream right now
+	})
+}
+
+// registeredUser loads the caller and rejects guests.
+func (h *FriendsHandler) registeredUser(w http.ResponseWriter, r *http.Request) (store.User, bool) {
+	return registeredUser(w, r, h.Users, "guest accounts cannot add friends")
+}
+
+// registeredUser loads the authenticated caller; guests get 403 with guestMsg.
+func registeredUser(w http.ResponseWriter, r *http.Request, users *store.UserStore, guestMsg string) (store.User, bool) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return store.User{}, false
+	}
+	u, err := users.GetByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "user not found")
+		return store.User{}, false
+	}
+	if u.IsGuest {
+		writeError(w, http.StatusForbidden, "guest_not_allowed", guestMsg)
+		return store.User{}, false
+	}
+	return u, true
+}
+
+// recipient loads the other side of a friend request or challenge; guests are not found.
+func (h *FriendsHandler) recipient(w http.ResponseWriter, r *http.Request, id string) (store.User, bool) {
+	if isBadUUID(id) {
+		writeError(w, http.StatusBadRequest, "bad_request", "userId must be a user id")
+		return store.User{}, false
+	}
+	u, err := h.Users.GetByID(r.Context(), id)
+	if errors.Is(err, store.ErrUserNotFound) || (err == nil && u.IsGuest) {
+		writeError(w, http.StatusNotFound, "not_found", "user not found")
+		return store.User{}, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load user")
+		return store.User{}, false
+	}
+	return u, true
+}
+
+func userRef(u store.User) map[string]string {
+	return map[string]string{"userId": u.ID, "displayName": u.DisplayName}
+}
+
+func isBadUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err != nil
+}
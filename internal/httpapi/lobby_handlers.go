@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"example.com/bc-mvp/internal/store"
+)
+
+// LobbyHandler lists public matches that are still waiting for players, so they can be
+// joined without knowing the match ID (POST /api/match with "public": true).
+type LobbyHandler struct {
+	Lobby *game.Lobby
+	Stats *store.StatsStore
+}
+
+// List returns the open matches, newest first, with the creator's stats.
+func (h *LobbyHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	items, err := h.items(r.Context(), h.Lobby.List())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load stats")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// Stream sends the lobby as Server-Sent Events: "lobby" with the full list first,
+// then "match_added" and "match_removed" as matches open and fill up. A client that
+// falls behind may miss events; reconnecting starts again from a full list.
+func (h *LobbyHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+
+	// subscribe before listing, so nothing opened in between is lost
+	events, cancel := h.Lobby.Subscribe()
+	defer cancel()
+	items, err := h.items(r.Context(), h.Lobby.List())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load stats")
+		return
+	}
+
+	rc, ok := startEventStream(w)
+	if !ok {
+		return
+	}
+	if err := writeEvent(w, "lobby", map[string]any{"items": items}); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Added == nil {
+				err = writeEvent(w, "match_removed", map[string]string{"matchId": ev.Removed})
+			} else {
+				var added []map[string]any
+				added, err = h.items(r.Context(), []game.LobbyEntry{*ev.Added})
+				if err == nil {
+					err = writeEvent(w, "match_added", added[0])
+				}
+			}
+			if err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *LobbyHandler) items(ctx context.Context, entries []game.LobbyEntry) ([]map[string]any, error) {
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.CreatedBy)
+	}
+	stats, err := h.Stats.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]map[string]any, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, map[string]any{
+			"matchId": e.MatchID,
+			"creator": map[string]any{
+				"id":          e.CreatedBy,
+				"displayName": e.CreatorName,
+				"stats":       statsJSON(stats[e.CreatedBy]),
+			},
+			"rules":     e.Rules,
+			"slots":     e.Slots,
+			"createdAt": e.CreatedAt,
+		})
+	}
+	return out, nil
+}
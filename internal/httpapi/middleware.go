@@ -10,10 +10,13 @@ import (
 
 type ctxKey string
 
-const userIDKey ctxKey = "userID"
+const (
+	userIDKey ctxKey = "userID"
+	guestKey  ctxKey = "guest"
+)
 
 type TokenVerifier interface {
-	Verify(token string) (*auth.Claims, error)
+	Verify(ctx context.Context, token string) (*auth.Claims, error)
 }
 
 func AuthMiddleware(verifier TokenVerifier) func(http.Handler) http.Handler {
@@ -26,13 +29,14 @@ func AuthMiddleware(verifier TokenVerifier) func(http.Handler) http.Handler {
 			}
 			token := strings.TrimPrefix(h, "Bearer ")
 
-			claims, err := verifier.Verify(token)
+			claims, err := verifier.Verify(r.Context(), token)
 			if err != nil {
 				writeError(w, http.StatusUnauthorized, "unauthorized", "invalid token")
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, guestKey, claims.Guest)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -43,3 +47,9 @@ func UserIDFromContext(ctx context.Context) (string, bool) {
 	s, ok := v.(string)
 	return s, ok
 }
+
+// GuestFromContext reports whether the token belongs to a guest account.
+func GuestFromContext(ctx context.Context) bool {
+	v, _ := ctx.Value(guestKey).(bool)
+	return v
+}
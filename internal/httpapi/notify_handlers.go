@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"example.com/bc-mvp/internal/notify"
+	"example.com/bc-mvp/internal/presence"
+	"github.com/google/uuid"
+)
+
+const sseHeartbeat = 25 * time.Second // keeps proxies from closing an idle stream
+
+// NotificationsHandler streams the caller's notifications (friend requests, challenges)
+// as Server-Sent Events: "event: <type>" with the JSON event as data.
+// The open stream also counts as an online session for presence (idle after a while:
+// a stream carries no client activity).
+type NotificationsHandler struct {
+	Hub      *notify.Hub
+	Presence *presence.Tracker // nil => not tracked
+}
+
+func (h *NotificationsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	rc, ok := startEventStream(w)
+	if !ok {
+		return
+	}
+
+	sub := h.Hub.Subscribe(userID)
+	defer sub.Close()
+
+	connID := uuid.NewString()
+	connectedAt := time.Now()
+	h.touchPresence(userID, connID, connectedAt)
+	defer h.endPresence(userID, connID)
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, ev.Type, ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			h.touchPresence(userID, connID, connectedAt)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// startEventStream clears the write deadline and sends the SSE headers; the caller flushes.
+// On failure the error response has already been written.
+func startEventStream(w http.ResponseWriter) (*http.ResponseController, bool) {
+	rc := http.NewResponseController(w)
+	// the stream outlives HTTP_WRITE_TIMEOUT by design
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeError(w, http.StatusInternalServerError, "internal", "streaming unsupported")
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	return rc, true
+}
+
+// writeEvent writes one SSE event with v as JSON data. A value that fails to encode is skipped.
+func writeEvent(w http.ResponseWriter, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+func (h *NotificationsHandler) touchPresence(userID, connID string, activeAt time.Time) {
+	if h.Presence == nil {
+		return
+	}
+	// request context may already be gone; presence is best effort
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = h.Presence.Set(ctx, userID, connID, presence.StateOnline, activeAt)
+}
+
+func (h *NotificationsHandler) endPresence(userID, connID string) {
+	if h.Presence == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = h.Presence.Remove(ctx, userID, connID)
+}
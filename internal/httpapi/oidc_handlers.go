@@ -0,0 +1,165 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/bc-mvp/internal/auth"
+	"example.com/bc-mvp/internal/profile"
+	"example.com/bc-mvp/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcCookie    = "bc_oidc"
+	oidcCookieTTL = 10 * time.Minute // time the user has to sign in at the provider
+)
+
+// OIDCStart redirects the browser to the provider's sign-in page (authorization code + PKCE).
+// The pending login (state, nonce, code verifier) travels in a short-lived signed cookie.
+func (h *AuthHandler) OIDCStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	p, ok := h.OIDC[r.PathValue("provider")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown_provider", "unknown identity provider")
+		return
+	}
+
+	req, err := auth.NewOIDCAuthRequest()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to start login")
+		return
+	}
+	to, err := p.AuthURL(r.Context(), req)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "oidc_unavailable", "identity provider is unavailable")
+		return
+	}
+	cookie, err := auth.EncodeOIDCAuthRequest(h.OIDCStateKey, p.Name(), req, oidcCookieTTL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to start login")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    cookie,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   int(oidcCookieTTL / time.Second),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode, // must survive the top-level redirect back from the provider
+	})
+	http.Redirect(w, r, to, http.StatusFound)
+}
+
+// OIDCCallback finishes the login: checks state, exchanges the code, verifies the ID token,
+// links (provider, subject) to a user and issues our own tokens. On first login the identity
+// joins the account with the same verified email, or a new user is created.
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	p, ok := h.OIDC[r.PathValue("provider")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown_provider", "unknown identity provider")
+		return
+	}
+
+	// the pending login is single-use whatever the outcome
+	http.SetCookie(w, &http.Cookie{Name: oidcCookie, Path: "/api/auth/oidc/", MaxAge: -1, HttpOnly: true, Secure: isHTTPS(r)})
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		writeError(w, http.StatusUnauthorized, "oidc_error", strings.TrimSpace(e+" "+q.Get("error_description")))
+		return
+	}
+
+	c, err := r.Cookie(oidcCookie)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "oidc_state", "login session not found or expired")
+		return
+	}
+	req, err := auth.DecodeOIDCAuthRequest(h.OIDCStateKey, p.Name(), c.Value)
+	if err != nil || subtle.ConstantTimeCompare([]byte(req.State), []byte(q.Get("state"))) != 1 {
+		writeError(w, http.StatusBadRequest, "oidc_state", "login session not found or expired")
+		return
+	}
+	code := q.Get("code")
+	if code == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "code is required")
+		return
+	}
+
+	id, err := p.Exchange(r.Context(), code, req)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "oidc_failed", "identity provider login failed")
+		return
+	}
+
+	identity := store.Identity{
+		Provider:      p.Name(),
+		Subject:       id.Subject,
+		Email:         strings.TrimSpace(strings.ToLower(id.Email)),
+		EmailVerified: id.EmailVerified,
+	}
+	u, err := h.Identities.Login(r.Context(), identity)
+	if errors.Is(err, store.ErrUserNotFound) {
+		u, err = h.firstOIDCLogin(r.Context(), identity, id.Name)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to link identity")
+		return
+	}
+
+	h.issueTokens(w, r, u)
+}
+
+// firstOIDCLogin links a new identity to an existing account or creates a user for it.
+func (h *AuthHandler) firstOIDCLogin(ctx context.Context, id store.Identity, name string) (store.User, error) {
+	name, err := h.oidcDisplayName(ctx, name, id.Email)
+	if err != nil {
+		return store.User{}, err
+	}
+	u, created, err := h.Identities.LinkOrCreate(ctx, id, uuid.NewString(), name)
+	if err != nil {
+		return store.User{}, err
+	}
+	if created {
+		_ = h.Stats.InitForUser(ctx, u.ID)
+	}
+	return u, nil
+}
+
+// oidcDisplayName picks a display name for a user created on first OIDC login: the provider's
+// name or else the email local part, if it passes the rules for chosen names and is free;
+// otherwise a unique generated one.
+func (h *AuthHandler) oidcDisplayName(ctx context.Context, name, email string) (string, error) {
+	local, _, _ := strings.Cut(email, "@")
+	for _, candidate := range []string{name, local} {
+		candidate = profile.NormalizeDisplayName(candidate)
+		if candidate == "" || profile.ValidateDisplayName(candidate) != nil {
+			continue
+		}
+		taken, err := h.Users.DisplayNameTaken(ctx, candidate, "")
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+	return uniqueName(ctx, h.Users, "Player")
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
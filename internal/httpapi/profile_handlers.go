@@ -0,0 +1,293 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"example.com/bc-mvp/internal/auth"
+	"example.com/bc-mvp/internal/profile"
+	"example.com/bc-mvp/internal/ratelimit"
+	"example.com/bc-mvp/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type UpdateMeRequest struct {
+	DisplayName *string `json:"displayName"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type DeleteMeRequest struct {
+	Password string `json:"password"` // required for accounts with a password
+}
+
+// MeRoutes dispatches /api/me by method: GET profile, PATCH update, DELETE account.
+func (h *AuthHandler) MeRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Me(w, r)
+	case http.MethodPatch:
+		h.UpdateMe(w, r)
+	case http.MethodDelete:
+		h.DeleteMe(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET, PATCH or DELETE")
+	}
+}
+
+// UpdateMe changes profile fields (currently the display name) and returns the updated profile.
+// Access tokens keep the old name in the "name" claim until they are refreshed.
+func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	var req UpdateMeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+
+	if req.DisplayName != nil {
+		name := profile.NormalizeDisplayName(*req.DisplayName)
+		if err := profile.ValidateDisplayName(name); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_display_name", err.Error())
+			return
+		}
+		err := h.Users.UpdateDisplayName(r.Context(), userID, name)
+		if errors.Is(err, store.ErrNameTaken) {
+			writeError(w, http.StatusConflict, "name_taken", "display name is already taken")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to update profile")
+			return
+		}
+	}
+
+	h.Me(w, r)
+}
+
+// ChangePassword sets a new password after checking the current one.
+// All other sessions are logged out; the response carries tokens for a new session.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+
+	u, err := h.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "user not found")
+		return
+	}
+	if u.PasswordHash == "" {
+		writeError(w, http.StatusBadRequest, "no_password", "account has no password")
+		return
+	}
+	if !h.checkPassword(w, r, u, req.CurrentPassword) {
+		return
+	}
+	if err := auth.ValidatePassword(req.NewPassword, u.Email, u.DisplayName); err != nil {
+		writeError(w, http.StatusBadRequest, "weak_password", err.Error())
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to hash password")
+		return
+	}
+	if err := h.Users.ChangePassword(r.Context(), u.ID, string(hash)); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to change password")
+		return
+	}
+
+	h.issueTokens(w, r, u)
+}
+
+// DeleteMe deletes the account. Match history stays for the opponents but is no longer
+// linked to the user; stats, sessions and linked identities are erased.
+func (h *AuthHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	u, err := h.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "user not found")
+		return
+	}
+	if u.PasswordHash != "" {
+		var req DeleteMeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+			return
+		}
+		if !h.checkPassword(w, r, u, req.Password) {
+			return
+		}
+	}
+
+	if err := h.Users.Delete(r.Context(), u.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to delete account")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Export returns everything stored about the current user as a JSON download.
+func (h *AuthHandler) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+
+	ex, err := h.Users.Export(r.Context(), userID)
+	if errors.Is(err, store.ErrUserNotFound) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "user not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to export data")
+		return
+	}
+	st, err := h.Stats.Get(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load stats")
+		return
+	}
+
+	identities := make([]map[string]any, 0, len(ex.Identities))
+	for _, id := range ex.Identities {
+		identities = append(identities, map[string]any{
+			"provider":    id.Provider,
+			"subject":     id.Subject,
+			"email":       id.Email,
+			"createdAt":   id.CreatedAt,
+			"lastLoginAt": id.LastLoginAt,
+		})
+	}
+	sessions := make([]map[string]any, 0, len(ex.Sessions))
+	for _, s := range ex.Sessions {
+		sessions = append(sessions, map[string]any{
+			"id":        s.ID,
+			"createdAt": s.CreatedAt,
+			"expiresAt": s.ExpiresAt,
+			"usedAt":    s.UsedAt,
+			"revokedAt": s.RevokedAt,
+		})
+	}
+	friends := make([]map[string]any, 0, len(ex.Friends))
+	for _, f := range ex.Friends {
+		friends = append(friends, map[string]any{
+			"userId":      f.UserID,
+			"displayName": f.DisplayName,
+			"status":      f.Status,
+			"since":       f.Since,
+		})
+	}
+	daily := make([]map[string]any, 0, len(ex.Daily))
+	for _, d := range ex.Daily {
+		daily = append(daily, dailyJSON(d))
+	}
+	games := make([]map[string]any, 0, len(ex.Games))
+	for _, g := range ex.Games {
+		games = append(games, map[string]any{
+			"matchId":     g.MatchID,
+			"gameNo":      g.GameNo,
+			"slot":        g.Slot,
+			"team":        g.Team,
+			"rank":        g.Rank,
+			"solvedRound": g.SolvedRound,
+			"winner":      g.Winner,
+			"reason":      g.Reason,
+			"ranked":      g.Ranked,
+			"rounds":      g.Rounds,
+			"finishedAt":  g.FinishedAt,
+		})
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="bc-export.json"`)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"exportedAt": time.Now().UTC(),
+		"user": map[string]any{
+			"id":            ex.User.ID,
+			"email":         ex.User.Email,
+			"displayName":   ex.User.DisplayName,
+			"guest":         ex.User.IsGuest,
+			"emailVerified": ex.User.EmailVerified,
+			"createdAt":     ex.User.CreatedAt,
+		},
+		"stats":      statsJSON(st),
+		"identities": identities,
+		"sessions":   sessions,
+		"friends":    friends,
+		"games":      games,
+
+		"achievements": achievementsJSON(ex.Achievements),
+		"daily":        daily,
+	})
+}
+
+// checkPassword verifies the current password for sensitive changes. Wrong guesses count
+// against the same per-IP and per-account budget as /api/auth/login.
+// On failure it writes the response and returns false.
+func (h *AuthHandler) checkPassword(w http.ResponseWriter, r *http.Request, u store.User, password string) bool {
+	var attempt ratelimit.Reservation
+	if h.Limiter != nil {
+		var (
+			wait time.Duration
+			err  error
+		)
+		attempt, wait, err = h.Limiter.Reserve(r.Context(), clientIP(r, h.TrustProxy), u.Email)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal", "failed to check login attempts")
+			return false
+		}
+		if wait > 0 {
+			writeTooManyRequests(w, wait, "too many failed attempts; try again later")
+			return false
+		}
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		if h.Limiter != nil {
+			if wait := h.Limiter.Fail(attempt); wait > 0 {
+				setRetryAfter(w, wait)
+			}
+		}
+		// 403, not 401: the access token itself is fine
+		writeError(w, http.StatusForbidden, "wrong_password", "current password is incorrect")
+		return false
+	}
+	if h.Limiter != nil {
+		_ = h.Limiter.Success(r.Context(), attempt)
+	}
+	return true
+}
@@ -0,0 +1,422 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example.com/bc-mvp/internal/game"
+	"example.com/bc-mvp/internal/store"
+	"example.com/bc-mvp/internal/tournament"
+)
+
+const (
+	defaultTournamentLimit = 20
+	maxTournamentLimit     = 100
+)
+
+// TournamentsHandler serves tournaments: creation and registration need a registered
+// account, everything else is public. Matches are created and results collected by
+// tournament.Service; these endpoints only start the tournament and show its progress.
+type TournamentsHandler struct {
+	Users       *store.UserStore
+	Tournaments *store.TournamentStore
+	Service     *tournament.Service
+	Matches     *game.MatchService
+}
+
+// CreateTournamentRequest: omitted rules use the server defaults; every tournament match is a duel.
+type CreateTournamentRequest struct {
+	Name       string  `json:"name"`
+	Format     string  `json:"format"`
+	MaxPlayers int     `json:"maxPlayers,omitempty"`
+	Rounds     int     `json:"rounds,omitempty"`
+	BestOf     *int    `json:"bestOf,omitempty"`
+	MaxRounds  *int    `json:"maxRounds,omitempty"`
+	Mode       *string `json:"mode,omitempty"`
+	Ranked     *bool   `json:"ranked,omitempty"`
+}
+
+// Routes dispatches /api/tournaments by method: GET lists (public), POST goes to create,
+// which the caller wraps in AuthMiddleware.
+func (h *TournamentsHandler) Routes(create http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.List(w, r)
+		case http.MethodPost:
+			create.ServeHTTP(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET or POST")
+		}
+	}
+}
+
+// List returns tournaments, newest first. Query: status (registering|running|finished)
+// and limit (default 20, max 100).
+func (h *TournamentsHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", store.TournamentRegistering, store.TournamentRunning, store.TournamentFinished:
+	default:
+		writeError(w, http.StatusBadRequest, "bad_request", "status must be registering, running or finished")
+		return
+	}
+	limit := defaultTournamentLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxTournamentLimit {
+			writeError(w, http.StatusBadRequest, "bad_request", "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	list, err := h.Tournaments.List(r.Context(), status, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load tournaments")
+		return
+	}
+	items := make([]map[string]any, 0, len(list))
+	for _, t := range list {
+		items = append(items, tournamentJSON(t))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// Create creates a tournament open for registration; the creator is not registered automatically.
+func (h *TournamentsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	me, ok := registeredUser(w, r, h.Users, "guest accounts cannot create tournaments")
+	if !ok {
+		return
+	}
+
+	var req CreateTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "invalid json")
+		return
+	}
+	rules := h.Matches.Rules()
+	if req.BestOf != nil {
+		rules.BestOf = *req.BestOf
+	}
+	if req.MaxRounds != nil {
+		rules.MaxRounds = *req.MaxRounds
+	}
+	if req.Mode != nil {
+		rules.Mode = *req.Mode
+	}
+	if req.Ranked != nil {
+		rules.Unranked = !*req.Ranked
+	}
+
+	t, err := tournament.New(tournament.Settings{
+		Name:       req.Name,
+		Format:     req.Format,
+		Rules:      rules,
+		MaxPlayers: req.MaxPlayers,
+		Rounds:     req.Rounds,
+	}, me.ID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_tournament", err.Error())
+		return
+	}
+	if err := h.Tournaments.Create(r.Context(), t); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to create tournament")
+		return
+	}
+	writeJSON(w, http.StatusCreated, tournamentJSON(t))
+}
+
+// Get returns the tournament with its registered players (by seed once started).
+func (h *TournamentsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	t, ok := h.tournament(w, r)
+	if !ok {
+		return
+	}
+	players, err := h.Tournaments.Players(r.Context(), t.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load players")
+		return
+	}
+	items := make([]map[string]any, 0, len(players))
+	for _, p := range players {
+		item := map[string]any{
+			"userId":       p.UserID,
+			"displayName":  p.DisplayName,
+			"registeredAt": p.RegisteredAt,
+		}
+		if p.Seed > 0 {
+			item["seed"] = p.Seed
+		}
+		items = append(items, item)
+	}
+	out := tournamentJSON(t)
+	out["players"] = items
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Registration registers the caller (POST) or withdraws the registration (DELETE).
+// Both are only possible before the tournament starts.
+func (h *TournamentsHandler) Registration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST or DELETE")
+		return
+	}
+	me, ok := registeredUser(w, r, h.Users, "guest accounts cannot play tournaments")
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	if isBadUUID(id) {
+		writeError(w, http.StatusNotFound, "not_found", "tournament not found")
+		return
+	}
+
+	var err error
+	if r.Method == http.MethodPost {
+		err = h.Tournaments.Register(r.Context(), id, me.ID)
+	} else {
+		err = h.Tournaments.Unregister(r.Context(), id, me.ID)
+	}
+	switch {
+	case errors.Is(err, store.ErrTournamentNotFound):
+		writeError(w, http.StatusNotFound, "not_found", "tournament not found")
+	case errors.Is(err, store.ErrTournamentNotRegistering):
+		writeError(w, http.StatusConflict, "registration_closed", "registration is closed")
+	case errors.Is(err, store.ErrTournamentFull):
+		writeError(w, http.StatusConflict, "tournament_full", "tournament is full")
+	case errors.Is(err, store.ErrTournamentRegistered):
+		writeError(w, http.StatusConflict, "already_registered", "already registered")
+	case errors.Is(err, store.ErrTournamentNotRegistered):
+		writeError(w, http.StatusNotFound, "not_registered", "not registered")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "internal", "failed to update registration")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Start closes registration, seeds the players in registration order and creates the
+// first round's matches; the players get a tournament_match notification each.
+func (h *TournamentsHandler) Start(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
+		return
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing auth context")
+		return
+	}
+	id := r.PathValue("id")
+	if isBadUUID(id) {
+		writeError(w, http.StatusNotFound, "not_found", "tournament not found")
+		return
+	}
+
+	t, err := h.Service.Start(r.Context(), id, userID)
+	switch {
+	case errors.Is(err, store.ErrTournamentNotFound):
+		writeError(w, http.StatusNotFound, "not_found", "tournament not found")
+		return
+	case errors.Is(err, store.ErrTournamentNotCreator):
+		writeError(w, http.StatusForbidden, "forbidden", "only the creator can start the tournament")
+		return
+	case errors.Is(err, store.ErrTournamentNotRegistering):
+		writeError(w, http.StatusConflict, "already_started", "tournament has already started")
+		return
+	case errors.Is(err, store.ErrTournamentTooFewPlayers):
+		writeError(w, http.StatusConflict, "too_few_players", "at least two players are needed")
+		return
+	case err != nil && t.ID == "":
+		writeError(w, http.StatusInternalServerError, "internal", "failed to start tournament")
+		return
+	}
+	// err with t set: started, but some matches could not be created; the pairings stand
+	writeJSON(w, http.StatusOK, tournamentJSON(t))
+}
+
+// Standings ranks the players from the finished matches so far.
+func (h *TournamentsHandler) Standings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	t, players, matches, ok := h.progress(w, r)
+	if !ok {
+		return
+	}
+	names := make(map[string]string, len(players))
+	for _, p := range players {
+		names[p.UserID] = p.DisplayName
+	}
+
+	standings := tournament.ComputeStandings(t.Format, seeds(players), tournament.ToPairings(matches))
+	items := make([]map[string]any, 0, len(standings))
+	for _, s := range standings {
+		item := map[string]any{
+			"rank":        s.Rank,
+			"seed":        s.Seed,
+			"userId":      s.UserID,
+			"displayName": names[s.UserID],
+			"points":      s.Points,
+			"wins":        s.Wins,
+			"draws":       s.Draws,
+			"losses":      s.Losses,
+			"byes":        s.Byes,
+		}
+		if t.Format == tournament.FormatSwiss {
+			item["buchholz"] = s.Buchholz
+			item["sonnebornBerger"] = s.SonnebornBerger
+		} else {
+			item["eliminated"] = s.Eliminated
+		}
+		items = append(items, item)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tournament": tournamentJSON(t),
+		"standings":  items,
+	})
+}
+
+// Bracket returns every round paired so far with its boards. In single elimination
+// winner is who advances (on a draw, the better seed).
+func (h *TournamentsHandler) Bracket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	t, players, matches, ok := h.progress(w, r)
+	if !ok {
+		return
+	}
+	names := make(map[string]string, len(players))
+	seedOf := make(map[string]int, len(players))
+	for _, p := range players {
+		names[p.UserID] = p.DisplayName
+		seedOf[p.UserID] = p.Seed
+	}
+	player := func(id string) any {
+		if id == "" {
+			return nil
+		}
+		return map[string]any{"userId": id, "displayName": names[id], "seed": seedOf[id]}
+	}
+
+	// matches come ordered by round and board
+	pairings := tournament.ToPairings(matches)
+	rounds := make([]map[string]any, 0, t.CurrentRound)
+	var boards []map[string]any
+	for i, m := range matches {
+		p := pairings[i]
+		board := map[string]any{
+			"board":  m.Board,
+			"p1":     player(m.P1ID),
+			"p2":     player(m.P2ID),
+			"bye":    p.Bye(),
+			"result": nil,
+			"winner": nil,
+		}
+		if m.MatchID != "" {
+			board["matchId"] = m.MatchID
+		}
+		if p.Finished() {
+			board["result"] = m.Result
+			switch {
+			case t.Format == tournament.FormatSingleElimination:
+				board["winner"] = tournament.Advancer(p, seedOf)
+			case m.Result == tournament.ResultP1:
+				board["winner"] = m.P1ID
+			case m.Result == tournament.ResultP2:
+				board["winner"] = m.P2ID
+			}
+		}
+		boards = append(boards, board)
+		if i == len(matches)-1 || matches[i+1].Round != m.Round {
+			rounds = append(rounds, map[string]any{"round": m.Round, "boards": boards})
+			boards = nil
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tournament": tournamentJSON(t),
+		"rounds":     rounds,
+	})
+}
+
+func (h *TournamentsHandler) tournament(w http.ResponseWriter, r *http.Request) (store.Tournament, bool) {
+	id := r.PathValue("id")
+	if isBadUUID(id) {
+		writeError(w, http.StatusNotFound, "not_found", "tournament not found")
+		return store.Tournament{}, false
+	}
+	t, err := h.Tournaments.Get(r.Context(), id)
+	if errors.Is(err, store.ErrTournamentNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "tournament not found")
+		return store.Tournament{}, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load tournament")
+		return store.Tournament{}, false
+	}
+	return t, true
+}
+
+// progress loads the tournament with its players and matches.
+func (h *TournamentsHandler) progress(w http.ResponseWriter, r *http.Request) (store.Tournament, []store.TournamentPlayer, []store.TournamentMatch, bool) {
+	t, ok := h.tournament(w, r)
+	if !ok {
+		return store.Tournament{}, nil, nil, false
+	}
+	players, err := h.Tournaments.Players(r.Context(), t.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load players")
+		return store.Tournament{}, nil, nil, false
+	}
+	matches, err := h.Tournaments.Matches(r.Context(), t.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load matches")
+		return store.Tournament{}, nil, nil, false
+	}
+	return t, players, matches, true
+}
+
+// seeds lists the user IDs in seed order (registration order before the start).
+func seeds(players []store.TournamentPlayer) []string {
+	out := make([]string, len(players))
+	for i, p := range players {
+		out[i] = p.UserID
+	}
+	return out
+}
+
+func tournamentJSON(t store.Tournament) map[string]any {
+	return map[string]any{
+		"id":           t.ID,
+		"name":         t.Name,
+		"format":       t.Format,
+		"status":       t.Status,
+		"rules":        t.Rules,
+		"maxPlayers":   t.MaxPlayers,
+		"playerCount":  t.PlayerCount,
+		"rounds":       t.Rounds,
+		"currentRound": t.CurrentRound,
+		"createdBy":    t.CreatedBy,
+		"createdAt":    t.CreatedAt,
+		"startedAt":    t.StartedAt,
+		"finishedAt":   t.FinishedAt,
+	}
+}
@@ -0,0 +1,191 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example.com/bc-mvp/internal/presence"
+	"example.com/bc-mvp/internal/store"
+)
+
+const (
+	recentResultsLen  = 10
+	defaultMatchLimit = 20
+	maxMatchLimit     = 100
+)
+
+// UsersHandler serves other players' public profiles: the ids come from
+// playerIds in the match state, so opponents can be looked up after a game.
+type UsersHandler struct {
+	Users        *store.UserStore
+	Stats        *store.StatsStore
+	History      *store.HistoryStore
+	Achievements *store.AchievementStore
+	Presence     *presence.Tracker
+}
+
+// Profile returns a user's public profile: display name, stats and the latest results.
+// Email and other private fields are never included.
+func (h *UsersHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	u, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	st, err := h.Stats.Get(r.Context(), u.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load stats")
+		return
+	}
+	recent, _, err := h.History.MatchHistory(r.Context(), u.ID, "", recentResultsLen)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load match history")
+		return
+	}
+	results := make([]string, 0, len(recent))
+	for _, g := range recent {
+		results = append(results, g.Result)
+	}
+	unlocked, err := h.Achievements.List(r.Context(), u.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load achievements")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":            u.ID,
+		"displayName":   u.DisplayName,
+		"guest":         u.IsGuest,
+		"createdAt":     u.CreatedAt,
+		"stats":         statsJSON(st),
+		"recentResults": results, // newest first
+		"achievements":  achievementsJSON(unlocked),
+	})
+}
+
+// Matches returns a page of the user's finished games, newest first.
+// Query: limit (default 20, max 100) and cursor (nextCursor of the previous page).
+func (h *UsersHandler) Matches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	u, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultMatchLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxMatchLimit {
+			writeError(w, http.StatusBadRequest, "bad_request", "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	games, next, err := h.History.MatchHistory(r.Context(), u.ID, r.URL.Query().Get("cursor"), limit)
+	if errors.Is(err, store.ErrBadCursor) {
+		writeError(w, http.StatusBadRequest, "bad_cursor", "invalid cursor")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load match history")
+		return
+	}
+
+	items := make([]map[string]any, 0, len(games))
+	for _, g := range games {
+		items = append(items, map[string]any{
+			"matchId":    g.MatchID,
+			"gameNo":     g.GameNo,
+			"slot":       g.Slot,
+			"team":       g.Team,
+			"rank":       g.Rank,
+			"result":     g.Result,
+			"reason":     g.Reason,
+			"ranked":     g.Ranked,
+			"rounds":     g.Rounds,
+			"finishedAt": g.FinishedAt,
+			"opponents":  participantsJSON(g.Opponents),
+			"teammates":  participantsJSON(g.Teammates),
+		})
+	}
+	resp := map[string]any{"items": items, "nextCursor": nil}
+	if next != "" {
+		resp["nextCursor"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PresenceStatus returns whether the user is offline, online, idle, waiting for an opponent or playing.
+// The match itself is not disclosed.
+func (h *UsersHandler) PresenceStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+		return
+	}
+	u, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := h.Presence.Get(r.Context(), u.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load presence")
+		return
+	}
+	var lastSeen any
+	if !p.LastSeen.IsZero() {
+		lastSeen = p.LastSeen.UTC()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"userId":     u.ID,
+		"status":     p.Status,
+		"lastSeenAt": lastSeen,
+	})
+}
+
+// user loads the user from the {id} path segment; writes 404 if there is none.
+func (h *UsersHandler) user(w http.ResponseWriter, r *http.Request) (store.User, bool) {
+	id := r.PathValue("id")
+	if isBadUUID(id) {
+		writeError(w, http.StatusNotFound, "not_found", "user not found")
+		return store.User{}, false
+	}
+	u, err := h.Users.GetByID(r.Context(), id)
+	if errors.Is(err, store.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "user not found")
+		return store.User{}, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal", "failed to load user")
+		return store.User{}, false
+	}
+	return u, true
+}
+
+// participantsJSON: userId and displayName are null for deleted accounts.
+func participantsJSON(ps []store.Participant) []map[string]any {
+	out := make([]map[string]any, 0, len(ps))
+	for _, p := range ps {
+		var userID, name any
+		if p.UserID != "" {
+			userID, name = p.UserID, p.DisplayName
+		}
+		out = append(out, map[string]any{
+			"userId":      userID,
+			"displayName": name,
+			"slot":        p.Slot,
+			"team":        p.Team,
+			"rank":        p.Rank,
+		})
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+// Package mail delivers transactional emails through a transactional outbox:
+// handlers enqueue messages in the same database transaction as the state change,
+// and Sender delivers them in the background through a Mailer.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Message is one email in the outbox.
+type Message struct {
+	ID       int64 // outbox id, 0 before it is stored
+	To       string
+	Subject  string
+	Body     string // plain text
+	Attempts int    // delivery attempts so far, including the current one
+}
+
+// Mailer delivers a message. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, from string, m Message) error
+}
+
+// LogMailer logs that a message would be sent (development). The body is not logged:
+// it carries password reset and verification links. Use FileMailer to read them.
+type LogMailer struct {
+	Log *slog.Logger
+}
+
+func (l LogMailer) Send(_ context.Context, from string, m Message) error {
+	l.Log.Info("mail", "from", from, "to", m.To, "subject", m.Subject)
+	return nil
+}
+
+// SMTPMailer sends messages through an SMTP relay (production). net/smtp upgrades
+// to STARTTLS when the server offers it and refuses PLAIN auth over an unencrypted connection.
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string // optional: PLAIN auth
+	Password string
+}
+
+func (s SMTPMailer) Send(_ context.Context, from string, m Message) error {
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("MAIL_FROM: %w", err)
+	}
+	var a smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		a = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	return smtp.SendMail(s.Addr, a, sender.Address, []string{m.To}, format(from, m))
+}
+
+// FileMailer writes every message to Dir as an .eml file (development, e2e tests).
+type FileMailer struct {
+	Dir string
+
+	seq atomic.Int64
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{Dir: dir}, nil
+}
+
+func (f *FileMailer) Send(_ context.Context, from string, m Message) error {
+	name := fmt.Sprintf("%s-%d-%d.eml", time.Now().UTC().Format("20060102T150405"), m.ID, f.seq.Add(1))
+	return os.WriteFile(filepath.Join(f.Dir, name), format(from, m), 0o644)
+}
+
+// format renders the message as a plain text RFC 5322 email.
+func format(from string, m Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		from, m.To, m.Subject, m.Body)
+	return []byte(b.String())
+}
+
+// PasswordReset builds the password reset email.
+func PasswordReset(to, link string, ttl time.Duration) Message {
+	return Message{
+		To:      to,
+		Subject: "Reset your Bulls & Cows password",
+		Body: fmt.Sprintf("Someone asked to reset the password for this account.\n\n"+
+			"Open this link to choose a new password (valid for %s):\n%s\n\n"+
+			"If it wasn't you, ignore this email: your password stays the same.", ttl, link),
+	}
+}
+
+// VerifyEmail builds the email address confirmation email.
+func VerifyEmail(to, link string, ttl time.Duration) Message {
+	return Message{
+		To:      to,
+		Subject: "Confirm your email for Bulls & Cows",
+		Body:    fmt.Sprintf("Open this link to confirm your email address (valid for %s):\n%s", ttl, link),
+	}
+}
+
+// YourMove builds the reminder that a correspondence match is waiting for the player's move.
+func YourMove(to, link string, round int, deadline time.Time) Message {
+	by := ""
+	if !deadline.IsZero() {
+		by = fmt.Sprintf(" Move by %s, or the round counts as missed.", deadline.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	return Message{
+		To:      to,
+		Subject: "Your move in Bulls & Cows",
+		Body:    fmt.Sprintf("Round %d of your correspondence match is waiting for you.%s\n\nOpen the match:\n%s", round, by, link),
+	}
+}
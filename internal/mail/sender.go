@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Outbox is the storage side of the transactional outbox (implemented by store.OutboxStore).
+type Outbox interface {
+	// Claim leases up to limit due messages so that other instances skip them for a while.
+	Claim(ctx context.Context, limit int) ([]Message, error)
+	MarkSent(ctx context.Context, id int64) error
+	// MarkFailed records the error and schedules a retry (or gives up after too many attempts).
+	MarkFailed(ctx context.Context, id int64, attempts int, sendErr error) error
+}
+
+// Sender polls the outbox and delivers messages through a Mailer.
+type Sender struct {
+	Outbox   Outbox
+	Mailer   Mailer
+	From     string
+	Interval time.Duration // poll interval when the outbox is empty
+	Batch    int
+	Log      *slog.Logger
+}
+
+// Run delivers messages until ctx is done.
+func (s *Sender) Run(ctx context.Context) error {
+	t := time.NewTicker(s.Interval)
+	defer t.Stop()
+	for {
+		// drain everything that is due before sleeping again
+		for {
+			n, err := s.deliver(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.Log.Error("mail outbox", "err", err)
+			}
+			if err != nil || n < s.Batch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Sender) deliver(ctx context.Context) (int, error) {
+	msgs, err := s.Outbox.Claim(ctx, s.Batch)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range msgs {
+		if err := s.Mailer.Send(ctx, s.From, m); err != nil {
+			s.Log.Warn("mail send failed", "id", m.ID, "attempt", m.Attempts, "err", err)
+			if err := s.Outbox.MarkFailed(ctx, m.ID, m.Attempts, err); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err := s.Outbox.MarkSent(ctx, m.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(msgs), nil
+}
@@ -0,0 +1,109 @@
+// Package notify delivers out-of-match events (friend requests, challenges) to users in real time.
+//
+// The hub is in-memory: a user receives an event only on connections to the instance that
+// published it, the same way match WebSockets live on the instance that holds the match.
+// Delivery is best effort — an event for a user with no open connection, or whose buffer
+// is full, is dropped; clients reload the underlying state over HTTP after reconnecting.
+package notify
+
+import (
+	"sync"
+)
+
+// bufferSize is how many undelivered events a single connection may lag behind.
+const bufferSize = 16
+
+// Event is sent to clients as {"type": ..., "payload": ...}.
+type Event struct {
+	Type    string `json:"type"`
+	Payload any    `json:"payload,omitempty"`
+}
+
+// Hub fans events out to every open connection of a user.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[*Subscription]struct{})}
+}
+
+// Subscription is one open connection. Events arrive on C until Close.
+type Subscription struct {
+	C <-chan Event
+
+	hub    *Hub
+	userID string
+	ch     chan Event
+	once   sync.Once
+}
+
+// Subscribe opens a subscription for the user; the caller must Close it.
+func (h *Hub) Subscribe(userID string) *Subscription {
+	ch := make(chan Event, bufferSize)
+	sub := &Subscription{C: ch, hub: h, userID: userID, ch: ch}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+// Publish sends ev to all of the user's connections without blocking
+// and returns how many received it.
+func (h *Hub) Publish(userID string, ev Event) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for sub := range h.subs[userID] {
+		select {
+		case sub.ch <- ev:
+			n++
+		default: // slow reader: drop rather than stall the publisher
+		}
+	}
+	return n
+}
+
+// Online reports whether the user has at least one open connection.
+func (h *Hub) Online(userID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[userID]) > 0
+}
+
+// Shutdown closes every subscription, which ends the streams reading them.
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	var all []*Subscription
+	for _, subs := range h.subs {
+		for sub := range subs {
+			all = append(all, sub)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, sub := range all {
+		sub.Close()
+	}
+}
+
+// Close unsubscribes and closes C. Safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[s.userID], s)
+		if len(h.subs[s.userID]) == 0 {
+			delete(h.subs, s.userID)
+		}
+		// under h.mu, so Publish never sends on a closed channel
+		close(s.ch)
+	})
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "event reaches every connection of the user and nobody else",
+			run: func(t *testing.T) {
+				h := NewHub()
+				a1, a2, b := h.Subscribe("alice"), h.Subscribe("alice"), h.Subscribe("bob")
+				defer a1.Close()
+				defer a2.Close()
+				defer b.Close()
+
+				assert.Equal(t, 2, h.Publish("alice", Event{Type: "challenge"}))
+				assert.Equal(t, "challenge", (<-a1.C).Type)
+				assert.Equal(t, "challenge", (<-a2.C).Type)
+				assert.Empty(t, b.C)
+			},
+		},
+		{
+			name: "full buffer drops instead of blocking",
+			run: func(t *testing.T) {
+				h := NewHub()
+				s := h.Subscribe("alice")
+				defer s.Close()
+
+				for range bufferSize {
+					require.Equal(t, 1, h.Publish("alice", Event{Type: "x"}))
+				}
+				assert.Equal(t, 0, h.Publish("alice", Event{Type: "x"}))
+			},
+		},
+		{
+			name: "closed subscription is removed",
+			run: func(t *testing.T) {
+				h := NewHub()
+				s := h.Subscribe("alice")
+				assert.True(t, h.Online("alice"))
+
+				s.Close()
+				s.Close()
+				_, ok := <-s.C
+				assert.False(t, ok)
+				assert.False(t, h.Online("alice"))
+				assert.Equal(t, 0, h.Publish("alice", Event{Type: "x"}))
+			},
+		},
+		{
+			name: "shutdown closes all subscriptions",
+			run: func(t *testing.T) {
+				h := NewHub()
+				a, b := h.Subscribe("alice"), h.Subscribe("bob")
+
+				h.Shutdown()
+				_, ok := <-a.C
+				assert.False(t, ok)
+				_, ok = <-b.C
+				assert.False(t, ok)
+				b.Close() // still safe after shutdown
+				assert.False(t, h.Online("alice"))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
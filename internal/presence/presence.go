@@ -0,0 +1,157 @@
+// Package presence tracks whether users are online, waiting for an opponent or playing.
+//
+// Every open WebSocket (or SSE stream) is a session stored in Redis under the user's key,
+// so any instance can answer for users connected to any other. Sessions carry their own
+// expiry and are refreshed by the connection's heartbeat; a crashed instance's sessions
+// simply expire. The public status is derived from the live sessions (see Aggregate).
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Session states, reported by the connection.
+const (
+	StateOnline  = "online"   // user channel open (/ws/user, notification stream)
+	StateInQueue = "in_queue" // in a match that is still waiting for opponents
+	StateInMatch = "in_match" // in a match that has started
+)
+
+// Public statuses.
+const (
+	StatusOffline = "offline"
+	StatusOnline  = "online"
+	StatusIdle    = "idle" // connected, but no client activity for IdleAfter
+	StatusInQueue = "in_queue"
+	StatusInMatch = "in_match"
+)
+
+// seenTTL is how long "last seen" is remembered after the last session ends.
+const seenTTL = 30 * 24 * time.Hour
+
+// Session is one open connection.
+type Session struct {
+	State     string    `json:"state"`
+	ActiveAt  time.Time `json:"activeAt"`  // last message from the client
+	ExpiresAt time.Time `json:"expiresAt"` // dropped unless refreshed before
+}
+
+// Presence is what other users see.
+type Presence struct {
+	Status   string
+	LastSeen time.Time // zero if the user was never seen (or not within seenTTL)
+}
+
+type Tracker struct {
+	rdb       *redis.Client
+	ttl       time.Duration
+	idleAfter time.Duration
+}
+
+// NewTracker: ttl is how long a session lives without a refresh (several heartbeats),
+// idleAfter is how long without client activity turns online into idle.
+func NewTracker(rdb *redis.Client, ttl, idleAfter time.Duration) *Tracker {
+	return &Tracker{rdb: rdb, ttl: ttl, idleAfter: idleAfter}
+}
+
+// Set creates or refreshes a session.
+func (t *Tracker) Set(ctx context.Context, userID, connID, state string, activeAt time.Time) error {
+	now := time.Now()
+	b, err := json.Marshal(Session{State: state, ActiveAt: activeAt, ExpiresAt: now.Add(t.ttl)})
+	if err != nil {
+		return err
+	}
+	pipe := t.rdb.TxPipeline()
+	pipe.HSet(ctx, connsKey(userID), connID, b)
+	pipe.Expire(ctx, connsKey(userID), t.ttl)
+	pipe.Set(ctx, seenKey(userID), now.UnixMilli(), seenTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Remove ends a session.
+func (t *Tracker) Remove(ctx context.Context, userID, connID string) error {
+	pipe := t.rdb.TxPipeline()
+	pipe.HDel(ctx, connsKey(userID), connID)
+	pipe.Set(ctx, seenKey(userID), time.Now().UnixMilli(), seenTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Get returns the user's current presence.
+func (t *Tracker) Get(ctx context.Context, userID string) (Presence, error) {
+	pipe := t.rdb.Pipeline()
+	conns := pipe.HGetAll(ctx, connsKey(userID))
+	seen := pipe.Get(ctx, seenKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return Presence{}, err
+	}
+
+	now := time.Now()
+	var (
+		live    []Session
+		expired []string
+	)
+	for connID, raw := range conns.Val() {
+		var s Session
+		if json.Unmarshal([]byte(raw), &s) != nil || !now.Before(s.ExpiresAt) {
+			expired = append(expired, connID)
+			continue
+		}
+		live = append(live, s)
+	}
+	if len(expired) > 0 {
+		// sessions of connections that died without Remove (instance crash)
+		_ = t.rdb.HDel(ctx, connsKey(userID), expired...).Err()
+	}
+
+	p := Presence{Status: Aggregate(live, now, t.idleAfter)}
+	if ms, err := strconv.ParseInt(seen.Val(), 10, 64); err == nil {
+		p.LastSeen = time.UnixMilli(ms)
+	}
+	if p.Status != StatusOffline {
+		p.LastSeen = now
+	}
+	return p, nil
+}
+
+// Aggregate derives the public status from live sessions: playing beats waiting,
+// waiting beats merely online; online turns idle when no session saw client activity
+// for idleAfter.
+func Aggregate(sessions []Session, now time.Time, idleAfter time.Duration) string {
+	if len(sessions) == 0 {
+		return StatusOffline
+	}
+	var inMatch, inQueue bool
+	var active time.Time
+	for _, s := range sessions {
+		switch s.State {
+		case StateInMatch:
+			inMatch = true
+		case StateInQueue:
+			inQueue = true
+		}
+		if s.ActiveAt.After(active) {
+			active = s.ActiveAt
+		}
+	}
+	switch {
+	case inMatch:
+		return StatusInMatch
+	case inQueue:
+		return StatusInQueue
+	case idleAfter > 0 && now.Sub(active) >= idleAfter:
+		return StatusIdle
+	default:
+		return StatusOnline
+	}
+}
+
+func connsKey(userID string) string { return "presence:conns:" + userID }
+func seenKey(userID string) string  { return "presence:seen:" + userID }
@@ -0,0 +1,36 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	idle := 5 * time.Minute
+	s := func(state string, ago time.Duration) Session {
+		return Session{State: state, ActiveAt: now.Add(-ago)}
+	}
+
+	cases := []struct {
+		name     string
+		sessions []Session
+		want     string
+	}{
+		{name: "no sessions", want: StatusOffline},
+		{name: "user channel", sessions: []Session{s(StateOnline, time.Minute)}, want: StatusOnline},
+		{name: "no activity for idleAfter", sessions: []Session{s(StateOnline, idle)}, want: StatusIdle},
+		{name: "one active tab keeps the user online", sessions: []Session{s(StateOnline, time.Hour), s(StateOnline, time.Second)}, want: StatusOnline},
+		{name: "waiting for an opponent", sessions: []Session{s(StateOnline, time.Minute), s(StateInQueue, time.Minute)}, want: StatusInQueue},
+		{name: "playing beats waiting", sessions: []Session{s(StateInQueue, 0), s(StateInMatch, 0)}, want: StatusInMatch},
+		{name: "a quiet player is still in the match", sessions: []Session{s(StateInMatch, time.Hour)}, want: StatusInMatch},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Aggregate(tc.sessions, now, idle))
+		})
+	}
+}
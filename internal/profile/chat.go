@@ -0,0 +1,43 @@
+package profile
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxChatMessageLen is the longest in-match chat message, in characters.
+const MaxChatMessageLen = 200
+
+// NormalizeChatMessage trims the message and collapses all whitespace, newlines included,
+// to single spaces.
+func NormalizeChatMessage(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// CensorChatMessage masks every word that starts with a listed word with asterisks.
+// Unlike display names, chat is checked word by word, so innocent words are not
+// masked because of their neighbours; look-alikes and separators inside a word
+// ("f.u_c-k") are still caught.
+func CensorChatMessage(text string) string {
+	var b strings.Builder
+	for len(text) > 0 {
+		i := strings.IndexFunc(text, unicode.IsSpace)
+		if i < 0 {
+			i = len(text)
+		}
+		word := text[:i]
+		if word != "" && isProfane(word) {
+			word = strings.Repeat("*", utf8.RuneCountInString(word))
+		}
+		b.WriteString(word)
+		text = text[i:]
+
+		// copy the separator as is
+		if r, size := utf8.DecodeRuneInString(text); size > 0 && unicode.IsSpace(r) {
+			b.WriteRune(r)
+			text = text[size:]
+		}
+	}
+	return b.String()
+}
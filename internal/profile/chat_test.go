@@ -0,0 +1,29 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCensorChatMessage(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "clean message", input: "good game, well played", want: "good game, well played"},
+		{name: "listed word", input: "oh shit again", want: "oh **** again"},
+		{name: "look-alikes and separators", input: "f.u_c-k this", want: "******* this"},
+		{name: "punctuation stays part of the word", input: "shit!", want: "*****"},
+		{name: "cyrillic", input: "ну ты сука", want: "ну ты ****"},
+		{name: "words are checked separately", input: "hit lerp", want: "hit lerp"},
+		{name: "listed word inside another word", input: "up the Scunthorpe", want: "up the Scunthorpe"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, CensorChatMessage(NormalizeChatMessage(tc.input)))
+		})
+	}
+}
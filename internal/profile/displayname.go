@@ -0,0 +1,102 @@
+// Package profile holds user profile rules shared by the HTTP handlers.
+package profile
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Display name limits.
+const (
+	MinDisplayNameLen = 3
+	MaxDisplayNameLen = 24
+)
+
+var (
+	ErrDisplayNameLength  = fmt.Errorf("display name must be %d to %d characters", MinDisplayNameLen, MaxDisplayNameLen)
+	ErrDisplayNameChars   = errors.New("display name may contain only letters, digits, spaces, '_', '-' and '.'")
+	ErrDisplayNameProfane = errors.New("display name is not allowed")
+)
+
+//go:embed data/profanity.txt
+var profanityRaw string
+
+var profanity = parseList(profanityRaw)
+
+// lookalikes maps characters commonly used to dodge filters onto the letters they imitate.
+var lookalikes = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b",
+	"@", "a", "$", "s", "!", "i", "ё", "е",
+)
+
+// NormalizeDisplayName trims the name and collapses inner whitespace to single spaces.
+func NormalizeDisplayName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ValidateDisplayName checks an already normalized display name.
+func ValidateDisplayName(name string) error {
+	if n := utf8.RuneCountInString(name); n < MinDisplayNameLen || n > MaxDisplayNameLen {
+		return ErrDisplayNameLength
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" _-.", r) {
+			return ErrDisplayNameChars
+		}
+	}
+	if isProfane(name) {
+		return ErrDisplayNameProfane
+	}
+	return nil
+}
+
+// isProfane looks for a listed word starting at a word boundary once separators are removed
+// and look-alike characters are mapped back ("F.u_c-k" and "5h1t" both match).
+// A word starts the name, follows a separator or is a camel-case hump ("AliceShit").
+// Listed words inside other words are fine, so "Scunthorpe" and "Matsushita" pass.
+func isProfane(name string) bool {
+	var (
+		compact []rune
+		starts  []int
+		prev    rune
+		sep     = true
+	)
+	for _, r := range name {
+		if strings.ContainsRune(" _-.", r) {
+			sep = true
+			continue
+		}
+		if sep || (unicode.IsUpper(r) && !unicode.IsUpper(prev)) {
+			starts = append(starts, len(compact))
+		}
+		compact = append(compact, unicode.ToLower(r))
+		prev, sep = r, false
+	}
+	normalized := []rune(lookalikes.Replace(string(compact)))
+
+	for _, i := range starts {
+		rest := string(normalized[i:])
+		for w := range profanity {
+			if strings.HasPrefix(rest, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseList(raw string) map[string]struct{} {
+	out := make(map[string]struct{})
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out[lookalikes.Replace(strings.ToLower(line))] = struct{}{}
+	}
+	return out
+}
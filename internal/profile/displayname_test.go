@@ -0,0 +1,37 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDisplayName(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{name: "plain name", input: "Alice", want: nil},
+		{name: "cyrillic and separators", input: "Бык_и-Корова 2.0", want: nil},
+		{name: "too short", input: "Al", want: ErrDisplayNameLength},
+		{name: "too long", input: strings.Repeat("я", MaxDisplayNameLen+1), want: ErrDisplayNameLength},
+		{name: "forbidden characters", input: "<script>", want: ErrDisplayNameChars},
+		{name: "profanity", input: "ShitHead", want: ErrDisplayNameProfane},
+		{name: "profanity behind separators and look-alikes", input: "f.u_c-k3r", want: ErrDisplayNameProfane},
+		{name: "cyrillic profanity", input: "Сука99", want: ErrDisplayNameProfane},
+		{name: "profanity after a separator", input: "alice_fuck", want: ErrDisplayNameProfane},
+		{name: "profanity in a camel-case hump", input: "AliceShit", want: ErrDisplayNameProfane},
+		{name: "listed word inside a town name", input: "Scunthorpe", want: nil},
+		{name: "listed word inside a surname", input: "Matsushita", want: nil},
+		{name: "listed word inside a cyrillic word", input: "Барсука", want: nil},
+		{name: "listed word across two words", input: "Ash Itch", want: nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ValidateDisplayName(NormalizeDisplayName(tc.input)))
+		})
+	}
+}
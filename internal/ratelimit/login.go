@@ -0,0 +1,149 @@
+// Package ratelimit slows down password guessing on /api/auth/login.
+//
+// Failed attempts are counted per client IP and per account (email) in Redis, so the limits
+// hold across instances. Past a number of free failures every further failure blocks the key
+// for an exponentially growing delay; past LockoutAfter the key is locked for LockoutFor.
+// An attempt is counted (and the block it would earn is set) before the password is checked,
+// so parallel guesses cannot slip past the limit; a successful login takes it back.
+// A successful login clears the account counter (the IP counter keeps running:
+// one valid account must not reset the budget for guessing others).
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Policy configures one dimension (IP or account).
+type Policy struct {
+	Free         int           // failures allowed without delay
+	BaseDelay    time.Duration // delay after the first failure past Free, doubled for each next one
+	MaxDelay     time.Duration
+	LockoutAfter int // failures that lock the key for LockoutFor (0 => never)
+	LockoutFor   time.Duration
+	Window       time.Duration // failure counter lifetime since the first failure
+}
+
+// Delay returns how long the key is blocked after the given number of failures.
+func (p Policy) Delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutFor
+	}
+	if failures <= p.Free {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := p.Free + 1; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+// LoginLimiter tracks failed logins per IP and per account.
+type LoginLimiter struct {
+	rdb     *redis.Client
+	ip      Policy
+	account Policy
+}
+
+func NewLoginLimiter(rdb *redis.Client, ip, account Policy) *LoginLimiter {
+	return &LoginLimiter{rdb: rdb, ip: ip, account: account}
+}
+
+// Reservation is an attempt counted by Reserve before the password is checked.
+type Reservation struct {
+	ip, account string
+	ipLocked    bool          // the reservation itself locked the IP; undone on success
+	block       time.Duration // block that applies if the attempt fails
+}
+
+// reserveScript checks both locks, and if neither is set counts the attempt against both keys
+// and sets the lock a failure would earn, all in one step: concurrent guesses cannot all pass
+// the check before the first failure is recorded.
+// KEYS: lock and fail key per dimension; ARGV[1]: JSON [[windowMs, [delayMs for 1, 2, ... failures]], ...].
+var reserveScript = redis.NewScript(`
+local dims = cjson.decode(ARGV[1])
+local wait = 0
+for i = 1, #dims do
+	local t = redis.call('PTTL', KEYS[2*i-1])
+	if t > wait then wait = t end
+end
+if wait > 0 then return {wait} end
+local out = {0}
+for i, dim in ipairs(dims) do
+	local n = redis.call('INCR', KEYS[2*i])
+	redis.call('PEXPIRE', KEYS[2*i], dim[1], 'NX')
+	local d = dim[2][math.min(n, #dim[2])]
+	if d > 0 then redis.call('SET', KEYS[2*i-1], 1, 'PX', d) end
+	table.insert(out, d)
+end
+return out
+`)
+
+// successScript clears the account and takes the reserved attempt back from the IP.
+// KEYS: ip lock, ip fail, account fail, account lock; ARGV[1]: "1" if the reservation locked the IP.
+var successScript = redis.NewScript(`
+redis.call('DEL', KEYS[3], KEYS[4])
+if redis.call('EXISTS', KEYS[2]) == 1 then redis.call('DECR', KEYS[2]) end
+if ARGV[1] == '1' then redis.call('DEL', KEYS[1]) end
+return 0
+`)
+
+// Reserve counts an attempt as failed before the password is checked; Success takes it back.
+// It returns how long the caller has to wait instead (wait > 0: nothing was counted).
+func (l *LoginLimiter) Reserve(ctx context.Context, ip, account string) (Reservation, time.Duration, error) {
+	dims, err := json.Marshal([]any{l.ip.table(), l.account.table()})
+	if err != nil {
+		return Reservation{}, 0, err
+	}
+	keys := []string{lockKey("ip", ip), failKey("ip", ip), lockKey("acct", account), failKey("acct", account)}
+	out, err := reserveScript.Run(ctx, l.rdb, keys, dims).Int64Slice()
+	if err != nil {
+		return Reservation{}, 0, err
+	}
+	if out[0] > 0 {
+		return Reservation{}, time.Duration(out[0]) * time.Millisecond, nil
+	}
+	ipBlock, accBlock := time.Duration(out[1])*time.Millisecond, time.Duration(out[2])*time.Millisecond
+	return Reservation{ip: ip, account: account, ipLocked: ipBlock > 0, block: max(ipBlock, accBlock)}, 0, nil
+}
+
+// Fail confirms a reserved attempt as failed and returns how long the next one is blocked (0 => not yet).
+func (l *LoginLimiter) Fail(r Reservation) time.Duration {
+	return r.block
+}
+
+// Success clears the account's failures and block and takes the attempt back from the IP.
+func (l *LoginLimiter) Success(ctx context.Context, r Reservation) error {
+	ipLocked := "0"
+	if r.ipLocked {
+		ipLocked = "1"
+	}
+	keys := []string{lockKey("ip", r.ip), failKey("ip", r.ip), failKey("acct", r.account), lockKey("acct", r.account)}
+	return successScript.Run(ctx, l.rdb, keys, ipLocked).Err()
+}
+
+// table is [windowMs, [Delay(1), Delay(2), ...]] for reserveScript, up to the failure count
+// from which the delay no longer changes.
+func (p Policy) table() []any {
+	var delays []int64
+	for n := 1; n <= maxTableLen; n++ {
+		d := p.Delay(n)
+		delays = append(delays, d.Milliseconds())
+		locked := p.LockoutAfter > 0 && n >= p.LockoutAfter
+		capped := p.LockoutAfter == 0 && n > p.Free && d >= p.MaxDelay
+		if locked || capped {
+			break
+		}
+	}
+	return []any{p.Window.Milliseconds(), delays}
+}
+
+// maxTableLen bounds the delay table for policies that never settle (e.g. lockout far away).
+const maxTableLen = 1000
+
+func failKey(kind, id string) string { return "login:fail:" + kind + ":" + id }
+func lockKey(kind, id string) string { return "login:lock:" + kind + ":" + id }
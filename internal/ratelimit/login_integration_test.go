@@ -0,0 +1,99 @@
+//go:build integration
+
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, rdb.Ping(ctx).Err(), "redis is not reachable")
+	require.NoError(t, rdb.FlushDB(ctx).Err())
+	return rdb
+}
+
+func TestLoginLimiter(t *testing.T) {
+	ctx := context.Background()
+	ipPolicy := Policy{Free: 100, BaseDelay: time.Second, MaxDelay: time.Minute, Window: time.Hour}
+	accPolicy := Policy{Free: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, LockoutAfter: 10, LockoutFor: time.Hour, Window: time.Hour}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T, l *LoginLimiter)
+	}{
+		{
+			name: "concurrent guesses share one budget",
+			run: func(t *testing.T, l *LoginLimiter) {
+				const attempts = 50
+				var (
+					wg      sync.WaitGroup
+					mu      sync.Mutex
+					allowed int
+				)
+				for range attempts {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, wait, err := l.Reserve(ctx, "1.2.3.4", "alice@example.com")
+						assert.NoError(t, err)
+						if wait == 0 {
+							mu.Lock()
+							allowed++
+							mu.Unlock()
+						}
+					}()
+				}
+				wg.Wait()
+				// three free attempts and the one that earns the first delay
+				assert.Equal(t, accPolicy.Free+1, allowed)
+			},
+		},
+		{
+			name: "success takes the attempt back",
+			run: func(t *testing.T, l *LoginLimiter) {
+				for range accPolicy.Free {
+					r, wait, err := l.Reserve(ctx, "1.2.3.4", "alice@example.com")
+					require.NoError(t, err)
+					require.Zero(t, wait)
+					assert.Zero(t, l.Fail(r))
+				}
+				r, wait, err := l.Reserve(ctx, "1.2.3.4", "alice@example.com")
+				require.NoError(t, err)
+				require.Zero(t, wait)
+				assert.Equal(t, time.Minute, l.Fail(r), "a failure here would block the account")
+
+				require.NoError(t, l.Success(ctx, r))
+				_, wait, err = l.Reserve(ctx, "1.2.3.4", "alice@example.com")
+				require.NoError(t, err)
+				assert.Zero(t, wait)
+				n, err := l.rdb.Get(ctx, failKey("ip", "1.2.3.4")).Int()
+				require.NoError(t, err)
+				assert.Equal(t, accPolicy.Free+1, n, "the successful attempt is not counted against the IP")
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rdb := newRedisClient(t)
+			tc.run(t, NewLoginLimiter(rdb, ipPolicy, accPolicy))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{Free: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second, LockoutAfter: 8, LockoutFor: time.Hour}
+
+	cases := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{name: "free attempts", failures: 3, want: 0},
+		{name: "first delayed failure", failures: 4, want: time.Second},
+		{name: "delay doubles", failures: 6, want: 4 * time.Second},
+		{name: "delay keeps doubling below the cap", failures: 7, want: 8 * time.Second},
+		{name: "lockout", failures: 8, want: time.Hour},
+		{name: "still locked out", failures: 20, want: time.Hour},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, p.Delay(tc.failures))
+		})
+	}
+
+	noLockout := Policy{Free: 0, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	assert.Equal(t, 10*time.Second, noLockout.Delay(100))
+}
+
+func TestPolicy_Table(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy Policy
+		want   []int64
+	}{
+		{
+			name:   "ends at the lockout",
+			policy: Policy{Free: 2, BaseDelay: time.Second, MaxDelay: 10 * time.Second, LockoutAfter: 5, LockoutFor: time.Hour, Window: time.Minute},
+			want:   []int64{0, 0, 1000, 2000, 3600000},
+		},
+		{
+			name:   "ends at the cap without lockout",
+			policy: Policy{Free: 1, BaseDelay: time.Second, MaxDelay: 3 * time.Second, Window: time.Minute},
+			want:   []int64{0, 1000, 2000, 3000},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, []any{int64(60000), tc.want}, tc.policy.table())
+		})
+	}
+}
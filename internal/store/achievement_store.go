@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UnlockedAchievement — открытое достижение пользователя. ID — из каталога internal/achievements.
+type UnlockedAchievement struct {
+	ID         string
+	MatchID    string // "" — матч неизвестен
+	UnlockedAt time.Time
+}
+
+type AchievementStore struct {
+	db *pgxpool.Pool
+}
+
+func NewAchievementStore(db *pgxpool.Pool) *AchievementStore {
+	return &AchievementStore{db: db}
+}
+
+// List — достижения пользователя в порядке открытия.
+func (s *AchievementStore) List(ctx context.Context, userID string) ([]UnlockedAchievement, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT achievement, COALESCE(match_id, ''), unlocked_at
+		FROM user_achievements
+		WHERE user_id = $1
+		ORDER BY unlocked_at, achievement
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []UnlockedAchievement
+	for rows.Next() {
+		var a UnlockedAchievement
+		if err := rows.Scan(&a.ID, &a.MatchID, &a.UnlockedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// Unlock открывает достижения ids и возвращает те, что открыты только что
+// (уже открытые не трогаем: повторная запись той же партии — no-op).
+func (s *AchievementStore) Unlock(ctx context.Context, userID, matchID string, ids []string) ([]UnlockedAchievement, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := s.db.Query(ctx, `
+		INSERT INTO user_achievements (user_id, achievement, match_id)
+		SELECT $1, a, $2 FROM unnest($3::text[]) AS a
+		ON CONFLICT (user_id, achievement) DO NOTHING
+		RETURNING achievement, COALESCE(match_id, ''), unlocked_at
+	`, userID, nullString(matchID), ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []UnlockedAchievement
+	for rows.Next() {
+		var a UnlockedAchievement
+		if err := rows.Scan(&a.ID, &a.MatchID, &a.UnlockedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
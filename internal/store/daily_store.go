@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DailyResult — попытка пользователя в ежедневной головоломке.
+type DailyResult struct {
+	Play       game.DailyPlay
+	StartedAt  time.Time  // zero — ещё не начинал
+	FinishedAt *time.Time // nil — ещё играет
+}
+
+// DailyEntry — строка таблицы лидеров дня.
+type DailyEntry struct {
+	Rank        int
+	UserID      string
+	DisplayName string
+	Rounds      int
+	Duration    time.Duration // от первой догадки до отгадки
+	FinishedAt  time.Time
+}
+
+type DailyStore struct {
+	db *pgxpool.Pool
+}
+
+func NewDailyStore(db *pgxpool.Pool) *DailyStore {
+	return &DailyStore{db: db}
+}
+
+// Get — попытка пользователя за день; если он ещё не играл — пустая.
+func (s *DailyStore) Get(ctx context.Context, day, userID string) (DailyResult, error) {
+	res, err := scanDaily(day, s.db.QueryRow(ctx, `
+		SELECT history, solved, started_at, finished_at
+		FROM daily_results
+		WHERE day = $1 AND user_id = $2
+	`, day, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DailyResult{Play: game.DailyPlay{Day: day, History: []game.RoundHistoryItem{}}}, nil
+	}
+	return res, err
+}
+
+// List — все попытки пользователя, от старых к новым (выгрузка данных).
+func (s *DailyStore) List(ctx context.Context, userID string) ([]DailyResult, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), history, solved, started_at, finished_at
+		FROM daily_results
+		WHERE user_id = $1
+		ORDER BY day
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DailyResult
+	for rows.Next() {
+		var (
+			res     DailyResult
+			history []byte
+		)
+		if err := rows.Scan(&res.Play.Day, &history, &res.Play.Solved, &res.StartedAt, &res.FinishedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(history, &res.Play.History); err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	return out, rows.Err()
+}
+
+// Play применяет move к попытке пользователя под блокировкой строки
+// (параллельные догадки одного пользователя выполняются по очереди) и сохраняет результат.
+// Ошибка move откатывает транзакцию и возвращается как есть.
+func (s *DailyStore) Play(ctx context.Context, day, userID string, move func(p *game.DailyPlay) error) (DailyResult, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return DailyResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO daily_results (day, user_id) VALUES ($1, $2)
+		ON CONFLICT (day, user_id) DO NOTHING
+	`, day, userID); err != nil {
+		return DailyResult{}, err
+	}
+	res, err := scanDaily(day, tx.QueryRow(ctx, `
+		SELECT history, solved, started_at, finished_at
+		FROM daily_results
+		WHERE day = $1 AND user_id = $2
+		FOR UPDATE
+	`, day, userID))
+	if err != nil {
+		return DailyResult{}, err
+	}
+
+	if err := move(&res.Play); err != nil {
+		return DailyResult{}, err
+	}
+
+	history, err := json.Marshal(res.Play.History)
+	if err != nil {
+		return DailyResult{}, err
+	}
+	err = tx.QueryRow(ctx, `
+		UPDATE daily_results
+		SET history = $3, rounds = $4, solved = $5,
+		    finished_at = CASE WHEN $6::bool THEN COALESCE(finished_at, now()) END
+		WHERE day = $1 AND user_id = $2
+		RETURNING finished_at
+	`, day, userID, history, res.Play.Rounds(), res.Play.Solved, res.Play.Finished()).Scan(&res.FinishedAt)
+	if err != nil {
+		return DailyResult{}, err
+	}
+	return res, tx.Commit(ctx)
+}
+
+// Leaderboard — отгадавшие за день: меньше догадок выше, при равенстве — кто быстрее.
+// Вторым значением — сколько всего закончили попытку (отгадали или нет).
+func (s *DailyStore) Leaderboard(ctx context.Context, day string, limit int) ([]DailyEntry, int, error) {
+	var finished int
+	if err := s.db.QueryRow(ctx, `
+		SELECT count(*) FROM daily_results d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.day = $1 AND d.finished_at IS NOT NULL AND NOT u.is_guest
+	`, day).Scan(&finished); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT d.user_id, u.display_name, d.rounds,
+		       (EXTRACT(EPOCH FROM d.finished_at - d.started_at) * 1000)::bigint, d.finished_at
+		FROM daily_results d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.day = $1 AND d.solved AND NOT u.is_guest
+		ORDER BY d.rounds, d.finished_at - d.started_at, d.finished_at, d.user_id
+		LIMIT $2
+	`, day, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []DailyEntry
+	for rows.Next() {
+		var (
+			e  DailyEntry
+			ms int64
+		)
+		if err := rows.Scan(&e.UserID, &e.DisplayName, &e.Rounds, &ms, &e.FinishedAt); err != nil {
+			return nil, 0, err
+		}
+		e.Rank = len(out) + 1
+		e.Duration = time.Duration(ms) * time.Millisecond
+		out = append(out, e)
+	}
+	return out, finished, rows.Err()
+}
+
+func scanDaily(day string, row pgx.Row) (DailyResult, error) {
+	var (
+		res     DailyResult
+		history []byte
+	)
+	if err := row.Scan(&history, &res.Play.Solved, &res.StartedAt, &res.FinishedAt); err != nil {
+		return DailyResult{}, err
+	}
+	res.Play.Day = day
+	if err := json.Unmarshal(history, &res.Play.History); err != nil {
+		return DailyResult{}, err
+	}
+	return res, nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// UserExport — данные пользователя из users, user_identities, refresh_tokens, friendships, истории партий, достижений и ежедневных головоломок
+// (GET /api/me/export; статистика — StatsStore.Get). Хеши паролей и токенов не выгружаем.
+type UserExport struct {
+	User       User
+	Identities []IdentityExport
+	Sessions   []SessionExport
+	Friends    []Friend
+	Games      []GameExport
+
+	Achievements []UnlockedAchievement
+	Daily        []DailyResult
+}
+
+type IdentityExport struct {
+	Provider    string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+// SessionExport — refresh-токен (без хеша).
+type SessionExport struct {
+	ID        string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// GameExport — участие пользователя в одной партии.
+type GameExport struct {
+	MatchID     string
+	GameNo      int
+	Slot        string
+	Team        string
+	Rank        int
+	SolvedRound int
+	Winner      string
+	Reason      string
+	Ranked      bool
+	Rounds      int
+	FinishedAt  time.Time
+}
+
+// Export собирает данные пользователя.
+func (s *UserStore) Export(ctx context.Context, id string) (UserExport, error) {
+	var out UserExport
+	var err error
+	if out.User, err = s.GetByID(ctx, id); err != nil {
+		return UserExport{}, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT provider, subject, COALESCE(email, ''), created_at, last_login_at
+		FROM user_identities WHERE user_id=$1 ORDER BY created_at
+	`, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+	for rows.Next() {
+		var e IdentityExport
+		if err := rows.Scan(&e.Provider, &e.Subject, &e.Email, &e.CreatedAt, &e.LastLoginAt); err != nil {
+			rows.Close()
+			return UserExport{}, err
+		}
+		out.Identities = append(out.Identities, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return UserExport{}, err
+	}
+
+	rows, err = s.db.Query(ctx, `
+		SELECT id, created_at, expires_at, used_at, revoked_at
+		FROM refresh_tokens WHERE user_id=$1 ORDER BY created_at
+	`, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+	for rows.Next() {
+		var e SessionExport
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.ExpiresAt, &e.UsedAt, &e.RevokedAt); err != nil {
+			rows.Close()
+			return UserExport{}, err
+		}
+		out.Sessions = append(out.Sessions, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return UserExport{}, err
+	}
+
+	if out.Friends, err = NewFriendStore(s.db).List(ctx, id); err != nil {
+		return UserExport{}, err
+	}
+	if out.Achievements, err = NewAchievementStore(s.db).List(ctx, id); err != nil {
+		return UserExport{}, err
+	}
+	if out.Daily, err = NewDailyStore(s.db).List(ctx, id); err != nil {
+		return UserExport{}, err
+	}
+
+	rows, err = s.db.Query(ctx, `
+		SELECT p.match_id, p.game_no, p.slot, COALESCE(p.team, ''), p.rank, p.solved_round,
+		       g.winner, g.reason, g.ranked, g.rounds, g.finished_at
+		FROM match_game_players p
+		JOIN match_games g ON g.match_id = p.match_id AND g.game_no = p.game_no
+		WHERE p.user_id=$1
+		ORDER BY g.finished_at, p.match_id, p.game_no
+	`, id)
+	if err != nil {
+		return UserExport{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e GameExport
+		if err := rows.Scan(&e.MatchID, &e.GameNo, &e.Slot, &e.Team, &e.Rank, &e.SolvedRound,
+			&e.Winner, &e.Reason, &e.Ranked, &e.Rounds, &e.FinishedAt); err != nil {
+			return UserExport{}, err
+		}
+		out.Games = append(out.Games, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrAlreadyFriends         = errors.New("already friends")
+	ErrFriendRequestExists    = errors.New("friend request already sent")
+	ErrFriendRequestNotFound  = errors.New("friend request not found")
+	ErrFriendNotFound         = errors.New("friend not found")
+	ErrFriendRequestYourself  = errors.New("cannot befriend yourself")
+	ErrFriendRequestRecipient = errors.New("recipient not found")
+)
+
+// Статусы в списке друзей с точки зрения пользователя.
+const (
+	FriendAccepted = "friend"
+	FriendIncoming = "incoming" // заявка ему
+	FriendOutgoing = "outgoing" // заявка от него
+)
+
+type Friend struct {
+	UserID      string
+	DisplayName string
+	Status      string    // FriendAccepted | FriendIncoming | FriendOutgoing
+	Since       time.Time // accepted_at для друзей, created_at для заявок
+}
+
+type FriendStore struct {
+	db *pgxpool.Pool
+}
+
+func NewFriendStore(db *pgxpool.Pool) *FriendStore {
+	return &FriendStore{db: db}
+}
+
+// Request отправляет заявку from => to. Если встречная заявка уже есть, она принимается
+// (accepted = true): двое, позвавшие друг друга, становятся друзьями.
+func (s *FriendStore) Request(ctx context.Context, from, to string) (accepted bool, err error) {
+	if from == to {
+		return false, ErrFriendRequestYourself
+	}
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE friendships SET status='accepted', accepted_at=now()
+		WHERE requester_id=$2 AND addressee_id=$1 AND status='pending'
+	`, from, to)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 1 {
+		return true, tx.Commit(ctx)
+	}
+
+	// конфликт по friendships_pair_idx — пара уже есть в каком-то виде
+	tag, err = tx.Exec(ctx, `
+		INSERT INTO friendships (requester_id, addressee_id)
+		SELECT $1, id FROM users WHERE id=$2
+		ON CONFLICT DO NOTHING
+	`, from, to)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		var status string
+		err := tx.QueryRow(ctx, `
+			SELECT status FROM friendships
+			WHERE (requester_id=$1 AND addressee_id=$2) OR (requester_id=$2 AND addressee_id=$1)
+		`, from, to).Scan(&status)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// пары нет — значит, не нашлось получателя
+			return false, ErrFriendRequestRecipient
+		case err != nil:
+			return false, err
+		case status == "accepted":
+			return false, ErrAlreadyFriends
+		default:
+			return false, ErrFriendRequestExists
+		}
+	}
+	return false, tx.Commit(ctx)
+}
+
+// Accept принимает заявку requesterID => userID.
+func (s *FriendStore) Accept(ctx context.Context, userID, requesterID string) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE friendships SET status='accepted', accepted_at=now()
+		WHERE requester_id=$2 AND addressee_id=$1 AND status='pending'
+	`, userID, requesterID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFriendRequestNotFound
+	}
+	return nil
+}
+
+// Decline отклоняет заявку requesterID => userID (строка удаляется, заявку можно отправить снова).
+func (s *FriendStore) Decline(ctx context.Context, userID, requesterID string) error {
+	tag, err := s.db.Exec(ctx, `
+		DELETE FROM friendships
+		WHERE requester_id=$2 AND addressee_id=$1 AND status='pending'
+	`, userID, requesterID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFriendRequestNotFound
+	}
+	return nil
+}
+
+// Remove удаляет друга или отзывает свою заявку.
+func (s *FriendStore) Remove(ctx context.Context, userID, otherID string) error {
+	tag, err := s.db.Exec(ctx, `
+		DELETE FROM friendships
+		WHERE (requester_id=$1 AND addressee_id=$2)
+		   OR (requester_id=$2 AND addressee_id=$1 AND status='accepted')
+	`, userID, otherID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFriendNotFound
+	}
+	return nil
+}
+
+// AreFriends — есть ли принятая дружба между a и b.
+func (s *FriendStore) AreFriends(ctx context.Context, a, b string) (bool, error) {
+	var ok bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM friendships
+			WHERE status='accepted'
+			  AND ((requester_id=$1 AND addressee_id=$2) OR (requester_id=$2 AND addressee_id=$1))
+		)
+	`, a, b).Scan(&ok)
+	return ok, err
+}
+
+// List — друзья и заявки пользователя: сначала друзья, затем входящие и исходящие, новые выше.
+func (s *FriendStore) List(ctx context.Context, userID string) ([]Friend, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT u.id, u.display_name,
+		       CASE WHEN f.status = 'accepted' THEN 'friend'
+		            WHEN f.addressee_id = $1 THEN 'incoming'
+		            ELSE 'outgoing' END AS kind,
+		       COALESCE(f.accepted_at, f.created_at) AS since
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.requester_id = $1 THEN f.addressee_id ELSE f.requester_id END
+		WHERE f.requester_id = $1 OR f.addressee_id = $1
+		ORDER BY CASE WHEN f.status = 'accepted' THEN 0 WHEN f.addressee_id = $1 THEN 1 ELSE 2 END,
+		         since DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Friend
+	for rows.Next() {
+		var f Friend
+		if err := rows.Scan(&f.UserID, &f.DisplayName, &f.Status, &f.Since); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
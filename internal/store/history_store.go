@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrBadCursor = errors.New("bad cursor")
+
+// GameSummary — одна партия из истории пользователя (GET /api/users/{id}/matches).
+type GameSummary struct {
+	MatchID    string
+	GameNo     int
+	Slot       string
+	Team       string
+	Rank       int
+	Result     string // win | loss | draw — с точки зрения пользователя
+	Winner     string
+	Reason     string
+	Ranked     bool
+	Rounds     int
+	FinishedAt time.Time
+	Opponents  []Participant
+	Teammates  []Participant
+
+	SolvedRound  int
+	MissedRounds int
+	TimeLeft     time.Duration // остаток таймера на отгадавшей попытке; 0 — без таймера или не отгадал
+}
+
+// Participant — другой игрок той же партии. UserID пуст, если аккаунт удалён
+// (user_id в истории обнуляется через ON DELETE SET NULL).
+type Participant struct {
+	UserID      string
+	DisplayName string
+	Slot        string
+	Team        string
+	Rank        int
+}
+
+// HistoryStore читает сохранённую историю партий (match_games + match_game_players).
+type HistoryStore struct {
+	db *pgxpool.Pool
+}
+
+func NewHistoryStore(db *pgxpool.Pool) *HistoryStore {
+	return &HistoryStore{db: db}
+}
+
+// MatchHistory возвращает партии пользователя от новых к старым, не больше limit.
+// cursor — значение next с предыдущей страницы ("" — с начала); next пуст на последней странице.
+func (s *HistoryStore) MatchHistory(ctx context.Context, userID, cursor string, limit int) ([]GameSummary, string, error) {
+	after, err := decodeHistoryCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// берём на одну больше, чтобы узнать про next
+	games, err := s.queryGames(ctx, userID, after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(games) > limit {
+		games = games[:limit]
+		last := games[len(games)-1]
+		next = encodeHistoryCursor(last.FinishedAt, last.MatchID, last.GameNo)
+	}
+	if len(games) == 0 {
+		return games, next, nil
+	}
+	if err := s.fillParticipants(ctx, games); err != nil {
+		return nil, "", err
+	}
+	return games, next, nil
+}
+
+// RecentGames — последние limit партий пользователя от новых к старым, без соперников
+// (для правил достижений).
+func (s *HistoryStore) RecentGames(ctx context.Context, userID string, limit int) ([]GameSummary, error) {
+	return s.queryGames(ctx, userID, historyCursor{}, limit)
+}
+
+// queryGames — keyset-пагинация по (finished_at, match_id, game_no).
+func (s *HistoryStore) queryGames(ctx context.Context, userID string, after historyCursor, limit int) ([]GameSummary, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT p.match_id, p.game_no, p.slot, COALESCE(p.team, ''), p.rank,
+		       p.solved_round, p.missed_rounds, COALESCE(p.time_left_ms, 0),
+		       g.winner, g.reason, g.ranked, g.rounds, g.finished_at,
+		       (SELECT count(*) FROM match_game_players f
+		        WHERE f.match_id = p.match_id AND f.game_no = p.game_no AND f.rank = 1)
+		FROM match_game_players p
+		JOIN match_games g ON g.match_id = p.match_id AND g.game_no = p.game_no
+		WHERE p.user_id = $1
+		  AND ($2::timestamptz IS NULL OR (g.finished_at, p.match_id, p.game_no) < ($2, $3, $4))
+		ORDER BY g.finished_at DESC, p.match_id DESC, p.game_no DESC
+		LIMIT $5
+	`, userID, after.finishedAt, after.matchID, after.gameNo, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []GameSummary
+	for rows.Next() {
+		var (
+			g      GameSummary
+			leftMs int64
+			first  int
+		)
+		if err := rows.Scan(&g.MatchID, &g.GameNo, &g.Slot, &g.Team, &g.Rank,
+			&g.SolvedRound, &g.MissedRounds, &leftMs,
+			&g.Winner, &g.Reason, &g.Ranked, &g.Rounds, &g.FinishedAt, &first); err != nil {
+			return nil, err
+		}
+		g.TimeLeft = time.Duration(leftMs) * time.Millisecond
+		g.Result = gameResult(g, first)
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
+
+// fillParticipants дописывает соперников и напарников одним запросом на всю страницу.
+func (s *HistoryStore) fillParticipants(ctx context.Context, games []GameSummary) error {
+	matchIDs := make([]string, len(games))
+	gameNos := make([]int32, len(games))
+	byKey := make(map[string]*GameSummary, len(games))
+	for i := range games {
+		matchIDs[i] = games[i].MatchID
+		gameNos[i] = int32(games[i].GameNo)
+		byKey[gameKey(games[i].MatchID, games[i].GameNo)] = &games[i]
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT p.match_id, p.game_no, p.slot, COALESCE(p.team, ''), p.rank,
+		       COALESCE(p.user_id::text, ''), COALESCE(u.display_name, '')
+		FROM unnest($1::text[], $2::int[]) AS k(match_id, game_no)
+		JOIN match_game_players p ON p.match_id = k.match_id AND p.game_no = k.game_no
+		LEFT JOIN users u ON u.id = p.user_id
+		ORDER BY p.match_id, p.game_no, p.slot
+	`, matchIDs, gameNos)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			matchID string
+			gameNo  int
+			p       Participant
+		)
+		if err := rows.Scan(&matchID, &gameNo, &p.Slot, &p.Team, &p.Rank, &p.UserID, &p.DisplayName); err != nil {
+			return err
+		}
+		g := byKey[gameKey(matchID, gameNo)]
+		switch {
+		case g == nil || p.Slot == g.Slot:
+		case g.Team != "" && p.Team == g.Team:
+			g.Teammates = append(g.Teammates, p)
+		default:
+			g.Opponents = append(g.Opponents, p)
+		}
+	}
+	return rows.Err()
+}
+
+// gameResult — те же правила, что при подсчёте player_stats (см. RecordGame).
+func gameResult(g GameSummary, firstPlaces int) string {
+	var w, d int
+	if g.Team != "" {
+		w, _, d = outcome(g.Winner, g.Team)
+	} else if g.Rank == 1 {
+		if firstPlaces > 1 {
+			d = 1
+		} else {
+			w = 1
+		}
+	}
+	switch {
+	case w > 0:
+		return "win"
+	case d > 0:
+		return "draw"
+	default:
+		return "loss"
+	}
+}
+
+func gameKey(matchID string, gameNo int) string {
+	return matchID + "#" + strconv.Itoa(gameNo)
+}
+
+type historyCursor struct {
+	finishedAt *time.Time
+	matchID    string
+	gameNo     int
+}
+
+// курсор непрозрачен для клиента: base64url("finished_at|match_id|game_no")
+func encodeHistoryCursor(finishedAt time.Time, matchID string, gameNo int) string {
+	raw := finishedAt.UTC().Format(time.RFC3339Nano) + "|" + matchID + "|" + strconv.Itoa(gameNo)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeHistoryCursor(s string) (historyCursor, error) {
+	if s == "" {
+		return historyCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return historyCursor{}, ErrBadCursor
+	}
+	// match_id может содержать '|', поэтому game_no режем справа
+	head, no, ok := cutLast(string(raw), "|")
+	if !ok {
+		return historyCursor{}, ErrBadCursor
+	}
+	ts, matchID, ok := strings.Cut(head, "|")
+	if !ok {
+		return historyCursor{}, ErrBadCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return historyCursor{}, ErrBadCursor
+	}
+	gameNo, err := strconv.Atoi(no)
+	if err != nil {
+		return historyCursor{}, ErrBadCursor
+	}
+	return historyCursor{finishedAt: &t, matchID: matchID, gameNo: gameNo}, nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
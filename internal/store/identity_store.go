@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Identity — привязка внешнего аккаунта (OIDC provider + subject) к пользователю.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string // email у провайдера в нижнем регистре, может быть ""
+	EmailVerified bool   // провайдер подтвердил, что email принадлежит пользователю
+}
+
+type IdentityStore struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityStore(db *pgxpool.Pool) *IdentityStore {
+	return &IdentityStore{db: db}
+}
+
+// Login возвращает пользователя, привязанного к identity, и запоминает email и время входа.
+// ErrUserNotFound — identity ещё не привязана (первый вход, см. LinkOrCreate).
+func (s *IdentityStore) Login(ctx context.Context, id Identity) (User, error) {
+	var userID string
+	err := s.db.QueryRow(ctx, `
+		UPDATE user_identities SET email=$3, last_login_at=now()
+		WHERE provider=$1 AND subject=$2
+		RETURNING user_id
+	`, id.Provider, id.Subject, nullString(id.Email)).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	return scanIdentityUser(ctx, s.db, userID)
+}
+
+// LinkOrCreate привязывает identity при первом входе.
+//
+// Если провайдер подтвердил email и тот совпадает с подтверждённым email существующего
+// (не гостевого) аккаунта — identity привязывается к нему. Неподтверждённый с нашей стороны
+// email не в счёт: иначе зарегистрировавший чужой адрес получил бы доступ к аккаунту владельца.
+//
+// Иначе создаётся пользователь (newUserID, displayName) без пароля. Email провайдера
+// сохраняется и в users, если он ещё ничей; подтверждённым — если его подтвердил провайдер.
+// created = пользователь создан этим вызовом.
+func (s *IdentityStore) LinkOrCreate(ctx context.Context, id Identity, newUserID, displayName string) (u User, created bool, err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return User{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID string
+	if id.Email != "" && id.EmailVerified {
+		err := tx.QueryRow(ctx, `
+			SELECT id FROM users WHERE email=$1 AND email_verified_at IS NOT NULL AND NOT is_guest
+		`, id.Email).Scan(&userID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return User{}, false, err
+		}
+	}
+
+	if userID == "" {
+		var emailTaken bool
+		if id.Email != "" {
+			if err := tx.QueryRow(ctx, `
+				SELECT EXISTS (SELECT 1 FROM users WHERE email=$1)
+			`, id.Email).Scan(&emailTaken); err != nil {
+				return User{}, false, err
+			}
+		}
+		email := id.Email
+		if emailTaken {
+			email = ""
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO users (id, email, display_name, email_verified_at)
+			VALUES ($1, $2, $3, CASE WHEN $4::bool THEN now() END)
+		`, newUserID, nullString(email), displayName, email != "" && id.EmailVerified); err != nil {
+			return User{}, false, err
+		}
+		userID, created = newUserID, true
+	}
+
+	// параллельный первый вход того же subject: вторая вставка упадёт на PRIMARY KEY,
+	// транзакция откатится вместе с пользователем, клиент просто повторит вход
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO user_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, $4)
+	`, id.Provider, id.Subject, userID, nullString(id.Email)); err != nil {
+		return User{}, false, err
+	}
+
+	u, err = scanIdentityUser(ctx, tx, userID)
+	if err != nil {
+		return User{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return User{}, false, err
+	}
+	return u, created, nil
+}
+
+func scanIdentityUser(ctx context.Context, q querier, userID string) (User, error) {
+	var u User
+	err := q.QueryRow(ctx, `
+		SELECT id, COALESCE(email, ''), COALESCE(password_hash, ''), display_name, is_guest, email_verified_at IS NOT NULL, created_at
+		FROM users
+		WHERE id=$1
+	`, userID).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.DisplayName, &u.IsGuest, &u.EmailVerified, &u.CreatedAt)
+	return u, err
+}
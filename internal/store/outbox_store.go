@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/bc-mvp/internal/mail"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// outboxLease — на сколько Claim откладывает письмо: если sender упал посреди отправки,
+	// письмо вернётся в очередь после lease (возможен дубль, но не потеря).
+	outboxLease = 5 * time.Minute
+	// outboxMaxAttempts — после стольких неудачных попыток письмо больше не отправляем (остаётся с last_error).
+	outboxMaxAttempts = 8
+)
+
+// OutboxStore — таблица email_outbox. Реализует mail.Outbox.
+type OutboxStore struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxStore(db *pgxpool.Pool) *OutboxStore {
+	return &OutboxStore{db: db}
+}
+
+// enqueueMail кладёт письмо в outbox внутри транзакции вызывающего.
+func enqueueMail(ctx context.Context, tx pgx.Tx, m mail.Message) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO email_outbox (recipient, subject, body) VALUES ($1, $2, $3)
+	`, m.To, m.Subject, m.Body)
+	return err
+}
+
+// EnqueueForUser кладёт письмо на подтверждённый адрес пользователя; build получает этот адрес.
+// false — писать некуда: гость, адрес не подтверждён или пользователя нет.
+func (s *OutboxStore) EnqueueForUser(ctx context.Context, userID string, build func(to string) mail.Message) (bool, error) {
+	var to string
+	err := s.db.QueryRow(ctx, `
+		SELECT email FROM users WHERE id=$1 AND email IS NOT NULL AND email_verified_at IS NOT NULL
+	`, userID).Scan(&to)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	m := build(to)
+	if _, err := s.db.Exec(ctx, `
+		INSERT INTO email_outbox (recipient, subject, body) VALUES ($1, $2, $3)
+	`, m.To, m.Subject, m.Body); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Claim забирает до limit писем, которым пора уходить, и сдвигает их next_attempt_at на lease.
+// SKIP LOCKED: несколько инстансов не возьмут одно письмо одновременно.
+func (s *OutboxStore) Claim(ctx context.Context, limit int) ([]mail.Message, error) {
+	rows, err := s.db.Query(ctx, `
+		UPDATE email_outbox
+		SET attempts = attempts + 1, next_attempt_at = now() + $2 * interval '1 second'
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE sent_at IS NULL AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, body, attempts
+	`, limit, outboxLease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []mail.Message
+	for rows.Next() {
+		var m mail.Message
+		if err := rows.Scan(&m.ID, &m.To, &m.Subject, &m.Body, &m.Attempts); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (s *OutboxStore) MarkSent(ctx context.Context, id int64) error {
+	_, err := s.db.Exec(ctx, `UPDATE email_outbox SET sent_at=now(), last_error=NULL WHERE id=$1`, id)
+	return err
+}
+
+// MarkFailed планирует повтор с квадратичной задержкой (1, 4, 9... минут),
+// после outboxMaxAttempts попыток — больше не пробуем.
+func (s *OutboxStore) MarkFailed(ctx context.Context, id int64, attempts int, sendErr error) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE email_outbox
+		SET last_error=$2,
+		    next_attempt_at = CASE WHEN $3::int >= $4::int THEN 'infinity'::timestamptz
+		                           ELSE now() + ($3::int * $3::int) * interval '1 minute' END
+		WHERE id=$1
+	`, id, sendErr.Error(), attempts, outboxMaxAttempts)
+	return err
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ResultStore пишет итоги партий и серий и обновляет player_stats.
+// Реализует game.ResultRecorder.
+type ResultStore struct {
+	db *pgxpool.Pool
+}
+
+func NewResultStore(db *pgxpool.Pool) *ResultStore {
+	return &ResultStore{db: db}
+}
+
+func (s *ResultStore) RecordGame(ctx context.Context, r game.GameResult) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// (match_id, game_no) уникален: повторная запись той же партии (например, после рестарта) — no-op
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO match_games (match_id, game_no, p1_id, p2_id, winner, reason, ranked, rounds, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (match_id, game_no) DO NOTHING
+	`, r.MatchID, r.GameNo, nullUUID(r.P1ID), nullUUID(r.P2ID), r.Winner, r.Reason, r.Ranked, r.Rounds, r.FinishedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	for _, p := range r.Players {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO match_game_players (match_id, game_no, slot, team, user_id, rank, solved_round,
+			                                missed_rounds, time_left_ms)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, r.MatchID, r.GameNo, p.Slot, nullString(p.Team), nullUUID(p.UserID), p.Rank, p.SolvedRound,
+			p.MissedRounds, nullMillis(p.TimeLeft))
+		if err != nil {
+			return err
+		}
+	}
+
+	// партии, решённые по tiebreak (лимит раундов), считаем ещё и отдельно
+	tiebreak := r.Reason == game.ReasonMaxRounds
+
+	for _, p := range r.Players {
+		w, l, d := rankOutcome(r.Players, p.Rank)
+		team := p.Team != ""
+		if team {
+			// в команде первое место делят напарники — это победа, а не ничья
+			w, l, d = outcome(r.Winner, p.Team)
+		}
+		if err := addGameStats(ctx, tx, p.UserID, w, l, d, tiebreak, team); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *ResultStore) RecordSeries(ctx context.Context, r game.SeriesResult) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO match_series (match_id, best_of, p1_id, p2_id, p1_wins, p2_wins, draws, winner, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (match_id) DO NOTHING
+	`, r.MatchID, r.BestOf, nullUUID(r.P1ID), nullUUID(r.P2ID),
+		r.Score.P1Wins, r.Score.P2Wins, r.Score.Draws, r.Winner, r.FinishedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	w1, l1, d1 := outcome(r.Winner, "p1")
+	w2, l2, d2 := outcome(r.Winner, "p2")
+	if err := addSeriesStats(ctx, tx, r.P1ID, w1, l1, d1); err != nil {
+		return err
+	}
+	if err := addSeriesStats(ctx, tx, r.P2ID, w2, l2, d2); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func addGameStats(ctx context.Context, tx pgx.Tx, userID string, wins, losses, draws int, tiebreak, team bool) error {
+	if userID == "" {
+		return nil
+	}
+	var tbWins, tbLosses int
+	if tiebreak {
+		tbWins, tbLosses = wins, losses
+	}
+	var tWins, tLosses, tDraws int
+	if team {
+		tWins, tLosses, tDraws = wins, losses, draws
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO player_stats (user_id, wins, losses, draws, tiebreak_wins, tiebreak_losses,
+		                          team_wins, team_losses, team_draws)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id) DO UPDATE SET
+			wins = player_stats.wins + EXCLUDED.wins,
+			losses = player_stats.losses + EXCLUDED.losses,
+			draws = player_stats.draws + EXCLUDED.draws,
+			tiebreak_wins = player_stats.tiebreak_wins + EXCLUDED.tiebreak_wins,
+			tiebreak_losses = player_stats.tiebreak_losses + EXCLUDED.tiebreak_losses,
+			team_wins = player_stats.team_wins + EXCLUDED.team_wins,
+			team_losses = player_stats.team_losses + EXCLUDED.team_losses,
+			team_draws = player_stats.team_draws + EXCLUDED.team_draws,
+			updated_at = now()
+	`, userID, wins, losses, draws, tbWins, tbLosses, tWins, tLosses, tDraws)
+	return err
+}
+
+func addSeriesStats(ctx context.Context, tx pgx.Tx, userID string, wins, losses, draws int) error {
+	if userID == "" {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO player_stats (user_id, series_wins, series_losses, series_draws)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			series_wins = player_stats.series_wins + EXCLUDED.series_wins,
+			series_losses = player_stats.series_losses + EXCLUDED.series_losses,
+			series_draws = player_stats.series_draws + EXCLUDED.series_draws,
+			updated_at = now()
+	`, userID, wins, losses, draws)
+	return err
+}
+
+// rankOutcome: единственное первое место — победа, разделённое первое — ничья, остальные — поражение.
+func rankOutcome(players []game.PlayerResult, rank int) (win, loss, draw int) {
+	if rank != 1 {
+		return 0, 1, 0
+	}
+	first := 0
+	for _, p := range players {
+		if p.Rank == 1 {
+			first++
+		}
+	}
+	if first > 1 {
+		return 0, 0, 1
+	}
+	return 1, 0, 0
+}
+
+// outcome переводит winner (p1|p2|t1|t2|draw) в win/loss/draw для конкретного слота или команды.
+func outcome(winner, slot string) (win, loss, draw int) {
+	switch winner {
+	case "draw":
+		return 0, 0, 1
+	case slot:
+		return 1, 0, 0
+	default:
+		return 0, 1, 0
+	}
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullMillis(d time.Duration) any {
+	if d <= 0 {
+		return nil
+	}
+	return d.Milliseconds()
+}
+
+func nullUUID(id string) any {
+	if id == "" {
+		return nil
+	}
+	return id
+}
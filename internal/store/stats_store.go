@@ -10,11 +10,21 @@ import (
 )
 
 type PlayerStats struct {
-	UserID    string
-	Wins      int
-	Losses    int
-	Draws     int
-	UpdatedAt time.Time
+	UserID       string
+	Wins         int
+	Losses       int
+	Draws        int
+	SeriesWins   int
+	SeriesLosses int
+	SeriesDraws  int
+	// партии, решённые по tiebreak после лимита раундов (входят и в Wins/Losses)
+	TiebreakWins   int
+	TiebreakLosses int
+	// командные партии 2v2 (входят и в Wins/Losses/Draws)
+	TeamWins   int
+	TeamLosses int
+	TeamDraws  int
+	UpdatedAt  time.Time
 }
 
 type StatsStore struct {
@@ -37,10 +47,14 @@ func (s *StatsStore) InitForUser(ctx context.Context, userID string) error {
 func (s *StatsStore) Get(ctx context.Context, userID string) (PlayerStats, error) {
 	var st PlayerStats
 	err := s.db.QueryRow(ctx, `
-		SELECT user_id, wins, losses, draws, updated_at
+		SELECT user_id, wins, losses, draws, series_wins, series_losses, series_draws,
+		       tiebreak_wins, tiebreak_losses, team_wins, team_losses, team_draws, updated_at
 		FROM player_stats
 		WHERE user_id=$1
-	`, userID).Scan(&st.UserID, &st.Wins, &st.Losses, &st.Draws, &st.UpdatedAt)
+	`, userID).Scan(&st.UserID, &st.Wins, &st.Losses, &st.Draws,
+		&st.SeriesWins, &st.SeriesLosses, &st.SeriesDraws,
+		&st.TiebreakWins, &st.TiebreakLosses,
+		&st.TeamWins, &st.TeamLosses, &st.TeamDraws, &st.UpdatedAt)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		// если вдруг статистики нет — это не фатально, можно считать нулями
@@ -51,3 +65,37 @@ func (s *StatsStore) Get(ctx context.Context, userID string) (PlayerStats, error
 	}
 	return st, nil
 }
+
+// GetMany — статистика нескольких игроков одним запросом (лобби); у кого строки нет — нули.
+func (s *StatsStore) GetMany(ctx context.Context, userIDs []string) (map[string]PlayerStats, error) {
+	out := make(map[string]PlayerStats, len(userIDs))
+	for _, id := range userIDs {
+		out[id] = PlayerStats{UserID: id}
+	}
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT user_id, wins, losses, draws, series_wins, series_losses, series_draws,
+		       tiebreak_wins, tiebreak_losses, team_wins, team_losses, team_draws, updated_at
+		FROM player_stats
+		WHERE user_id = ANY($1::uuid[])
+	`, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var st PlayerStats
+		if err := rows.Scan(&st.UserID, &st.Wins, &st.Losses, &st.Draws,
+			&st.SeriesWins, &st.SeriesLosses, &st.SeriesDraws,
+			&st.TiebreakWins, &st.TiebreakLosses,
+			&st.TeamWins, &st.TeamLosses, &st.TeamDraws, &st.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out[st.UserID] = st
+	}
+	return out, rows.Err()
+}
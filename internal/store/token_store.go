@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// RefreshToken — строка refresh_tokens. Сам токен не храним, только его хеш.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type TokenStore struct {
+	db *pgxpool.Pool
+}
+
+func NewTokenStore(db *pgxpool.Pool) *TokenStore {
+	return &TokenStore{db: db}
+}
+
+func (s *TokenStore) Create(ctx context.Context, t RefreshToken) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, t.ID, t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt)
+	return err
+}
+
+// Rotate обменивает refresh-токен (по хешу) на next из той же цепочки.
+// next.UserID и next.FamilyID заполняются из старого токена.
+//
+// Повторное предъявление уже обменянного токена — признак кражи:
+// отзываем всю цепочку и все выданные access-токены владельца
+// (по ним не понять, из какой цепочки они выпущены) и возвращаем ErrRefreshTokenReused.
+func (s *TokenStore) Rotate(ctx context.Context, oldHash string, next RefreshToken) (RefreshToken, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		id, userID, familyID string
+		expiresAt            time.Time
+		usedAt, revokedAt    *time.Time
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT id, user_id, family_id, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash=$1
+		FOR UPDATE
+	`, oldHash).Scan(&id, &userID, &familyID, &expiresAt, &usedAt, &revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	if usedAt != nil {
+		if err := revokeFamily(ctx, tx, familyID); err != nil {
+			return RefreshToken{}, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE users SET tokens_revoked_at=now() WHERE id=$1`, userID); err != nil {
+			return RefreshToken{}, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return RefreshToken{}, err
+		}
+		return RefreshToken{}, ErrRefreshTokenReused
+	}
+	if revokedAt != nil || time.Now().After(expiresAt) {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+
+	next.UserID = userID
+	next.FamilyID = familyID
+	if _, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens SET used_at=now(), replaced_by=$2 WHERE id=$1
+	`, id, next.ID); err != nil {
+		return RefreshToken{}, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, next.ID, next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt); err != nil {
+		return RefreshToken{}, err
+	}
+	return next, tx.Commit(ctx)
+}
+
+// RevokeFamily отзывает цепочку, к которой относится токен, и возвращает её владельца.
+// Уже отозванный токен — не ошибка (logout идемпотентен).
+func (s *TokenStore) RevokeFamily(ctx context.Context, tokenHash string) (string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID, familyID string
+	err = tx.QueryRow(ctx, `
+		SELECT user_id, family_id FROM refresh_tokens WHERE token_hash=$1
+	`, tokenHash).Scan(&userID, &familyID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := revokeFamily(ctx, tx, familyID); err != nil {
+		return "", err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+// RevokeAllForUser отзывает все refresh-токены пользователя (logout everywhere).
+func (s *TokenStore) RevokeAllForUser(ctx context.Context, userID string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at=now() WHERE user_id=$1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
+
+func revokeFamily(ctx context.Context, tx pgx.Tx, familyID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at=now() WHERE family_id=$1 AND revoked_at IS NULL
+	`, familyID)
+	return err
+}
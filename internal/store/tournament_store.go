@@ -0,0 +1,436 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTournamentNotFound       = errors.New("tournament not found")
+	ErrTournamentNotRegistering = errors.New("registration is closed")
+	ErrTournamentFull           = errors.New("tournament is full")
+	ErrTournamentRegistered     = errors.New("already registered")
+	ErrTournamentNotRegistered  = errors.New("not registered")
+	ErrTournamentNotCreator     = errors.New("only the creator can start the tournament")
+	ErrTournamentTooFewPlayers  = errors.New("at least two players are needed")
+)
+
+// Статусы турнира.
+const (
+	TournamentRegistering = "registering"
+	TournamentRunning     = "running"
+	TournamentFinished    = "finished"
+)
+
+type Tournament struct {
+	ID           string
+	Name         string
+	Format       string // tournament.FormatSwiss | tournament.FormatSingleElimination
+	Status       string
+	Rules        game.Rules // правила каждого матча турнира
+	MaxPlayers   int
+	Rounds       int // при создании — запрошенное число туров swiss (0 — авто), после start — фактическое
+	CurrentRound int
+	CreatedBy    string
+	CreatedAt    time.Time
+	StartedAt    *time.Time
+	FinishedAt   *time.Time
+	PlayerCount  int
+}
+
+type TournamentPlayer struct {
+	UserID       string
+	DisplayName  string
+	Seed         int // 0 до start
+	RegisteredAt time.Time
+}
+
+// TournamentMatch — пара тура. P2ID == "" — bye.
+type TournamentMatch struct {
+	Round      int
+	Board      int
+	MatchID    string
+	P1ID       string
+	P2ID       string
+	Result     string // "" — идёт; p1|p2|draw
+	FinishedAt *time.Time
+}
+
+// TournamentStart — план первого тура: получает турнир и посев (userId по порядку)
+// и возвращает число туров и пары. Вызывается внутри транзакции start.
+type TournamentStart func(t Tournament, seeds []string) (rounds int, first []TournamentMatch, err error)
+
+// TournamentAdvance — следующий тур по всем парам турнира; finished — турнир окончен.
+// Вызывается внутри транзакции, когда записан результат.
+type TournamentAdvance func(t Tournament, seeds []string, matches []TournamentMatch) (next []TournamentMatch, finished bool)
+
+type TournamentStore struct {
+	db *pgxpool.Pool
+}
+
+func NewTournamentStore(db *pgxpool.Pool) *TournamentStore {
+	return &TournamentStore{db: db}
+}
+
+const tournamentColumns = `
+	t.id, t.name, t.format, t.status, t.rules, t.max_players, t.rounds, t.current_round,
+	COALESCE(t.created_by::text, ''), t.created_at, t.started_at, t.finished_at,
+	(SELECT count(*) FROM tournament_players p WHERE p.tournament_id = t.id)`
+
+func scanTournament(row pgx.Row) (Tournament, error) {
+	var (
+		t     Tournament
+		rules []byte
+	)
+	err := row.Scan(&t.ID, &t.Name, &t.Format, &t.Status, &rules, &t.MaxPlayers, &t.Rounds, &t.CurrentRound,
+		&t.CreatedBy, &t.CreatedAt, &t.StartedAt, &t.FinishedAt, &t.PlayerCount)
+	if err != nil {
+		return Tournament{}, err
+	}
+	if err := json.Unmarshal(rules, &t.Rules); err != nil {
+		return Tournament{}, err
+	}
+	return t, nil
+}
+
+func (s *TournamentStore) Create(ctx context.Context, t Tournament) error {
+	rules, err := json.Marshal(t.Rules)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(ctx, `
+		INSERT INTO tournaments (id, name, format, rules, max_players, rounds, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, t.ID, t.Name, t.Format, rules, t.MaxPlayers, t.Rounds, nullUUID(t.CreatedBy), t.CreatedAt)
+	return err
+}
+
+func (s *TournamentStore) Get(ctx context.Context, id string) (Tournament, error) {
+	t, err := scanTournament(s.db.QueryRow(ctx, `SELECT `+tournamentColumns+` FROM tournaments t WHERE t.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Tournament{}, ErrTournamentNotFound
+	}
+	return t, err
+}
+
+// List — турниры с данным статусом ("" — любые), новые первыми.
+func (s *TournamentStore) List(ctx context.Context, status string, limit int) ([]Tournament, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+tournamentColumns+`
+		FROM tournaments t
+		WHERE $1 = '' OR t.status = $1
+		ORDER BY t.created_at DESC
+		LIMIT $2
+	`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Tournament
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// Register записывает игрока, пока идёт регистрация и есть места.
+func (s *TournamentStore) Register(ctx context.Context, id, userID string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// блокировка строки турнира: параллельные регистрации не превысят max_players
+	var (
+		status     string
+		maxPlayers int
+	)
+	err = tx.QueryRow(ctx, `SELECT status, max_players FROM tournaments WHERE id = $1 FOR UPDATE`, id).
+		Scan(&status, &maxPlayers)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTournamentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != TournamentRegistering {
+		return ErrTournamentNotRegistering
+	}
+
+	var n int
+	if err := tx.QueryRow(ctx, `SELECT count(*) FROM tournament_players WHERE tournament_id = $1`, id).Scan(&n); err != nil {
+		return err
+	}
+	if n >= maxPlayers {
+		return ErrTournamentFull
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO tournament_players (tournament_id, user_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTournamentRegistered
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *TournamentStore) Unregister(ctx context.Context, id, userID string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM tournaments WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTournamentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != TournamentRegistering {
+		return ErrTournamentNotRegistering
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM tournament_players WHERE tournament_id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTournamentNotRegistered
+	}
+	return tx.Commit(ctx)
+}
+
+// Players — участники по посеву (до start — по порядку регистрации).
+func (s *TournamentStore) Players(ctx context.Context, id string) ([]TournamentPlayer, error) {
+	return queryTournamentPlayers(ctx, s.db, id)
+}
+
+func (s *TournamentStore) Matches(ctx context.Context, id string) ([]TournamentMatch, error) {
+	return queryTournamentMatches(ctx, s.db, id)
+}
+
+// Start закрывает регистрацию: раздаёт посев по порядку регистрации, записывает первый тур
+// из plan и переводит турнир в running. Запускать может только создатель.
+func (s *TournamentStore) Start(ctx context.Context, id, userID string, plan TournamentStart) (Tournament, []TournamentMatch, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return Tournament{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := lockTournament(ctx, tx, id)
+	if err != nil {
+		return Tournament{}, nil, err
+	}
+	if t.CreatedBy != userID {
+		return Tournament{}, nil, ErrTournamentNotCreator
+	}
+	if t.Status != TournamentRegistering {
+		return Tournament{}, nil, ErrTournamentNotRegistering
+	}
+	if t.PlayerCount < 2 {
+		return Tournament{}, nil, ErrTournamentTooFewPlayers
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE tournament_players p SET seed = o.seed
+		FROM (SELECT user_id, row_number() OVER (ORDER BY registered_at, user_id) AS seed
+		      FROM tournament_players WHERE tournament_id = $1) o
+		WHERE p.tournament_id = $1 AND p.user_id = o.user_id
+	`, id)
+	if err != nil {
+		return Tournament{}, nil, err
+	}
+	seeds, err := tournamentSeeds(ctx, tx, id)
+	if err != nil {
+		return Tournament{}, nil, err
+	}
+
+	rounds, first, err := plan(t, seeds)
+	if err != nil {
+		return Tournament{}, nil, err
+	}
+	if err := insertTournamentMatches(ctx, tx, id, first); err != nil {
+		return Tournament{}, nil, err
+	}
+	err = tx.QueryRow(ctx, `
+		UPDATE tournaments SET status = 'running', rounds = $2, current_round = 1, started_at = now()
+		WHERE id = $1
+		RETURNING started_at
+	`, id, rounds).Scan(&t.StartedAt)
+	if err != nil {
+		return Tournament{}, nil, err
+	}
+	t.Status, t.Rounds, t.CurrentRound = TournamentRunning, rounds, 1
+	return t, first, tx.Commit(ctx)
+}
+
+// RecordResult записывает итог матча турнира: winnerID — победитель серии ("" — ничья).
+// Если тур на этом закончился, advance строит следующий (или завершает турнир).
+// found = false — матч не турнирный. Повторная запись того же матча — no-op.
+func (s *TournamentStore) RecordResult(ctx context.Context, matchID, winnerID string, advance TournamentAdvance) (t Tournament, next []TournamentMatch, found bool, err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return Tournament{}, nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `SELECT tournament_id FROM tournament_matches WHERE match_id = $1`, matchID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Tournament{}, nil, false, nil
+	}
+	if err != nil {
+		return Tournament{}, nil, false, err
+	}
+
+	// сначала блокируем турнир: матчи одного тура, закончившиеся одновременно,
+	// не построят следующий тур дважды
+	t, err = lockTournament(ctx, tx, id)
+	if err != nil {
+		return Tournament{}, nil, true, err
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE tournament_matches
+		SET result = CASE WHEN p1_id = $2::uuid THEN 'p1' WHEN p2_id = $2::uuid THEN 'p2' ELSE 'draw' END,
+		    finished_at = now()
+		WHERE match_id = $1 AND result IS NULL
+	`, matchID, nullUUID(winnerID))
+	if err != nil {
+		return Tournament{}, nil, true, err
+	}
+	if tag.RowsAffected() == 0 {
+		return t, nil, true, nil
+	}
+
+	seeds, err := tournamentSeeds(ctx, tx, id)
+	if err != nil {
+		return Tournament{}, nil, true, err
+	}
+	matches, err := queryTournamentMatches(ctx, tx, id)
+	if err != nil {
+		return Tournament{}, nil, true, err
+	}
+	next, finished := advance(t, seeds, matches)
+	switch {
+	case finished:
+		err = tx.QueryRow(ctx, `
+			UPDATE tournaments SET status = 'finished', finished_at = now() WHERE id = $1
+			RETURNING finished_at
+		`, id).Scan(&t.FinishedAt)
+		t.Status = TournamentFinished
+	case len(next) > 0:
+		if err = insertTournamentMatches(ctx, tx, id, next); err == nil {
+			t.CurrentRound = next[0].Round
+			_, err = tx.Exec(ctx, `UPDATE tournaments SET current_round = $2 WHERE id = $1`, id, t.CurrentRound)
+		}
+	}
+	if err != nil {
+		return Tournament{}, nil, true, err
+	}
+	return t, next, true, tx.Commit(ctx)
+}
+
+func lockTournament(ctx context.Context, tx pgx.Tx, id string) (Tournament, error) {
+	t, err := scanTournament(tx.QueryRow(ctx, `SELECT `+tournamentColumns+` FROM tournaments t WHERE t.id = $1 FOR UPDATE`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Tournament{}, ErrTournamentNotFound
+	}
+	return t, err
+}
+
+func tournamentSeeds(ctx context.Context, q querier, id string) ([]string, error) {
+	players, err := queryTournamentPlayers(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	seeds := make([]string, len(players))
+	for i, p := range players {
+		seeds[i] = p.UserID
+	}
+	return seeds, nil
+}
+
+// querier — общее у pgxpool.Pool и pgx.Tx для чтения.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func queryTournamentPlayers(ctx context.Context, q querier, id string) ([]TournamentPlayer, error) {
+	rows, err := q.Query(ctx, `
+		SELECT p.user_id, u.display_name, COALESCE(p.seed, 0), p.registered_at
+		FROM tournament_players p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.tournament_id = $1
+		ORDER BY p.seed NULLS LAST, p.registered_at, p.user_id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TournamentPlayer
+	for rows.Next() {
+		var p TournamentPlayer
+		if err := rows.Scan(&p.UserID, &p.DisplayName, &p.Seed, &p.RegisteredAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func queryTournamentMatches(ctx context.Context, q querier, id string) ([]TournamentMatch, error) {
+	rows, err := q.Query(ctx, `
+		SELECT round, board, COALESCE(match_id, ''), COALESCE(p1_id::text, ''), COALESCE(p2_id::text, ''),
+		       COALESCE(result, ''), finished_at
+		FROM tournament_matches
+		WHERE tournament_id = $1
+		ORDER BY round, board
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TournamentMatch
+	for rows.Next() {
+		var m TournamentMatch
+		if err := rows.Scan(&m.Round, &m.Board, &m.MatchID, &m.P1ID, &m.P2ID, &m.Result, &m.FinishedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func insertTournamentMatches(ctx context.Context, tx pgx.Tx, id string, matches []TournamentMatch) error {
+	for _, m := range matches {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO tournament_matches (tournament_id, round, board, match_id, p1_id, p2_id, result, finished_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, CASE WHEN $7::text IS NULL THEN NULL ELSE now() END)
+		`, id, m.Round, m.Board, nullString(m.MatchID), nullUUID(m.P1ID), nullUUID(m.P2ID), nullString(m.Result))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
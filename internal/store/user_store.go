@@ -6,20 +6,25 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	ErrUserNotFound = errors.New("user not found")
 	ErrEmailTaken   = errors.New("email already exists")
+	ErrNotGuest     = errors.New("user is not a guest")
+	ErrNameTaken    = errors.New("display name is already taken")
 )
 
 type User struct {
-	ID           string
-	Email        string
-	PasswordHash string
-	DisplayName  string
-	CreatedAt    time.Time
+	ID            string
+	Email         string // "" for guests
+	PasswordHash  string // "" for guests
+	DisplayName   string
+	IsGuest       bool
+	EmailVerified bool
+	CreatedAt     time.Time
 }
 
 type UserStore struct {
@@ -45,13 +50,43 @@ func (s *UserStore) Create(ctx context.Context, u User) error {
 	return nil
 }
 
+// CreateGuest создаёт гостевой аккаунт без email и пароля.
+func (s *UserStore) CreateGuest(ctx context.Context, id, displayName string) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO users (id, display_name, is_guest)
+		VALUES ($1, $2, true)
+	`, id, displayName)
+	return err
+}
+
+// UpgradeGuest привязывает email и пароль к гостевому аккаунту; ID (и с ним статистика и история) не меняется.
+// displayName "" => оставить текущее имя.
+func (s *UserStore) UpgradeGuest(ctx context.Context, id, email, passwordHash, displayName string) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE users
+		SET email=$2, password_hash=$3, display_name=COALESCE(NULLIF($4, ''), display_name), is_guest=false, email_verified_at=NULL
+		WHERE id=$1 AND is_guest
+	`, id, email, passwordHash, displayName)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotGuest
+	}
+	return nil
+}
+
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (User, error) {
 	var u User
 	err := s.db.QueryRow(ctx, `
-		SELECT id, email, password_hash, display_name, created_at
+		SELECT id, COALESCE(email, ''), COALESCE(password_hash, ''), display_name, is_guest, email_verified_at IS NOT NULL, created_at
 		FROM users
 		WHERE email=$1
-	`, email).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.DisplayName, &u.CreatedAt)
+	`, email).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.DisplayName, &u.IsGuest, &u.EmailVerified, &u.CreatedAt)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return User{}, ErrUserNotFound
@@ -65,10 +100,10 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (User, error)
 func (s *UserStore) GetByID(ctx context.Context, id string) (User, error) {
 	var u User
 	err := s.db.QueryRow(ctx, `
-		SELECT id, email, password_hash, display_name, created_at
+		SELECT id, COALESCE(email, ''), COALESCE(password_hash, ''), display_name, is_guest, email_verified_at IS NOT NULL, created_at
 		FROM users
 		WHERE id=$1
-	`, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.DisplayName, &u.CreatedAt)
+	`, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.DisplayName, &u.IsGuest, &u.EmailVerified, &u.CreatedAt)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return User{}, ErrUserNotFound
@@ -78,3 +113,103 @@ func (s *UserStore) GetByID(ctx context.Context, id string) (User, error) {
 	}
 	return u, nil
 }
+
+// RevokeTokens делает недействительными все access-токены пользователя, выпущенные до этого момента.
+func (s *UserStore) RevokeTokens(ctx context.Context, userID string) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET tokens_revoked_at=now() WHERE id=$1`, userID)
+	return err
+}
+
+// TokensRevokedAt — момент последнего отзыва токенов (zero, если не отзывались).
+// Реализует auth.RevocationChecker.
+func (s *UserStore) TokensRevokedAt(ctx context.Context, userID string) (time.Time, error) {
+	var at *time.Time
+	err := s.db.QueryRow(ctx, `SELECT tokens_revoked_at FROM users WHERE id=$1`, userID).Scan(&at)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, ErrUserNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if at == nil {
+		return time.Time{}, nil
+	}
+	return *at, nil
+}
+
+// DisplayNameTaken — занято ли имя другим пользователем (без учёта регистра).
+func (s *UserStore) DisplayNameTaken(ctx context.Context, name, exceptID string) (bool, error) {
+	var taken bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE lower(display_name)=lower($1) AND id::text <> $2)
+	`, name, exceptID).Scan(&taken)
+	return taken, err
+}
+
+// UpdateDisplayName меняет имя, если оно не занято другим пользователем (без учёта регистра).
+func (s *UserStore) UpdateDisplayName(ctx context.Context, id, name string) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE users SET display_name=$2
+		WHERE id=$1 AND NOT EXISTS (SELECT 1 FROM users WHERE lower(display_name)=lower($2) AND id <> $1)
+	`, id, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNameTaken
+	}
+	return nil
+}
+
+// ChangePassword меняет хеш пароля и отзывает все сессии (refresh- и access-токены).
+func (s *UserStore) ChangePassword(ctx context.Context, id, passwordHash string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET password_hash=$2, tokens_revoked_at=now() WHERE id=$1
+	`, id, passwordHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at=now() WHERE user_id=$1 AND revoked_at IS NULL
+	`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Delete удаляет пользователя. Статистика, токены и привязки OIDC удаляются каскадом,
+// в истории матчей user_id обнуляется (ON DELETE SET NULL): соперники сохраняют свои результаты,
+// но партии больше не связаны с удалённым аккаунтом. Письма на его адрес (и отправленные) удаляются из outbox.
+func (s *UserStore) Delete(ctx context.Context, id string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var email *string
+	err = tx.QueryRow(ctx, `DELETE FROM users WHERE id=$1 RETURNING email`, id).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if email != nil {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM email_outbox WHERE recipient=$1
+		`, *email); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
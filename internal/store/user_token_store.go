@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"example.com/bc-mvp/internal/mail"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Назначения одноразовых токенов из писем.
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+)
+
+// userTokenCooldown — не чаще одного письма одного назначения в минуту на пользователя.
+const userTokenCooldown = time.Minute
+
+var (
+	ErrUserTokenInvalid   = errors.New("token is invalid, used or expired")
+	ErrUserTokenThrottled = errors.New("token was issued recently")
+)
+
+// UserToken — строка user_tokens. Сам токен не храним, только его хеш.
+type UserToken struct {
+	ID        string
+	UserID    string
+	Purpose   string
+	TokenHash string
+	Email     string // куда ушло письмо
+	ExpiresAt time.Time
+}
+
+type UserTokenStore struct {
+	db *pgxpool.Pool
+}
+
+func NewUserTokenStore(db *pgxpool.Pool) *UserTokenStore {
+	return &UserTokenStore{db: db}
+}
+
+// Issue сохраняет токен и кладёт письмо в outbox одной транзакцией.
+// Прежние неиспользованные токены того же назначения гасятся: работает только последняя ссылка.
+func (s *UserTokenStore) Issue(ctx context.Context, t UserToken, m mail.Message) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var recent bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_tokens
+			WHERE user_id=$1 AND purpose=$2 AND created_at > now() - $3 * interval '1 second'
+		)
+	`, t.UserID, t.Purpose, userTokenCooldown.Seconds()).Scan(&recent)
+	if err != nil {
+		return err
+	}
+	if recent {
+		return ErrUserTokenThrottled
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE user_tokens SET used_at=now() WHERE user_id=$1 AND purpose=$2 AND used_at IS NULL
+	`, t.UserID, t.Purpose); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO user_tokens (id, user_id, purpose, token_hash, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, t.ID, t.UserID, t.Purpose, t.TokenHash, t.Email, t.ExpiresAt); err != nil {
+		return err
+	}
+	if err := enqueueMail(ctx, tx, m); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ResetPassword гасит токен сброса, меняет хеш пароля и отзывает все сессии пользователя
+// (refresh-токены и выданные access-токены). Письмо дошло — значит, email подтверждён.
+func (s *UserTokenStore) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	userID, email, err := consumeUserToken(ctx, tx, PurposePasswordReset, tokenHash)
+	if err != nil {
+		return "", err
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash=$2, tokens_revoked_at=now(),
+		    email_verified_at=COALESCE(email_verified_at, now())
+		WHERE id=$1 AND email=$3
+	`, userID, passwordHash, email)
+	if err != nil {
+		return "", err
+	}
+	if tag.RowsAffected() == 0 {
+		// email сменился после отправки письма
+		return "", ErrUserTokenInvalid
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked_at=now() WHERE user_id=$1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return "", err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+// VerifyEmail гасит токен подтверждения и отмечает email подтверждённым,
+// если он не менялся с момента отправки письма.
+func (s *UserTokenStore) VerifyEmail(ctx context.Context, tokenHash string) (string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	userID, email, err := consumeUserToken(ctx, tx, PurposeVerifyEmail, tokenHash)
+	if err != nil {
+		return "", err
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET email_verified_at=COALESCE(email_verified_at, now()) WHERE id=$1 AND email=$2
+	`, userID, email)
+	if err != nil {
+		return "", err
+	}
+	if tag.RowsAffected() == 0 {
+		return "", ErrUserTokenInvalid
+	}
+	return userID, tx.Commit(ctx)
+}
+
+// consumeUserToken атомарно помечает токен использованным (одноразовость) и возвращает владельца и email.
+func consumeUserToken(ctx context.Context, tx pgx.Tx, purpose, tokenHash string) (userID, email string, err error) {
+	err = tx.QueryRow(ctx, `
+		UPDATE user_tokens SET used_at=now()
+		WHERE token_hash=$1 AND purpose=$2 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id, email
+	`, tokenHash, purpose).Scan(&userID, &email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", ErrUserTokenInvalid
+	}
+	return userID, email, err
+}
@@ -0,0 +1,241 @@
+// Package tournament runs Swiss and single-elimination tournaments on top of regular matches.
+//
+// The functions in this file and standings.go are pure: they take the seeded players and
+// every pairing so far and compute standings or the next round. Service (service.go) stores
+// the pairings, spawns a match for each one through game.MatchService and advances the
+// tournament as series results come in.
+package tournament
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// Formats.
+const (
+	FormatSwiss             = "swiss"
+	FormatSingleElimination = "single_elimination"
+)
+
+// Pairing results, relative to the pairing's P1/P2.
+const (
+	ResultP1   = "p1"
+	ResultP2   = "p2"
+	ResultDraw = "draw"
+)
+
+// swissPairingBudget bounds the backtracking search for a pairing without rematches;
+// past it rematches are allowed rather than stalling the tournament.
+const swissPairingBudget = 100_000
+
+// Pairing is one board of a round. P2 == "" is a bye: P1 scores a win without playing.
+type Pairing struct {
+	Round  int
+	Board  int // 1-based; in elimination boards 2k-1 and 2k feed board k of the next round
+	P1     string
+	P2     string
+	Result string // "" while the match is being played
+}
+
+func (p Pairing) Bye() bool      { return p.P2 == "" }
+func (p Pairing) Finished() bool { return p.Result != "" }
+
+// Rounds is how many rounds a tournament of n players lasts. For Swiss, requested (if > 0)
+// wins, otherwise ceil(log2 n), which is enough to leave a single perfect score;
+// it never exceeds n-1, after which every pair would be a rematch.
+func Rounds(format string, n, requested int) int {
+	if n < 2 {
+		return 0
+	}
+	log := bits.Len(uint(n - 1)) // ceil(log2 n)
+	if format == FormatSingleElimination {
+		return log
+	}
+	r := requested
+	if r <= 0 {
+		r = log
+	}
+	return min(r, n-1)
+}
+
+// NextRound returns the pairings of the round after the latest one, or finished = true
+// once the last of rounds is complete. While the latest round still has matches in play
+// both results are empty.
+func NextRound(format string, rounds int, seeds []string, pairings []Pairing) (next []Pairing, finished bool) {
+	current := 0
+	for _, p := range pairings {
+		current = max(current, p.Round)
+	}
+	for _, p := range pairings {
+		if p.Round == current && !p.Finished() {
+			return nil, false
+		}
+	}
+	if current >= rounds {
+		return nil, true
+	}
+	if format == FormatSingleElimination {
+		return pairElimination(current+1, seeds, pairings), false
+	}
+	return pairSwiss(current+1, seeds, pairings), false
+}
+
+// pairSwiss pairs players in standings order: each takes the highest-ranked player it has
+// not met yet (backtracking when that leaves the rest unpairable). With an odd number of
+// players the lowest-ranked player without a bye so far gets one.
+func pairSwiss(round int, seeds []string, pairings []Pairing) []Pairing {
+	order := make([]string, 0, len(seeds))
+	for _, s := range ComputeStandings(FormatSwiss, seeds, pairings) {
+		order = append(order, s.UserID)
+	}
+
+	played := make(map[[2]string]bool)
+	hadBye := make(map[string]bool)
+	for _, p := range pairings {
+		if p.Bye() {
+			hadBye[p.P1] = true
+			continue
+		}
+		played[pairKey(p.P1, p.P2)] = true
+	}
+
+	bye := ""
+	if len(order)%2 == 1 {
+		at := len(order) - 1
+		for i := len(order) - 1; i >= 0; i-- {
+			if !hadBye[order[i]] {
+				at = i
+				break
+			}
+		}
+		bye = order[at]
+		order = append(order[:at:at], order[at+1:]...)
+	}
+
+	budget := swissPairingBudget
+	pairs, ok := pairUp(order, played, &budget)
+	if !ok {
+		pairs = pairs[:0]
+		for i := 0; i+1 < len(order); i += 2 {
+			pairs = append(pairs, [2]string{order[i], order[i+1]})
+		}
+	}
+
+	out := make([]Pairing, 0, len(pairs)+1)
+	for i, pr := range pairs {
+		out = append(out, Pairing{Round: round, Board: i + 1, P1: pr[0], P2: pr[1]})
+	}
+	if bye != "" {
+		out = append(out, Pairing{Round: round, Board: len(out) + 1, P1: bye, Result: ResultP1})
+	}
+	return out
+}
+
+func pairUp(ids []string, played map[[2]string]bool, budget *int) ([][2]string, bool) {
+	if len(ids) == 0 {
+		return nil, true
+	}
+	first := ids[0]
+	for i := 1; i < len(ids); i++ {
+		if *budget <= 0 {
+			return nil, false
+		}
+		*budget--
+		if played[pairKey(first, ids[i])] {
+			continue
+		}
+		rest := make([]string, 0, len(ids)-2)
+		rest = append(rest, ids[1:i]...)
+		rest = append(rest, ids[i+1:]...)
+		if pairs, ok := pairUp(rest, played, budget); ok {
+			return append([][2]string{{first, ids[i]}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+func pairKey(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// pairElimination builds round 1 of the bracket from the seeds (1 vs N, byes for the top
+// seeds when the field is not a power of two), and every later round from the winners of
+// neighbouring boards.
+func pairElimination(round int, seeds []string, pairings []Pairing) []Pairing {
+	if round == 1 {
+		size := 1 << bits.Len(uint(len(seeds)-1))
+		order := bracketOrder(size)
+		out := make([]Pairing, 0, size/2)
+		for i := 0; i < size; i += 2 {
+			// order[i] is always the better seed and always a real player: more than half the slots are filled
+			p := Pairing{Round: 1, Board: i/2 + 1, P1: seeds[order[i]-1]}
+			if s := order[i+1]; s <= len(seeds) {
+				p.P2 = seeds[s-1]
+			} else {
+				p.Result = ResultP1
+			}
+			out = append(out, p)
+		}
+		return out
+	}
+
+	seedOf := seedIndex(seeds)
+	var prev []Pairing
+	for _, p := range pairings {
+		if p.Round == round-1 {
+			prev = append(prev, p)
+		}
+	}
+	sort.Slice(prev, func(i, j int) bool { return prev[i].Board < prev[j].Board })
+
+	out := make([]Pairing, 0, len(prev)/2)
+	for i := 0; i+1 < len(prev); i += 2 {
+		out = append(out, Pairing{
+			Round: round,
+			Board: i/2 + 1,
+			P1:    Advancer(prev[i], seedOf),
+			P2:    Advancer(prev[i+1], seedOf),
+		})
+	}
+	return out
+}
+
+// Advancer is who goes through from a finished elimination pairing. Tournament matches
+// can end in a draw (e.g. a drawn series); then the better seed advances.
+func Advancer(p Pairing, seedOf map[string]int) string {
+	switch {
+	case p.Bye() || p.Result == ResultP1:
+		return p.P1
+	case p.Result == ResultP2:
+		return p.P2
+	case seedOf[p.P2] < seedOf[p.P1]:
+		return p.P2
+	default:
+		return p.P1
+	}
+}
+
+// bracketOrder lists seeds 1..size in bracket order, so that neighbouring pairs are first
+// round matches and the top seeds can only meet late: 4 => [1 4 2 3], 8 => [1 8 4 5 2 7 3 6].
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, s := range order {
+			next = append(next, s, n+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+func seedIndex(seeds []string) map[string]int {
+	out := make(map[string]int, len(seeds))
+	for i, id := range seeds {
+		out[id] = i + 1
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package tournament
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func players(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("u%d", i+1)
+	}
+	return out
+}
+
+// finish plays out a round: the better seed wins every board.
+func finish(round []Pairing, seeds []string) []Pairing {
+	seedOf := seedIndex(seeds)
+	out := make([]Pairing, len(round))
+	for i, p := range round {
+		if !p.Finished() {
+			p.Result = ResultP1
+			if seedOf[p.P2] < seedOf[p.P1] {
+				p.Result = ResultP2
+			}
+		}
+		out[i] = p
+	}
+	return out
+}
+
+func TestRounds(t *testing.T) {
+	cases := []struct {
+		name      string
+		format    string
+		n         int
+		requested int
+		want      int
+	}{
+		{"elimination 2", FormatSingleElimination, 2, 0, 1},
+		{"elimination 5", FormatSingleElimination, 5, 0, 3},
+		{"elimination 8", FormatSingleElimination, 8, 0, 3},
+		{"elimination ignores requested", FormatSingleElimination, 8, 7, 3},
+		{"swiss auto", FormatSwiss, 9, 0, 4},
+		{"swiss requested", FormatSwiss, 9, 6, 6},
+		{"swiss capped at n-1", FormatSwiss, 4, 10, 3},
+		{"too few players", FormatSwiss, 1, 3, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Rounds(tc.format, tc.n, tc.requested))
+		})
+	}
+}
+
+func TestBracketOrder(t *testing.T) {
+	assert.Equal(t, []int{1, 2}, bracketOrder(2))
+	assert.Equal(t, []int{1, 4, 2, 3}, bracketOrder(4))
+	assert.Equal(t, []int{1, 8, 4, 5, 2, 7, 3, 6}, bracketOrder(8))
+}
+
+func TestNextRound(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "elimination gives byes to the top seeds",
+			run: func(t *testing.T) {
+				seeds := players(6)
+				first, finished := NextRound(FormatSingleElimination, 3, seeds, nil)
+				require.False(t, finished)
+				require.Len(t, first, 4)
+				assert.Equal(t, Pairing{Round: 1, Board: 1, P1: "u1", Result: ResultP1}, first[0])
+				assert.Equal(t, Pairing{Round: 1, Board: 2, P1: "u4", P2: "u5"}, first[1])
+				assert.Equal(t, Pairing{Round: 1, Board: 3, P1: "u2", Result: ResultP1}, first[2])
+				assert.Equal(t, Pairing{Round: 1, Board: 4, P1: "u3", P2: "u6"}, first[3])
+			},
+		},
+		{
+			name: "nothing new while a match is in play",
+			run: func(t *testing.T) {
+				seeds := players(4)
+				first, _ := NextRound(FormatSingleElimination, 2, seeds, nil)
+				first[0].Result = ResultP2
+				next, finished := NextRound(FormatSingleElimination, 2, seeds, first)
+				assert.Nil(t, next)
+				assert.False(t, finished)
+			},
+		},
+		{
+			name: "elimination advances winners of neighbouring boards",
+			run: func(t *testing.T) {
+				seeds := players(4)
+				first, _ := NextRound(FormatSingleElimination, 2, seeds, nil)
+				first[0].Result = ResultP2 // u4 beats u1
+				first[1].Result = ResultDraw
+				final, finished := NextRound(FormatSingleElimination, 2, seeds, first)
+				require.False(t, finished)
+				// the draw between u2 and u3 sends the better seed through
+				assert.Equal(t, []Pairing{{Round: 2, Board: 1, P1: "u4", P2: "u2"}}, final)
+
+				all := append(first, finish(final, seeds)...)
+				next, finished := NextRound(FormatSingleElimination, 2, seeds, all)
+				assert.Nil(t, next)
+				assert.True(t, finished)
+			},
+		},
+		{
+			name: "swiss avoids rematches and rotates the bye",
+			run: func(t *testing.T) {
+				seeds := players(5)
+				rounds := Rounds(FormatSwiss, len(seeds), 4)
+				var all []Pairing
+				met := make(map[[2]string]bool)
+				byes := make(map[string]int)
+				for r := 1; r <= rounds; r++ {
+					next, finished := NextRound(FormatSwiss, rounds, seeds, all)
+					require.False(t, finished)
+					require.Len(t, next, 3)
+					for _, p := range next {
+						assert.Equal(t, r, p.Round)
+						if p.Bye() {
+							byes[p.P1]++
+							continue
+						}
+						key := pairKey(p.P1, p.P2)
+						assert.False(t, met[key], "rematch %v in round %d", key, r)
+						met[key] = true
+					}
+					all = append(all, finish(next, seeds)...)
+				}
+				for id, n := range byes {
+					assert.Equal(t, 1, n, "%s got %d byes", id, n)
+				}
+				_, finished := NextRound(FormatSwiss, rounds, seeds, all)
+				assert.True(t, finished)
+			},
+		},
+		{
+			name: "swiss pairs leaders together",
+			run: func(t *testing.T) {
+				seeds := players(4)
+				first, _ := NextRound(FormatSwiss, 2, seeds, nil)
+				first = finish(first, seeds)
+				second, _ := NextRound(FormatSwiss, 2, seeds, first)
+				require.Len(t, second, 2)
+				winners := map[string]bool{first[0].P1: true, first[1].P1: true}
+				assert.True(t, winners[second[0].P1] && winners[second[0].P2], "board 1 is between the round 1 winners: %+v", second[0])
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}
@@ -0,0 +1,217 @@
+package tournament
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"example.com/bc-mvp/internal/game"
+	"example.com/bc-mvp/internal/notify"
+	"example.com/bc-mvp/internal/store"
+	"github.com/google/uuid"
+)
+
+// Tournament limits.
+const (
+	MaxNameLen        = 64
+	MinPlayers        = 2
+	MaxPlayers        = 64
+	DefaultMaxPlayers = 16
+	MaxSwissRounds    = 15
+)
+
+// Settings is what the creator chooses. Rules apply to every match of the tournament;
+// they are always duels, and a match is decided by its series, so BestOf is at least 1.
+type Settings struct {
+	Name       string
+	Format     string
+	Rules      game.Rules
+	MaxPlayers int // 0 => DefaultMaxPlayers
+	Rounds     int // Swiss only; 0 => enough rounds for a single perfect score
+}
+
+// New validates the settings and returns the tournament to store.
+func New(s Settings, createdBy string) (store.Tournament, error) {
+	name := strings.Join(strings.Fields(s.Name), " ")
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLen {
+		return store.Tournament{}, fmt.Errorf("name must be 1 to %d characters", MaxNameLen)
+	}
+	if s.Format != FormatSwiss && s.Format != FormatSingleElimination {
+		return store.Tournament{}, fmt.Errorf("unknown format %q (want %s|%s)", s.Format, FormatSwiss, FormatSingleElimination)
+	}
+	maxPlayers := s.MaxPlayers
+	if maxPlayers == 0 {
+		maxPlayers = DefaultMaxPlayers
+	}
+	if maxPlayers < MinPlayers || maxPlayers > MaxPlayers {
+		return store.Tournament{}, fmt.Errorf("maxPlayers must be between %d and %d", MinPlayers, MaxPlayers)
+	}
+	rounds := 0
+	if s.Format == FormatSwiss {
+		if s.Rounds < 0 || s.Rounds > MaxSwissRounds {
+			return store.Tournament{}, fmt.Errorf("rounds must be between 0 and %d", MaxSwissRounds)
+		}
+		rounds = s.Rounds
+	}
+
+	rules := s.Rules
+	rules.Players, rules.Teams = 0, false
+	if rules.BestOf == 0 {
+		rules.BestOf = 1
+	}
+	if err := rules.Validate(); err != nil {
+		return store.Tournament{}, err
+	}
+
+	return store.Tournament{
+		ID:         uuid.NewString(),
+		Name:       name,
+		Format:     s.Format,
+		Status:     store.TournamentRegistering,
+		Rules:      rules,
+		MaxPlayers: maxPlayers,
+		Rounds:     rounds,
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// MatchPayload is the tournament_match notification: the player's next opponent and match.
+type MatchPayload struct {
+	TournamentID string `json:"tournamentId"`
+	Name         string `json:"name"`
+	Round        int    `json:"round"`
+	Board        int    `json:"board"`
+	MatchID      string `json:"matchId"`
+	OpponentID   string `json:"opponentId"`
+}
+
+// Service starts tournaments and advances them. It is a game.ResultRecorder: every
+// finished series is checked against the tournament matches, and once a round is complete
+// the next one is paired and its matches created, reserved for the two players.
+type Service struct {
+	store   tournamentStore
+	matches *game.MatchService
+	notify  game.Notifier // optional: nil => players are not told about new matches
+
+	// a player who hasn't joined their match by then forfeits it; 0 => wait forever
+	joinTimeout time.Duration
+}
+
+// tournamentStore is the part of store.TournamentStore the service drives.
+type tournamentStore interface {
+	Start(ctx context.Context, id, userID string, plan store.TournamentStart) (store.Tournament, []store.TournamentMatch, error)
+	RecordResult(ctx context.Context, matchID, winnerID string, advance store.TournamentAdvance) (store.Tournament, []store.TournamentMatch, bool, error)
+}
+
+func NewService(st *store.TournamentStore, matches *game.MatchService) *Service {
+	return &Service{store: st, matches: matches}
+}
+
+// SetNotifier sends tournament_match to both players whenever their match is created.
+func (s *Service) SetNotifier(n game.Notifier) {
+	s.notify = n
+}
+
+// SetJoinTimeout makes no-shows forfeit: a player who hasn't joined their match within d
+// loses the series, and the round goes on without them (see game.CreateOptions.JoinTimeout).
+func (s *Service) SetJoinTimeout(d time.Duration) {
+	s.joinTimeout = d
+}
+
+// Start closes registration and creates the first round's matches.
+func (s *Service) Start(ctx context.Context, id, userID string) (store.Tournament, error) {
+	t, first, err := s.store.Start(ctx, id, userID, func(t store.Tournament, seeds []string) (int, []store.TournamentMatch, error) {
+		rounds := Rounds(t.Format, len(seeds), t.Rounds)
+		next, _ := NextRound(t.Format, rounds, seeds, nil)
+		return rounds, toMatches(next), nil
+	})
+	if err != nil {
+		return store.Tournament{}, err
+	}
+	return t, s.spawn(ctx, t, first)
+}
+
+func (s *Service) RecordGame(ctx context.Context, r game.GameResult) error {
+	return nil
+}
+
+// RecordSeries records the result of a tournament match; other matches are ignored.
+func (s *Service) RecordSeries(ctx context.Context, r game.SeriesResult) error {
+	// slots in the game match follow the join order, so the result goes by user ID
+	winner := ""
+	switch r.Winner {
+	case "p1":
+		winner = r.P1ID
+	case "p2":
+		winner = r.P2ID
+	}
+	t, next, found, err := s.store.RecordResult(ctx, r.MatchID, winner, advance)
+	if err != nil || !found {
+		return err
+	}
+	return s.spawn(ctx, t, next)
+}
+
+func advance(t store.Tournament, seeds []string, matches []store.TournamentMatch) ([]store.TournamentMatch, bool) {
+	next, finished := NextRound(t.Format, t.Rounds, seeds, ToPairings(matches))
+	return toMatches(next), finished
+}
+
+// spawn creates the game matches of freshly paired boards (byes have none). The pairings are
+// already committed, so a failure here leaves a board without a playable match; it is reported
+// to the caller, which has no one to show it to but the logs.
+func (s *Service) spawn(ctx context.Context, t store.Tournament, matches []store.TournamentMatch) error {
+	var errs []error
+	for _, m := range matches {
+		if m.MatchID == "" {
+			continue
+		}
+		_, err := s.matches.CreateWithOptions(ctx, m.MatchID, t.Rules, game.CreateOptions{
+			Reserved:    []string{m.P1ID, m.P2ID},
+			JoinTimeout: s.joinTimeout,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tournament %s round %d board %d: %w", t.ID, m.Round, m.Board, err))
+			continue
+		}
+		if s.notify == nil {
+			continue
+		}
+		for _, p := range [][2]string{{m.P1ID, m.P2ID}, {m.P2ID, m.P1ID}} {
+			s.notify.Publish(p[0], notify.Event{Type: "tournament_match", Payload: MatchPayload{
+				TournamentID: t.ID,
+				Name:         t.Name,
+				Round:        m.Round,
+				Board:        m.Board,
+				MatchID:      m.MatchID,
+				OpponentID:   p[1],
+			}})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ToPairings converts stored matches for ComputeStandings.
+func ToPairings(matches []store.TournamentMatch) []Pairing {
+	out := make([]Pairing, len(matches))
+	for i, m := range matches {
+		out[i] = Pairing{Round: m.Round, Board: m.Board, P1: m.P1ID, P2: m.P2ID, Result: m.Result}
+	}
+	return out
+}
+
+// toMatches gives every board with two players a fresh match ID.
+func toMatches(pairings []Pairing) []store.TournamentMatch {
+	out := make([]store.TournamentMatch, len(pairings))
+	for i, p := range pairings {
+		out[i] = store.TournamentMatch{Round: p.Round, Board: p.Board, P1ID: p.P1, P2ID: p.P2, Result: p.Result}
+		if !p.Bye() {
+			out[i].MatchID = game.NewMatchID()
+		}
+	}
+	return out
+}
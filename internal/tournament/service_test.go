@@ -0,0 +1,135 @@
+package tournament
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/bc-mvp/internal/game"
+	"example.com/bc-mvp/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore does in memory what store.TournamentStore does in its transactions.
+type memStore struct {
+	mu      sync.Mutex
+	t       store.Tournament
+	seeds   []string
+	matches []store.TournamentMatch
+}
+
+func (s *memStore) Start(ctx context.Context, id, userID string, plan store.TournamentStart) (store.Tournament, []store.TournamentMatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rounds, first, err := plan(s.t, s.seeds)
+	if err != nil {
+		return store.Tournament{}, nil, err
+	}
+	s.t.Status, s.t.Rounds, s.t.CurrentRound = store.TournamentRunning, rounds, 1
+	s.matches = append(s.matches, first...)
+	return s.t, first, nil
+}
+
+func (s *memStore) RecordResult(ctx context.Context, matchID, winnerID string, advance store.TournamentAdvance) (store.Tournament, []store.TournamentMatch, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, m := range s.matches {
+		if m.MatchID != matchID {
+			continue
+		}
+		if m.Result != "" {
+			return s.t, nil, true, nil
+		}
+		switch winnerID {
+		case m.P1ID:
+			s.matches[i].Result = ResultP1
+		case m.P2ID:
+			s.matches[i].Result = ResultP2
+		default:
+			s.matches[i].Result = ResultDraw
+		}
+		next, finished := advance(s.t, s.seeds, s.matches)
+		switch {
+		case finished:
+			s.t.Status = store.TournamentFinished
+		case len(next) > 0:
+			s.matches = append(s.matches, next...)
+			s.t.CurrentRound = next[0].Round
+		}
+		return s.t, next, true, nil
+	}
+	return store.Tournament{}, nil, false, nil
+}
+
+func (s *memStore) round(n int) []store.TournamentMatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []store.TournamentMatch
+	for _, m := range s.matches {
+		if m.Round == n {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+type memPersist struct {
+	mu sync.Mutex
+	m  map[string]game.MatchSnapshot
+}
+
+func (p *memPersist) Save(ctx context.Context, matchID string, snap game.MatchSnapshot) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.m == nil {
+		p.m = make(map[string]game.MatchSnapshot)
+	}
+	p.m[matchID] = snap
+	return nil
+}
+
+func (p *memPersist) Load(ctx context.Context, matchID string) (game.MatchSnapshot, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	snap, ok := p.m[matchID]
+	return snap, ok, nil
+}
+
+func TestService_NoShow(t *testing.T) {
+	ctx := context.Background()
+	st := &memStore{
+		t:     store.Tournament{ID: "t1", Name: "Cup", Format: FormatSingleElimination, Rules: game.Rules{BestOf: 1}},
+		seeds: players(4),
+	}
+	matches := game.NewMatchService(game.Config{}, &memPersist{})
+	svc := &Service{store: st, matches: matches}
+	svc.SetJoinTimeout(20 * time.Millisecond)
+	matches.SetResultRecorder(svc)
+
+	_, err := svc.Start(ctx, "t1", "u1")
+	require.NoError(t, err)
+	first := st.round(1)
+	require.Len(t, first, 2)
+	require.Equal(t, [2]string{"u1", "u4"}, [2]string{first[0].P1ID, first[0].P2ID})
+
+	// u4 shows up, u1 doesn't; nobody comes to board 2
+	m, ok, err := matches.GetOrLoad(ctx, first[0].MatchID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, code, _ := m.Attach("u4", "Dan", nil)
+	require.Empty(t, code)
+
+	require.Eventually(t, func() bool { return len(st.round(2)) == 1 }, time.Second, 5*time.Millisecond,
+		"the round advances without the absent players")
+	first = st.round(1)
+	assert.Equal(t, ResultP2, first[0].Result, "u1 forfeits")
+	assert.Equal(t, ResultDraw, first[1].Result, "neither player came")
+
+	final := st.round(2)[0]
+	assert.Equal(t, [2]string{"u4", "u2"}, [2]string{final.P1ID, final.P2ID}, "the draw sends the better seed through")
+	_, ok, err = matches.GetOrLoad(ctx, final.MatchID)
+	require.NoError(t, err)
+	assert.True(t, ok, "the final's match is created")
+}
@@ -0,0 +1,137 @@
+package tournament
+
+import "sort"
+
+// Standing is one player's line in the standings. A win (or a bye) is worth 1 point,
+// a draw half a point.
+type Standing struct {
+	UserID string
+	Rank   int
+	Seed   int
+
+	Points float64
+	Wins   int // byes included
+	Draws  int
+	Losses int
+	Byes   int
+
+	// Swiss tiebreaks, in order: Buchholz (sum of the opponents' points) and
+	// Sonneborn-Berger (points of the opponents beaten plus half of those drawn).
+	Buchholz        float64
+	SonnebornBerger float64
+
+	// Eliminated is set in single elimination once the player has lost.
+	Eliminated bool
+}
+
+// ComputeStandings ranks the players from the finished pairings.
+//
+// Swiss: points, then Buchholz, then Sonneborn-Berger, then wins, then seed.
+// Single elimination: players still in first, then by how many rounds they won, then seed.
+func ComputeStandings(format string, seeds []string, pairings []Pairing) []Standing {
+	seedOf := seedIndex(seeds)
+	byID := make(map[string]*Standing, len(seeds))
+	out := make([]Standing, len(seeds))
+	for i, id := range seeds {
+		out[i] = Standing{UserID: id, Seed: i + 1}
+		byID[id] = &out[i]
+	}
+
+	opponents := make(map[string][]string)
+	for _, p := range pairings {
+		if !p.Finished() {
+			continue
+		}
+		a, b := byID[p.P1], byID[p.P2]
+		if a == nil {
+			continue
+		}
+		if p.Bye() {
+			a.Points++
+			a.Wins++
+			a.Byes++
+			continue
+		}
+		if b == nil {
+			continue
+		}
+		opponents[a.UserID] = append(opponents[a.UserID], b.UserID)
+		opponents[b.UserID] = append(opponents[b.UserID], a.UserID)
+		switch p.Result {
+		case ResultP1:
+			a.Points++
+			a.Wins++
+			b.Losses++
+		case ResultP2:
+			b.Points++
+			b.Wins++
+			a.Losses++
+		default:
+			a.Points += 0.5
+			b.Points += 0.5
+			a.Draws++
+			b.Draws++
+		}
+		if format == FormatSingleElimination {
+			if Advancer(p, seedOf) == a.UserID {
+				b.Eliminated = true
+			} else {
+				a.Eliminated = true
+			}
+		}
+	}
+
+	// tiebreaks need everyone's final points, so a second pass
+	for _, p := range pairings {
+		if !p.Finished() || p.Bye() {
+			continue
+		}
+		a, b := byID[p.P1], byID[p.P2]
+		if a == nil || b == nil {
+			continue
+		}
+		switch p.Result {
+		case ResultP1:
+			a.SonnebornBerger += b.Points
+		case ResultP2:
+			b.SonnebornBerger += a.Points
+		default:
+			a.SonnebornBerger += b.Points / 2
+			b.SonnebornBerger += a.Points / 2
+		}
+	}
+	for i := range out {
+		for _, opp := range opponents[out[i].UserID] {
+			out[i].Buchholz += byID[opp].Points
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if format == FormatSingleElimination {
+			if a.Eliminated != b.Eliminated {
+				return !a.Eliminated
+			}
+			if a.Wins != b.Wins {
+				return a.Wins > b.Wins
+			}
+			return a.Seed < b.Seed
+		}
+		switch {
+		case a.Points != b.Points:
+			return a.Points > b.Points
+		case a.Buchholz != b.Buchholz:
+			return a.Buchholz > b.Buchholz
+		case a.SonnebornBerger != b.SonnebornBerger:
+			return a.SonnebornBerger > b.SonnebornBerger
+		case a.Wins != b.Wins:
+			return a.Wins > b.Wins
+		default:
+			return a.Seed < b.Seed
+		}
+	})
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
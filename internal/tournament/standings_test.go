@@ -0,0 +1,107 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeStandings(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{
+			name: "swiss: points, then Buchholz",
+			run: func(t *testing.T) {
+				seeds := players(4)
+				pairings := []Pairing{
+					{Round: 1, Board: 1, P1: "u1", P2: "u2", Result: ResultP1},
+					{Round: 1, Board: 2, P1: "u3", P2: "u4", Result: ResultP1},
+					{Round: 2, Board: 1, P1: "u1", P2: "u3", Result: ResultDraw},
+					{Round: 2, Board: 2, P1: "u2", P2: "u4", Result: ResultP2},
+				}
+				got := ComputeStandings(FormatSwiss, seeds, pairings)
+				require.Len(t, got, 4)
+
+				// u1 and u3 both have 1.5; u1 met u2 (0) and u3 (1.5), u3 met u4 (1) and u1 (1.5)
+				assert.Equal(t, "u3", got[0].UserID)
+				assert.Equal(t, 1.5, got[0].Points)
+				assert.Equal(t, 2.5, got[0].Buchholz)
+				assert.Equal(t, "u1", got[1].UserID)
+				assert.Equal(t, 1.5, got[1].Buchholz)
+				assert.Equal(t, "u4", got[2].UserID)
+				assert.Equal(t, "u2", got[3].UserID)
+				for i, s := range got {
+					assert.Equal(t, i+1, s.Rank)
+				}
+			},
+		},
+		{
+			name: "swiss: equal tiebreaks fall back to wins and seed",
+			run: func(t *testing.T) {
+				seeds := players(4)
+				pairings := []Pairing{
+					{Round: 1, Board: 1, P1: "u1", P2: "u2", Result: ResultP1},
+					{Round: 1, Board: 2, P1: "u3", P2: "u4", Result: ResultP2},
+					{Round: 2, Board: 1, P1: "u4", P2: "u1", Result: ResultDraw},
+					{Round: 2, Board: 2, P1: "u2", P2: "u3", Result: ResultDraw},
+				}
+				got := ComputeStandings(FormatSwiss, seeds, pairings)
+				// u1 and u4: 1.5 points and Buchholz 2 each; u4 beat u3 (0.5), u1 beat u2 (0.5),
+				// and they drew each other, so SB is equal too and wins then seed decide
+				assert.Equal(t, "u1", got[0].UserID)
+				assert.Equal(t, "u4", got[1].UserID)
+				assert.Equal(t, got[0].SonnebornBerger, got[1].SonnebornBerger)
+				assert.Equal(t, 1.25, got[0].SonnebornBerger)
+			},
+		},
+		{
+			name: "byes count as wins without opponents",
+			run: func(t *testing.T) {
+				seeds := players(3)
+				pairings := []Pairing{
+					{Round: 1, Board: 1, P1: "u1", P2: "u2", Result: ResultP2},
+					{Round: 1, Board: 2, P1: "u3", Result: ResultP1},
+				}
+				got := ComputeStandings(FormatSwiss, seeds, pairings)
+				byID := make(map[string]Standing)
+				for _, s := range got {
+					byID[s.UserID] = s
+				}
+				assert.Equal(t, Standing{UserID: "u3", Rank: byID["u3"].Rank, Seed: 3, Points: 1, Wins: 1, Byes: 1}, byID["u3"])
+				assert.Equal(t, 1.0, byID["u1"].Buchholz)
+				assert.Equal(t, 0.0, byID["u2"].Buchholz)
+			},
+		},
+		{
+			name: "unfinished pairings are ignored",
+			run: func(t *testing.T) {
+				got := ComputeStandings(FormatSwiss, players(2), []Pairing{{Round: 1, Board: 1, P1: "u1", P2: "u2"}})
+				assert.Zero(t, got[0].Points+got[1].Points)
+				assert.Equal(t, "u1", got[0].UserID)
+			},
+		},
+		{
+			name: "elimination: players still in come first",
+			run: func(t *testing.T) {
+				seeds := players(4)
+				pairings := []Pairing{
+					{Round: 1, Board: 1, P1: "u1", P2: "u4", Result: ResultP2},
+					{Round: 1, Board: 2, P1: "u2", P2: "u3", Result: ResultDraw},
+					{Round: 2, Board: 1, P1: "u4", P2: "u2"},
+				}
+				got := ComputeStandings(FormatSingleElimination, seeds, pairings)
+				ids := []string{got[0].UserID, got[1].UserID, got[2].UserID, got[3].UserID}
+				assert.Equal(t, []string{"u4", "u2", "u1", "u3"}, ids)
+				assert.False(t, got[1].Eliminated)
+				assert.True(t, got[2].Eliminated)
+				assert.True(t, got[3].Eliminated)
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, tc.run)
+	}
+}